@@ -34,17 +34,24 @@ func (pl *PosLookup) Get(file string, line, col int) string {
 }
 
 // DefLookup maps types.Object (declaration) to node IDs for REF edges.
+// Objects of a package copy that was not walked (see dedupTestVariants)
+// resolve through the position of their declaration.
 type DefLookup struct {
-	m map[types.Object]string
+	m     map[types.Object]string
+	byPos map[string]string // "file:line:col" of the declaration → node ID
+	fset  *token.FileSet
 }
 
-func NewDefLookup() *DefLookup {
-	return &DefLookup{m: make(map[types.Object]string)}
+func NewDefLookup(fset *token.FileSet) *DefLookup {
+	return &DefLookup{m: make(map[types.Object]string), byPos: make(map[string]string), fset: fset}
 }
 
 func (dl *DefLookup) Set(obj types.Object, id string) {
 	if obj != nil {
 		dl.m[obj] = id
+		if obj.Pos().IsValid() {
+			dl.byPos[dl.fset.Position(obj.Pos()).String()] = id
+		}
 	}
 }
 
@@ -52,7 +59,13 @@ func (dl *DefLookup) Get(obj types.Object) string {
 	if obj == nil {
 		return ""
 	}
-	return dl.m[obj]
+	if id, ok := dl.m[obj]; ok {
+		return id
+	}
+	if !obj.Pos().IsValid() {
+		return ""
+	}
+	return dl.byPos[dl.fset.Position(obj.Pos()).String()]
 }
 
 // FuncLookup maps function positions to node IDs for parent tracking.
@@ -79,7 +92,7 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 
 	posLookup := NewPosLookup()
 	funcLookup := NewFuncLookup()
-	defLookup := NewDefLookup()
+	defLookup := NewDefLookup(fset)
 
 	var nodeCount, edgeCount int
 	var skippedFiles int
@@ -113,11 +126,17 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 				continue
 			}
 			absFile := pkg.CompiledGoFiles[i]
+			compiledFile := absFile
 
 			// Compute relative path via ModuleSet
 			relFile := modSet.RelFile(absFile)
 			if relFile == "" {
-				continue
+				// cgo output lives in the build cache; its //line
+				// directives map it back to the original source file.
+				absFile = fset.Position(file.Package).Filename
+				if relFile = modSet.RelFile(absFile); relFile == "" {
+					continue
+				}
 			}
 
 			if shouldSkipFile(relFile) {
@@ -170,6 +189,15 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 				}
 			}
 
+			// Snippets are cut by offset, so they must come from the compiled
+			// file that was parsed, not the original source.
+			source := cpg.Sources[relFile]
+			if compiledFile != absFile {
+				if content, err := os.ReadFile(compiledFile); err == nil {
+					source = string(content)
+				}
+			}
+
 			// Walk AST of this file
 			v := &astVisitor{
 				pkg:         pkg,
@@ -181,7 +209,7 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 				posLookup:   posLookup,
 				funcLookup:  funcLookup,
 				defLookup:   defLookup,
-				source:      cpg.Sources[relFile],
+				source:      source,
 				parentStack: []string{fileID},
 				initIDs:     &initFuncIDs,
 				scopeNodes:  make(map[string]bool),
@@ -232,8 +260,11 @@ func WalkAST(pkgs []*packages.Package, fset *token.FileSet, cpg *CPG, prog *Prog
 	// Done after all packages are walked so defLookup is fully populated.
 	hmCount := emitHasMethodEdges(pkgs, fset, defLookup, cpg)
 
-	prog.Log("Created %d nodes, %d AST edges, %d has_method edges (skipped %d generated/test files)",
-		nodeCount, edgeCount, hmCount, skippedFiles)
+	// Emit api_use edges: uses of other modules' exported declarations.
+	auCount := emitCrossModuleUses(pkgs, fset, defLookup, funcLookup, cpg)
+
+	prog.Log("Created %d nodes, %d AST edges, %d has_method edges, %d api_use edges (skipped %d generated/test files)",
+		nodeCount, edgeCount, hmCount, auCount, skippedFiles)
 
 	return posLookup, funcLookup
 }
@@ -993,6 +1024,24 @@ func (v *astVisitor) visitAssign(n *ast.AssignStmt) {
 				}
 			}
 		}
+	} else if len(n.Lhs) == len(n.Rhs) {
+		// Reassignment edge: declared variable → RHS expression. Mirrors
+		// initializer for plain and op-assignments, so data dependence is
+		// kept even when SSA folds the value into a constant or phi.
+		for i, lhs := range n.Lhs {
+			ident, ok := lhs.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			defID := v.defLookup.Get(v.pkg.TypesInfo.Uses[ident])
+			if defID == "" {
+				continue
+			}
+			if rhsID := v.exprNodeID(n.Rhs[i]); rhsID != "" {
+				v.cpg.AddEdge(Edge{Source: defID, Target: rhsID, Kind: "assigned_from"})
+				v.edgeCount++
+			}
+		}
 	}
 
 	v.parentStack = append(v.parentStack, id)
@@ -1179,9 +1228,14 @@ func (v *astVisitor) visitField(field *ast.Field) {
 
 	id := StmtID(v.relPkg, BaseName(v.relFile), line, col, "field")
 
-	// Register field definition for REF edges
-	if len(field.Names) > 0 {
-		v.defLookup.Set(v.pkg.TypesInfo.Defs[field.Names[0]], id)
+	// Register field definition for REF edges; the names of a, b int share
+	// the node
+	for i, ident := range field.Names {
+		v.defLookup.Set(v.pkg.TypesInfo.Defs[ident], id)
+		if i > 0 {
+			nameLine, nameCol := v.pos(ident.Pos())
+			v.posLookup.Set(v.relFile, nameLine, nameCol, id)
+		}
 	}
 
 	props := map[string]any{
@@ -2,12 +2,18 @@ package handler
 
 import (
 	"net/http"
+	"slices"
+	"sort"
+	"strings"
 
 	"cpg-explorer/internal/model"
 )
 
 // CallGraph performs a BFS over call edges from a given function,
-// returning a subgraph suitable for interactive visualization.
+// returning a subgraph suitable for interactive visualization. remote_call
+// edges (HTTP clients to the handlers of the routes they reach) are
+// followed too, so the traversal crosses service boundaries. A pair
+// linked by both kinds is one edge listing both.
 func (h *Handler) CallGraph(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("id")
 	if id == "" {
@@ -65,29 +71,26 @@ func (h *Handler) bfsCallees(rootID string, maxDepth int, nodeMap map[string]*mo
 		var next []string
 		for _, srcID := range frontier {
 			rows, err := h.db.Query(`
-				SELECT e.target, n.name, COALESCE(n.package, ''), COALESCE(n.file, ''),
+				SELECT e.target, GROUP_CONCAT(DISTINCT e.kind), n.name, COALESCE(n.package, ''), COALESCE(n.file, ''),
 				       COALESCE(n.line, 0),
 				       COALESCE(m.cyclomatic_complexity, 0),
 				       COALESCE(m.fan_in, 0), COALESCE(m.fan_out, 0)
 				FROM edges e
 				JOIN nodes n ON n.id = e.target
 				LEFT JOIN metrics m ON m.function_id = n.id
-				WHERE e.source = ? AND e.kind = 'call' AND n.kind = 'function'
+				WHERE e.source = ? AND e.kind IN ('call', 'remote_call') AND n.kind = 'function'
+				GROUP BY e.target
 				LIMIT 30`, srcID)
 			if err != nil {
 				continue
 			}
 
 			for rows.Next() {
-				var tgtID, name, pkg, file string
+				var tgtID, kinds, name, pkg, file string
 				var line, complexity, fanIn, fanOut int
-				rows.Scan(&tgtID, &name, &pkg, &file, &line, &complexity, &fanIn, &fanOut)
+				rows.Scan(&tgtID, &kinds, &name, &pkg, &file, &line, &complexity, &fanIn, &fanOut)
 
-				*edges = append(*edges, model.CallGraphEdge{
-					Source: srcID,
-					Target: tgtID,
-					Kind:   "call",
-				})
+				*edges = append(*edges, callGraphEdge(srcID, tgtID, kinds))
 
 				if _, exists := nodeMap[tgtID]; !exists {
 					nodeMap[tgtID] = &model.CallGraphNode{
@@ -113,29 +116,26 @@ func (h *Handler) bfsCallers(rootID string, maxDepth int, nodeMap map[string]*mo
 		var next []string
 		for _, tgtID := range frontier {
 			rows, err := h.db.Query(`
-				SELECT e.source, n.name, COALESCE(n.package, ''), COALESCE(n.file, ''),
+				SELECT e.source, GROUP_CONCAT(DISTINCT e.kind), n.name, COALESCE(n.package, ''), COALESCE(n.file, ''),
 				       COALESCE(n.line, 0),
 				       COALESCE(m.cyclomatic_complexity, 0),
 				       COALESCE(m.fan_in, 0), COALESCE(m.fan_out, 0)
 				FROM edges e
 				JOIN nodes n ON n.id = e.source
 				LEFT JOIN metrics m ON m.function_id = n.id
-				WHERE e.target = ? AND e.kind = 'call' AND n.kind = 'function'
+				WHERE e.target = ? AND e.kind IN ('call', 'remote_call') AND n.kind = 'function'
+				GROUP BY e.source
 				LIMIT 30`, tgtID)
 			if err != nil {
 				continue
 			}
 
 			for rows.Next() {
-				var srcID, name, pkg, file string
+				var srcID, kinds, name, pkg, file string
 				var line, complexity, fanIn, fanOut int
-				rows.Scan(&srcID, &name, &pkg, &file, &line, &complexity, &fanIn, &fanOut)
+				rows.Scan(&srcID, &kinds, &name, &pkg, &file, &line, &complexity, &fanIn, &fanOut)
 
-				*edges = append(*edges, model.CallGraphEdge{
-					Source: srcID,
-					Target: tgtID,
-					Kind:   "call",
-				})
+				*edges = append(*edges, callGraphEdge(srcID, tgtID, kinds))
 
 				if _, exists := nodeMap[srcID]; !exists {
 					nodeMap[srcID] = &model.CallGraphNode{
@@ -153,6 +153,19 @@ func (h *Handler) bfsCallers(rootID string, maxDepth int, nodeMap map[string]*mo
 	}
 }
 
+// callGraphEdge builds the edge for a caller/callee pair from the
+// comma-separated edge kinds linking them. Kind is remote_call when the
+// pair has one, call otherwise.
+func callGraphEdge(source, target, kinds string) model.CallGraphEdge {
+	ks := strings.Split(kinds, ",")
+	sort.Strings(ks)
+	kind := "call"
+	if slices.Contains(ks, "remote_call") {
+		kind = "remote_call"
+	}
+	return model.CallGraphEdge{Source: source, Target: target, Kind: kind, Kinds: ks}
+}
+
 // fetchCallGraphNode loads a single node from the database.
 func (h *Handler) fetchCallGraphNode(id string, depth int, isRoot bool) *model.CallGraphNode {
 	var name, pkg, file string
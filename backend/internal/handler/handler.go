@@ -31,6 +31,10 @@ func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/functions/detail", h.FunctionDetail)
 	mux.HandleFunc("GET /api/callgraph", h.CallGraph)
 	mux.HandleFunc("GET /api/dataflow", h.DataFlow)
+	mux.HandleFunc("GET /api/slice", h.Slice)
+	mux.HandleFunc("GET /api/prometheus/metrics", h.Metrics)
+	mux.HandleFunc("GET /api/prometheus/metric", h.MetricDetail)
+	mux.HandleFunc("GET /api/modules/usage", h.CrossModuleUsage)
 	mux.HandleFunc("GET /api/source", h.Source)
 	mux.HandleFunc("GET /api/source/outline", h.FileOutline)
 	mux.HandleFunc("GET /api/schema", h.Schema)
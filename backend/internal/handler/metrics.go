@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"cpg-explorer/internal/model"
+)
+
+const metricColumns = `
+	metric_id, COALESCE(name, ''), type, vec, COALESCE(help, ''), labels,
+	COALESCE(variable, ''), COALESCE(constructor, ''), COALESCE(package, ''),
+	COALESCE(file, ''), COALESCE(line, 0), definition_sites, registration_sites,
+	auto_registered, use_count`
+
+// Metrics lists the metrics catalog, optionally filtered by a name substring.
+func (h *Handler) Metrics(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+	limit := queryInt(r, "limit", 100)
+	offset := queryInt(r, "offset", 0)
+
+	rows, err := h.db.Query(`SELECT `+metricColumns+`
+		FROM metrics_catalog
+		WHERE ? = '' OR name LIKE ?
+		ORDER BY name
+		LIMIT ? OFFSET ?`, search, "%"+search+"%", limit, offset)
+	if err != nil {
+		writeError(w, "failed to query metrics", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	metrics := []model.Metric{}
+	for rows.Next() {
+		m, err := scanMetric(rows)
+		if err != nil {
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	writeJSON(w, metrics)
+}
+
+// MetricDetail looks a metric up by its full name (or catalog ID) and
+// returns it with every call site that updates it.
+func (h *Handler) MetricDetail(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	m, err := scanMetric(h.db.QueryRow(`SELECT `+metricColumns+`
+		FROM metrics_catalog
+		WHERE name = ? OR metric_id = ?
+		LIMIT 1`, name, name))
+	if err == sql.ErrNoRows {
+		writeError(w, "metric not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, "failed to query metric", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT call_id, method, COALESCE(function_id, ''), COALESCE(function_name, ''),
+		       COALESCE(package, ''), COALESCE(file, ''), COALESCE(line, 0),
+		       COALESCE(label_values, '[]')
+		FROM metric_uses
+		WHERE metric_id = ?
+		ORDER BY file, line`, m.ID)
+	if err != nil {
+		writeError(w, "failed to query metric uses", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	detail := model.MetricDetail{Metric: m, Uses: []model.MetricUse{}}
+	for rows.Next() {
+		var u model.MetricUse
+		var labels string
+		if err := rows.Scan(&u.CallID, &u.Method, &u.FunctionID, &u.FunctionName,
+			&u.Package, &u.File, &u.Line, &labels); err != nil {
+			continue
+		}
+		u.LabelValues = jsonStrings(labels)
+		detail.Uses = append(detail.Uses, u)
+	}
+	writeJSON(w, detail)
+}
+
+// scanMetric reads one metricColumns row.
+func scanMetric(row interface{ Scan(...any) error }) (model.Metric, error) {
+	var m model.Metric
+	var labels, defs, regs string
+	err := row.Scan(&m.ID, &m.Name, &m.Type, &m.Vec, &m.Help, &labels,
+		&m.Variable, &m.Constructor, &m.Package, &m.File, &m.Line, &defs, &regs,
+		&m.AutoRegistered, &m.UseCount)
+	if err != nil {
+		return m, err
+	}
+	m.Labels = jsonStrings(labels)
+	m.DefinitionSites = jsonStrings(defs)
+	m.RegistrationSites = jsonStrings(regs)
+	return m, nil
+}
+
+// jsonStrings decodes a JSON string array column, yielding an empty slice
+// for NULL or malformed values.
+func jsonStrings(s string) []string {
+	out := []string{}
+	json.Unmarshal([]byte(s), &out)
+	if out == nil {
+		out = []string{}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"cpg-explorer/internal/model"
+)
+
+// CrossModuleUsage lists exported symbols used across module boundaries,
+// optionally filtered by provider module, consumer module and a symbol
+// substring, most used first.
+func (h *Handler) CrossModuleUsage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	provider := q.Get("provider")
+	consumer := q.Get("consumer")
+	search := q.Get("search")
+	limit := queryInt(r, "limit", 100)
+	offset := queryInt(r, "offset", 0)
+
+	rows, err := h.db.Query(`
+		SELECT symbol_id, symbol, symbol_kind, provider_module, COALESCE(provider_package, ''),
+		       COALESCE(file, ''), COALESCE(line, 0), consumer_module, consumer_packages,
+		       consumer_functions, reference_count, call_count, call_sites
+		FROM cross_module_usage
+		WHERE (? = '' OR provider_module = ?)
+		  AND (? = '' OR consumer_module = ?)
+		  AND (? = '' OR symbol LIKE ?)
+		ORDER BY reference_count DESC, symbol
+		LIMIT ? OFFSET ?`,
+		provider, provider, consumer, consumer, search, "%"+search+"%", limit, offset)
+	if err != nil {
+		writeError(w, "failed to query cross-module usage", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	usages := []model.ModuleUsage{}
+	for rows.Next() {
+		var u model.ModuleUsage
+		var pkgs, sites string
+		if err := rows.Scan(&u.SymbolID, &u.Symbol, &u.SymbolKind, &u.ProviderModule, &u.ProviderPackage,
+			&u.File, &u.Line, &u.ConsumerModule, &pkgs,
+			&u.ConsumerFunctions, &u.ReferenceCount, &u.CallCount, &sites); err != nil {
+			continue
+		}
+		u.ConsumerPackages = jsonStrings(pkgs)
+		u.CallSites = jsonStrings(sites)
+		usages = append(usages, u)
+	}
+	writeJSON(w, usages)
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"cpg-explorer/internal/model"
+)
+
+// maxSliceNodes bounds the size of a slice response.
+const maxSliceNodes = 5000
+
+// Slice computes a backward or forward program slice from a criterion node
+// over the pdg_edges table (data + control dependence). With
+// interprocedural=true it crosses calls through param_in/param_out using the
+// two-phase traversal: a backward slice first ascends into callers, then
+// descends into callees, so it never returns through an unrelated call site.
+func (h *Handler) Slice(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("node")
+	if id == "" {
+		writeError(w, "node id is required", http.StatusBadRequest)
+		return
+	}
+
+	direction := r.URL.Query().Get("direction")
+	if direction == "" {
+		direction = "backward"
+	}
+	if direction != "backward" && direction != "forward" {
+		writeError(w, "direction must be backward or forward", http.StatusBadRequest)
+		return
+	}
+
+	interprocedural := true
+	if s := r.URL.Query().Get("interprocedural"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			writeError(w, "interprocedural must be a boolean", http.StatusBadRequest)
+			return
+		}
+		interprocedural = v
+	}
+
+	root := h.fetchSliceNode(id, 0)
+	if root == nil {
+		writeError(w, "node not found", http.StatusNotFound)
+		return
+	}
+
+	s := &sliceState{
+		nodeMap:  map[string]*model.SliceNode{id: root},
+		edgeSeen: make(map[model.SliceEdge]bool),
+	}
+
+	// Phase 1 stays in the criterion's callers, phase 2 descends into callees.
+	// Backward: skip param_out, then param_in. Forward: the mirror image.
+	skip1, skip2 := "param_out", "param_in"
+	if direction == "forward" {
+		skip1, skip2 = "param_in", "param_out"
+	}
+	h.bfsSlice([]string{id}, direction == "backward", skip1, interprocedural, s)
+	if interprocedural && !s.truncated {
+		frontier := make([]string, 0, len(s.nodeMap))
+		for nid := range s.nodeMap {
+			frontier = append(frontier, nid)
+		}
+		sort.Strings(frontier)
+		h.bfsSlice(frontier, direction == "backward", skip2, interprocedural, s)
+	}
+
+	nodes := make([]model.SliceNode, 0, len(s.nodeMap))
+	lines := make(map[string][]int)
+	seenLine := make(map[string]map[int]bool)
+	for _, n := range s.nodeMap {
+		nodes = append(nodes, *n)
+		if n.File == "" || n.Line == 0 {
+			continue
+		}
+		if seenLine[n.File] == nil {
+			seenLine[n.File] = make(map[int]bool)
+		}
+		if !seenLine[n.File][n.Line] {
+			seenLine[n.File][n.Line] = true
+			lines[n.File] = append(lines[n.File], n.Line)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].File != nodes[j].File {
+			return nodes[i].File < nodes[j].File
+		}
+		return nodes[i].Line < nodes[j].Line
+	})
+	for _, ls := range lines {
+		sort.Ints(ls)
+	}
+
+	writeJSON(w, model.Slice{
+		Criterion:       id,
+		Direction:       direction,
+		Interprocedural: interprocedural,
+		Nodes:           nodes,
+		Edges:           s.edges,
+		Lines:           lines,
+		Truncated:       s.truncated,
+	})
+}
+
+// sliceState accumulates the slice across both traversal phases.
+type sliceState struct {
+	nodeMap   map[string]*model.SliceNode
+	edges     []model.SliceEdge
+	edgeSeen  map[model.SliceEdge]bool
+	truncated bool
+}
+
+// bfsSlice walks pdg_edges from frontier until fixpoint, skipping edges of
+// kind skip and, unless interprocedural, all interprocedural edges.
+func (h *Handler) bfsSlice(frontier []string, backward bool, skip string, interprocedural bool, s *sliceState) {
+	query := `
+		SELECT p.target, p.kind, n.name, n.kind, COALESCE(n.file, ''), COALESCE(n.line, 0),
+		       COALESCE(n.parent_function, '')
+		FROM pdg_edges p
+		JOIN nodes n ON n.id = p.target
+		WHERE p.source = ? AND p.kind != ? AND (p.interprocedural = 0 OR ?)`
+	if backward {
+		query = `
+		SELECT p.source, p.kind, n.name, n.kind, COALESCE(n.file, ''), COALESCE(n.line, 0),
+		       COALESCE(n.parent_function, '')
+		FROM pdg_edges p
+		JOIN nodes n ON n.id = p.source
+		WHERE p.target = ? AND p.kind != ? AND (p.interprocedural = 0 OR ?)`
+	}
+
+	for d := 1; len(frontier) > 0; d++ {
+		var next []string
+		for _, cur := range frontier {
+			rows, err := h.db.Query(query, cur, skip, interprocedural)
+			if err != nil {
+				continue
+			}
+
+			for rows.Next() {
+				var otherID, edgeKind, name, kind, file, fn string
+				var line int
+				rows.Scan(&otherID, &edgeKind, &name, &kind, &file, &line, &fn)
+
+				edge := model.SliceEdge{Source: otherID, Target: cur, Kind: edgeKind}
+				if !backward {
+					edge = model.SliceEdge{Source: cur, Target: otherID, Kind: edgeKind}
+				}
+
+				// Edges only between nodes in the response
+				if _, exists := s.nodeMap[otherID]; !exists {
+					if len(s.nodeMap) >= maxSliceNodes {
+						s.truncated = true
+						continue
+					}
+					s.nodeMap[otherID] = &model.SliceNode{
+						ID: otherID, Label: name, Kind: kind,
+						File: file, Line: line, Function: fn, Depth: d,
+					}
+					next = append(next, otherID)
+				}
+				if !s.edgeSeen[edge] {
+					s.edgeSeen[edge] = true
+					s.edges = append(s.edges, edge)
+				}
+			}
+			rows.Close()
+		}
+		frontier = next
+	}
+}
+
+// fetchSliceNode loads the slicing criterion.
+func (h *Handler) fetchSliceNode(id string, depth int) *model.SliceNode {
+	var name, kind, file, fn string
+	var line int
+
+	err := h.db.QueryRow(`
+		SELECT name, kind, COALESCE(file, ''), COALESCE(line, 0), COALESCE(parent_function, '')
+		FROM nodes WHERE id = ?`, id).Scan(&name, &kind, &file, &line, &fn)
+	if err != nil {
+		return nil
+	}
+
+	return &model.SliceNode{
+		ID: id, Label: name, Kind: kind,
+		File: file, Line: line, Function: fn, Depth: depth,
+	}
+}
@@ -101,9 +101,10 @@ type CallGraphNode struct {
 
 // CallGraphEdge represents an edge in the call graph visualization.
 type CallGraphEdge struct {
-	Source string `json:"source"`
-	Target string `json:"target"`
-	Kind   string `json:"kind"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Kind   string   `json:"kind"`  // remote_call when the pair has one, else call
+	Kinds  []string `json:"kinds"` // every edge kind linking the pair
 }
 
 // CallGraph holds the full call graph for rendering.
@@ -195,3 +196,87 @@ type Query struct {
 	Description string `json:"description"`
 	SQL         string `json:"sql"`
 }
+
+// SliceNode is a node in a program slice.
+type SliceNode struct {
+	ID       string `json:"id"`
+	Label    string `json:"label"`
+	Kind     string `json:"kind"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function"`
+	Depth    int    `json:"depth"`
+}
+
+// SliceEdge is a dependence edge in a program slice.
+type SliceEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind"`
+}
+
+// Slice holds a backward or forward program slice over the PDG.
+type Slice struct {
+	Criterion       string           `json:"criterion"`
+	Direction       string           `json:"direction"`
+	Interprocedural bool             `json:"interprocedural"`
+	Nodes           []SliceNode      `json:"nodes"`
+	Edges           []SliceEdge      `json:"edges"`
+	Lines           map[string][]int `json:"lines"`
+	Truncated       bool             `json:"truncated"`
+}
+
+// Metric is a Prometheus metric from the metrics catalog.
+type Metric struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	Type              string   `json:"type"`
+	Vec               bool     `json:"vec"`
+	Help              string   `json:"help"`
+	Labels            []string `json:"labels"`
+	Variable          string   `json:"variable"`
+	Constructor       string   `json:"constructor"`
+	Package           string   `json:"package"`
+	File              string   `json:"file"`
+	Line              int      `json:"line"`
+	DefinitionSites   []string `json:"definition_sites"`
+	RegistrationSites []string `json:"registration_sites"`
+	AutoRegistered    bool     `json:"auto_registered"`
+	UseCount          int      `json:"use_count"`
+}
+
+// MetricUse is a call site that updates or selects a metric.
+type MetricUse struct {
+	CallID       string   `json:"call_id"`
+	Method       string   `json:"method"`
+	FunctionID   string   `json:"function_id"`
+	FunctionName string   `json:"function_name"`
+	Package      string   `json:"package"`
+	File         string   `json:"file"`
+	Line         int      `json:"line"`
+	LabelValues  []string `json:"label_values"`
+}
+
+// MetricDetail holds a metric and every site that uses it.
+type MetricDetail struct {
+	Metric Metric      `json:"metric"`
+	Uses   []MetricUse `json:"uses"`
+}
+
+// ModuleUsage is the use of one exported symbol of a provider module by
+// one consumer module.
+type ModuleUsage struct {
+	SymbolID          string   `json:"symbol_id"`
+	Symbol            string   `json:"symbol"`
+	SymbolKind        string   `json:"symbol_kind"`
+	ProviderModule    string   `json:"provider_module"`
+	ProviderPackage   string   `json:"provider_package"`
+	File              string   `json:"file"`
+	Line              int      `json:"line"`
+	ConsumerModule    string   `json:"consumer_module"`
+	ConsumerPackages  []string `json:"consumer_packages"`
+	ConsumerFunctions int      `json:"consumer_functions"`
+	ReferenceCount    int      `json:"reference_count"`
+	CallCount         int      `json:"call_count"`
+	CallSites         []string `json:"call_sites"`
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// chanOp is one send, receive or close on a traced channel. state is the
+// select case index for operations inside a select, -1 otherwise.
+type chanOp struct {
+	kind    string // "send", "recv" or "close"
+	instr   ssa.Instruction
+	state   int
+	commaOk bool
+}
+
+// pos returns the source position of the operation; select cases have
+// their own positions.
+func (op chanOp) pos() token.Pos {
+	if sel, ok := op.instr.(*ssa.Select); ok && op.state >= 0 {
+		return sel.States[op.state].Pos
+	}
+	return op.instr.Pos()
+}
+
+// chanIdent is a channel identity: one make(chan) site and every operation
+// reached by following the value it creates.
+type chanIdent struct {
+	make   *ssa.MakeChan
+	ops    []chanOp
+	fields map[fieldKey]string // struct fields the channel is stored in → "Type.field"
+}
+
+// chanOpKey identifies an operation site; select cases share the instruction.
+type chanOpKey struct {
+	instr ssa.Instruction
+	state int
+}
+
+// fieldKey identifies a struct field across all instances of the struct.
+type fieldKey struct {
+	typ   string
+	field int
+}
+
+// buffered reports whether the channel was made with a constant non-zero
+// capacity. A variable capacity counts as unbuffered.
+func (c *chanIdent) buffered() bool {
+	n, ok := c.capacity()
+	return ok && n > 0
+}
+
+// capacity returns the constant buffer size, or false when it is computed
+// at run time.
+func (c *chanIdent) capacity() (int64, bool) {
+	k, ok := c.make.Size.(*ssa.Const)
+	if !ok || k.Value == nil || k.Value.Kind() != constant.Int {
+		return 0, false
+	}
+	return constant.Int64Val(k.Value)
+}
+
+// has reports whether the channel has an operation of any of the given kinds.
+func (c *chanIdent) has(kinds ...string) bool {
+	for _, op := range c.ops {
+		for _, k := range kinds {
+			if op.kind == k {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// chanTracer follows channel values across function boundaries: into
+// parameters of static and interface calls, out through return values, and
+// through struct fields and package variables, where a store to T.f (or
+// the variable) reaches every load of T.f (or the variable) in the program.
+type chanTracer struct {
+	prog       *ssa.Program
+	fieldRefs  map[fieldKey][]ssa.Value    // FieldAddr and Field instructions
+	globalRefs map[*ssa.Global][]ssa.Value // loads of package variables
+	callers    map[*ssa.Function][]*ssa.Call
+	concrete   []types.Type // named types converted to interfaces
+	implCache  map[string][]*ssa.Function
+}
+
+func newChanTracer(ssaResult *SSAResult) *chanTracer {
+	t := &chanTracer{
+		prog:       ssaResult.Prog,
+		fieldRefs:  make(map[fieldKey][]ssa.Value),
+		globalRefs: make(map[*ssa.Global][]ssa.Value),
+		callers:    make(map[*ssa.Function][]*ssa.Call),
+		implCache:  make(map[string][]*ssa.Function),
+	}
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				switch inst := instr.(type) {
+				case *ssa.FieldAddr:
+					k := fieldKey{deref(inst.X.Type()).String(), inst.Field}
+					t.fieldRefs[k] = append(t.fieldRefs[k], inst)
+				case *ssa.Field:
+					k := fieldKey{inst.X.Type().String(), inst.Field}
+					t.fieldRefs[k] = append(t.fieldRefs[k], inst)
+				case *ssa.UnOp:
+					// Globals have no referrers; index their loads.
+					if g, ok := inst.X.(*ssa.Global); ok && inst.Op == token.MUL {
+						t.globalRefs[g] = append(t.globalRefs[g], inst)
+					}
+				case *ssa.Call:
+					if callee := inst.Call.StaticCallee(); callee != nil {
+						t.callers[callee] = append(t.callers[callee], inst)
+					}
+				}
+			}
+		}
+	}
+	for _, rt := range ssaResult.Prog.RuntimeTypes() {
+		if n, ok := deref(rt).(*types.Named); ok && n.Obj().Pkg() != nil && modSet.IsKnownPkg(n.Obj().Pkg().Path()) {
+			t.concrete = append(t.concrete, rt)
+		}
+	}
+	return t
+}
+
+// traceChannels follows every make(chan) in the analyzed code and records
+// its sends, receives (including select cases and range) and closes. The
+// returned index maps each operation site to the channels it may act on.
+func traceChannels(ssaResult *SSAResult) ([]*chanIdent, map[chanOpKey][]*chanIdent) {
+	tracer := newChanTracer(ssaResult)
+	var chans []*chanIdent
+	index := make(map[chanOpKey][]*chanIdent)
+
+	for fn := range ssaResult.AllFuncs {
+		// Package initializers make the channels of package variables.
+		if fn.Pkg == nil || (fn.Synthetic != "" && fn.Synthetic != "package initializer") {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				mc, ok := instr.(*ssa.MakeChan)
+				if !ok {
+					continue
+				}
+				c := &chanIdent{make: mc, fields: make(map[fieldKey]string)}
+				tracer.follow(c, mc, map[ssa.Value]bool{})
+				chans = append(chans, c)
+				for _, op := range c.ops {
+					k := chanOpKey{op.instr, op.state}
+					index[k] = append(index[k], c)
+				}
+			}
+		}
+	}
+	sort.Slice(chans, func(i, j int) bool { return chans[i].make.Pos() < chans[j].make.Pos() })
+	return chans, index
+}
+
+// follow records the channel operations reachable from val.
+func (t *chanTracer) follow(c *chanIdent, val ssa.Value, visited map[ssa.Value]bool) {
+	if visited[val] {
+		return
+	}
+	visited[val] = true
+
+	refs := val.Referrers()
+	if refs == nil {
+		return
+	}
+	for _, ref := range *refs {
+		switch inst := ref.(type) {
+		case *ssa.Send:
+			if inst.Chan == val {
+				c.ops = append(c.ops, chanOp{kind: "send", instr: inst, state: -1})
+			}
+		case *ssa.UnOp:
+			if inst.Op == token.ARROW && inst.X == val {
+				c.ops = append(c.ops, chanOp{kind: "recv", instr: inst, state: -1, commaOk: inst.CommaOk})
+			} else if inst.Op == token.MUL {
+				t.follow(c, inst, visited)
+			}
+		case *ssa.Select:
+			for i, st := range inst.States {
+				if st.Chan != val {
+					continue
+				}
+				kind := "recv"
+				if st.Dir == types.SendOnly {
+					kind = "send"
+				}
+				c.ops = append(c.ops, chanOp{kind: kind, instr: inst, state: i})
+			}
+		case *ssa.Call:
+			if isBuiltinClose(&inst.Call, val) {
+				c.ops = append(c.ops, chanOp{kind: "close", instr: inst, state: -1})
+				continue
+			}
+			t.followCallArgs(c, &inst.Call, val, visited)
+		case *ssa.Go:
+			t.followCallArgs(c, &inst.Call, val, visited)
+		case *ssa.Defer:
+			if isBuiltinClose(&inst.Call, val) {
+				c.ops = append(c.ops, chanOp{kind: "close", instr: inst, state: -1})
+				continue
+			}
+			t.followCallArgs(c, &inst.Call, val, visited)
+		case *ssa.Return:
+			// Returned from a constructor: continue at every static call
+			// site, through Extract for multi-value returns.
+			for i, r := range inst.Results {
+				if r != val {
+					continue
+				}
+				for _, call := range t.callers[inst.Parent()] {
+					if len(inst.Results) == 1 {
+						t.follow(c, call, visited)
+						continue
+					}
+					crefs := call.Referrers()
+					if crefs == nil {
+						continue
+					}
+					for _, cref := range *crefs {
+						if ex, ok := cref.(*ssa.Extract); ok && ex.Index == i {
+							t.follow(c, ex, visited)
+						}
+					}
+				}
+			}
+		case *ssa.MakeClosure:
+			closureFn, ok := inst.Fn.(*ssa.Function)
+			if !ok {
+				continue
+			}
+			for i, binding := range inst.Bindings {
+				if binding == val && i < len(closureFn.FreeVars) {
+					t.follow(c, closureFn.FreeVars[i], visited)
+				}
+			}
+		case *ssa.Store:
+			if inst.Val != val {
+				continue
+			}
+			t.follow(c, inst.Addr, visited)
+			// A store into T.f reaches loads of T.f through any instance,
+			// a store into a package variable every load of it.
+			switch addr := inst.Addr.(type) {
+			case *ssa.FieldAddr:
+				k := fieldKey{deref(addr.X.Type()).String(), addr.Field}
+				c.fields[k] = fieldName(addr)
+				for _, ref := range t.fieldRefs[k] {
+					t.follow(c, ref, visited)
+				}
+			case *ssa.Global:
+				for _, ref := range t.globalRefs[addr] {
+					t.follow(c, ref, visited)
+				}
+			}
+		case ssa.Value:
+			t.follow(c, inst, visited)
+		}
+	}
+}
+
+// followCallArgs follows a channel argument into the callee's parameter.
+// Interface method calls are followed into every implementation among the
+// analyzed types that reach an interface conversion.
+func (t *chanTracer) followCallArgs(c *chanIdent, common *ssa.CallCommon, val ssa.Value, visited map[ssa.Value]bool) {
+	if common.IsInvoke() {
+		for _, callee := range t.implementations(common) {
+			// Params[0] is the receiver; invoke Args exclude it.
+			for i, arg := range common.Args {
+				if arg == val && i+1 < len(callee.Params) {
+					t.follow(c, callee.Params[i+1], visited)
+				}
+			}
+		}
+		return
+	}
+	callee, ok := common.Value.(*ssa.Function)
+	if !ok {
+		return
+	}
+	for i, arg := range common.Args {
+		if arg == val && i < len(callee.Params) {
+			t.follow(c, callee.Params[i], visited)
+		}
+	}
+}
+
+// implementations returns the concrete methods an interface call may
+// dispatch to.
+func (t *chanTracer) implementations(common *ssa.CallCommon) []*ssa.Function {
+	iface, ok := common.Value.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	key := common.Value.Type().String() + "." + common.Method.Name()
+	if fns, ok := t.implCache[key]; ok {
+		return fns
+	}
+	var fns []*ssa.Function
+	for _, ct := range t.concrete {
+		if !types.Implements(ct, iface) {
+			continue
+		}
+		if fn := t.prog.LookupMethod(ct, common.Method.Pkg(), common.Method.Name()); fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	t.implCache[key] = fns
+	return fns
+}
+
+// isBuiltinClose reports whether common is close(val).
+func isBuiltinClose(common *ssa.CallCommon, val ssa.Value) bool {
+	b, ok := common.Value.(*ssa.Builtin)
+	return ok && b.Name() == "close" && len(common.Args) == 1 && common.Args[0] == val
+}
+
+// fieldName renders the field a FieldAddr selects as "Type.field".
+func fieldName(fa *ssa.FieldAddr) string {
+	t := deref(fa.X.Type())
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || fa.Field >= st.NumFields() {
+		return ""
+	}
+	typeName := t.String()
+	if n, ok := t.(*types.Named); ok {
+		typeName = n.Obj().Name()
+	}
+	return typeName + "." + st.Field(fa.Field).Name()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+func TestTraceChannels(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/lib", Dir: "/src/example.com/lib"}, nil)
+
+	prog, _ := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+func Local() int {
+	ch := make(chan int, 1)
+	ch <- 1
+	return <-ch
+}
+
+func Param() {
+	ch := make(chan int)
+	go drain(ch)
+	ch <- 1
+	close(ch)
+}
+
+func drain(ch chan int) {
+	for range ch {
+	}
+}
+
+type Worker struct{ jobs chan int }
+
+func newWorker() *Worker { return &Worker{jobs: make(chan int)} }
+
+func (w *Worker) Submit(j int) { w.jobs <- j }
+func (w *Worker) Run()         { <-w.jobs }
+
+var events = make(chan string)
+
+func Emit(e string) { events <- e }
+func Listen() string {
+	select {
+	case e := <-events:
+		return e
+	}
+}
+`})
+
+	chans, _ := traceChannels(&SSAResult{Prog: prog, AllFuncs: ssautil.AllFunctions(prog)})
+	got := make(map[string][]string) // function holding the make → op kinds
+	for _, c := range chans {
+		var kinds []string
+		for _, op := range c.ops {
+			kinds = append(kinds, op.kind)
+		}
+		slices.Sort(kinds)
+		got[c.make.Parent().Name()] = kinds
+	}
+
+	tests := []struct {
+		name, fn string
+		want     []string
+	}{
+		{"local", "Local", []string{"recv", "send"}},
+		{"parameter", "Param", []string{"close", "recv", "send"}},
+		{"struct field", "newWorker", []string{"recv", "send"}},
+		{"global", "init", []string{"recv", "send"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kinds := got[tt.fn]; !slices.Equal(kinds, tt.want) {
+				t.Errorf("ops of the channel made in %s = %v, want %v", tt.fn, kinds, tt.want)
+			}
+		})
+	}
+}
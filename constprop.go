@@ -0,0 +1,523 @@
+package main
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Bounds on constant propagation: how many distinct values one argument
+// may take before it counts as unknown, and how deep value chains
+// (concatenations, phis, parameters) are followed.
+const (
+	constMaxValues = 16
+	constMaxDepth  = 8
+)
+
+// constResolver resolves SSA values to the set of constants they may hold.
+type constResolver struct {
+	callers map[*ssa.Function][]*ssa.CallCommon // static call sites of functions whose callers are all known
+	memo    map[ssa.Value][]any
+	active  map[ssa.Value]bool
+}
+
+// PropagateConstStrings resolves the possible constant values of string
+// call arguments over SSA and records them as const_values on the
+// corresponding argument edges, so analyses keyed on route paths, metric
+// names, SQL text, flag names or config keys see real strings rather than
+// identifiers.
+//
+// A value resolves when every way it can be computed is constant: literals
+// and named constants, + concatenations, fmt.Sprintf with a constant
+// format and constant operands, conversions between string types, phis,
+// parameters of functions whose callers are all known (unexported or in
+// package main, never used as a value, directly or as a method value, and
+// not callable through an interface) and all pass constants, and the
+// results of single-result functions of the analyzed modules.
+// Variadic arguments map to their position in the call expression. Values
+// beyond constMaxValues, or chains deeper than constMaxDepth, leave the
+// argument unresolved.
+func PropagateConstStrings(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Propagating constant strings...")
+
+	funcs := knownFuncs(ssaResult)
+	r := newConstResolver(funcs)
+
+	argEdges := make(map[string]int) // call node ID + "#" + index → edge index
+	for i, e := range cpg.Edges {
+		if e.Kind == "argument" {
+			if idx, ok := e.Properties["index"].(int); ok {
+				argEdges[e.Source+"#"+strconv.Itoa(idx)] = i
+			}
+		}
+	}
+
+	var resolved, multi int
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				file, line, col := instrPos(call, fset)
+				if file == "" {
+					continue
+				}
+				callID := posLookup.Get(file, line, col)
+				if callID == "" {
+					continue
+				}
+				for idx, arg := range callArgsByIndex(call.Common()) {
+					ei, ok := argEdges[callID+"#"+strconv.Itoa(idx)]
+					if !ok || !isStringValue(arg) {
+						continue
+					}
+					vals := r.strings(arg)
+					if len(vals) == 0 {
+						continue
+					}
+					e := &cpg.Edges[ei]
+					if e.Properties == nil {
+						e.Properties = map[string]any{}
+					}
+					e.Properties["const_values"] = vals
+					resolved++
+					if len(vals) > 1 {
+						multi++
+					}
+				}
+			}
+		}
+	}
+
+	prog.Log("Constant strings: %d arguments resolved (%d with several values)", resolved, multi)
+}
+
+// knownFuncs returns the non-synthetic functions with bodies in the
+// analyzed modules, plus package initializers (they evaluate package
+// variable initializers), in source order.
+func knownFuncs(ssaResult *SSAResult) []*ssa.Function {
+	var funcs []*ssa.Function
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || (fn.Synthetic != "" && fn.Synthetic != "package initializer") {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) > 0 {
+			funcs = append(funcs, fn)
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Pos() < funcs[j].Pos() })
+	return funcs
+}
+
+// newConstResolver prepares constant resolution over funcs, recording the
+// static call sites of every function whose callers are all known.
+func newConstResolver(funcs []*ssa.Function) *constResolver {
+	r := &constResolver{
+		callers: make(map[*ssa.Function][]*ssa.CallCommon),
+		memo:    make(map[ssa.Value][]any),
+		active:  make(map[ssa.Value]bool),
+	}
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if call, ok := instr.(ssa.CallInstruction); ok {
+					common := call.Common()
+					if callee := common.StaticCallee(); callee != nil && !common.IsInvoke() {
+						r.callers[callee] = append(r.callers[callee], common)
+					}
+				}
+			}
+		}
+	}
+	escaped := escapedFuncs(funcs)
+	for fn := range r.callers {
+		if escaped[fn] || fn.Pkg == nil || !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) ||
+			(token.IsExported(fn.Name()) && fn.Pkg.Pkg.Name() != "main" && fn.Parent() == nil) {
+			delete(r.callers, fn)
+		}
+	}
+	return r
+}
+
+// knownCallers returns the static call sites of fn when they are all of
+// its callers: fn is unexported (or in package main), never used as a
+// value and not callable through an interface.
+func (r *constResolver) knownCallers(fn *ssa.Function) ([]*ssa.CallCommon, bool) {
+	sites, ok := r.callers[fn]
+	return sites, ok && len(sites) > 0
+}
+
+// escapedFuncs returns the functions that funcs may call other than
+// through a static call: functions used as a value, methods reached
+// through a $bound (method value) or $thunk (method expression) wrapper,
+// and methods named by an interface that some call invokes.
+func escapedFuncs(funcs []*ssa.Function) map[*ssa.Function]bool {
+	escaped := make(map[*ssa.Function]bool)
+	invoked := make(map[string]bool)
+	var methods []*ssa.Function
+	for _, fn := range funcs {
+		if fn.Signature.Recv() != nil {
+			methods = append(methods, fn)
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				var callValue ssa.Value
+				if call, ok := instr.(ssa.CallInstruction); ok {
+					common := call.Common()
+					if common.IsInvoke() {
+						if iface, ok := common.Value.Type().Underlying().(*types.Interface); ok {
+							for i := range iface.NumMethods() {
+								invoked[iface.Method(i).Name()] = true
+							}
+						}
+					} else if common.StaticCallee() != nil {
+						callValue = common.Value
+					}
+				}
+				var ops [8]*ssa.Value
+				for _, op := range instr.Operands(ops[:0]) {
+					f, ok := (*op).(*ssa.Function)
+					if !ok {
+						continue
+					}
+					if f.Synthetic != "" && f.Signature.Recv() == nil {
+						// $bound and $thunk wrappers call the method they wrap.
+						if m := sourceFunc(f.Prog, f); m != nil && m != f {
+							escaped[m] = true
+						}
+					}
+					if *op != callValue {
+						escaped[f] = true
+					}
+				}
+			}
+		}
+	}
+	for _, m := range methods {
+		if invoked[m.Name()] {
+			escaped[m] = true
+		}
+	}
+	return escaped
+}
+
+// callArgsByIndex maps the arguments of a call to their index in the call
+// expression: a static method call's receiver is dropped, and the
+// elements of an implicit variadic slice are spread out.
+func callArgsByIndex(common *ssa.CallCommon) map[int]ssa.Value {
+	args := common.Args
+	if !common.IsInvoke() {
+		if callee := common.StaticCallee(); callee != nil && callee.Signature.Recv() != nil && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	byIndex := make(map[int]ssa.Value, len(args))
+	for i, a := range args {
+		if i == len(args)-1 && common.Signature().Variadic() {
+			if elems, ok := varargsElems(a); ok {
+				for k, el := range elems {
+					byIndex[i+k] = el
+				}
+				continue
+			}
+		}
+		byIndex[i] = a
+	}
+	return byIndex
+}
+
+// varargsElems returns the values stored into the slice SSA builds for
+// the variadic part of a call, by position.
+func varargsElems(v ssa.Value) (map[int]ssa.Value, bool) {
+	sl, ok := v.(*ssa.Slice)
+	if !ok {
+		return nil, false
+	}
+	if alloc, ok := sl.X.(*ssa.Alloc); !ok || alloc.Comment != "varargs" {
+		return nil, false
+	}
+	return sliceElems(v)
+}
+
+// sliceElems returns the elements of a slice literal by position.
+func sliceElems(v ssa.Value) (map[int]ssa.Value, bool) {
+	sl, ok := v.(*ssa.Slice)
+	if !ok {
+		return nil, false
+	}
+	alloc, ok := sl.X.(*ssa.Alloc)
+	if !ok || alloc.Referrers() == nil {
+		return nil, false
+	}
+	elems := make(map[int]ssa.Value)
+	for _, ref := range *alloc.Referrers() {
+		ia, ok := ref.(*ssa.IndexAddr)
+		if !ok || ia.Referrers() == nil {
+			continue
+		}
+		c, ok := ia.Index.(*ssa.Const)
+		if !ok {
+			continue
+		}
+		k, _ := constant.Int64Val(c.Value)
+		for _, use := range *ia.Referrers() {
+			if st, ok := use.(*ssa.Store); ok && st.Addr == ia {
+				elems[int(k)] = st.Val
+			}
+		}
+	}
+	return elems, true
+}
+
+// isStringValue reports whether v is a string, or an interface holding
+// one (variadic ...any arguments).
+func isStringValue(v ssa.Value) bool {
+	if mi, ok := v.(*ssa.MakeInterface); ok {
+		v = mi.X
+	}
+	b, ok := v.Type().Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsString != 0
+}
+
+// strings returns the sorted possible string values of v, or nil when any
+// of them is not constant.
+func (r *constResolver) strings(v ssa.Value) []string {
+	vals := r.values(v, 0)
+	if vals == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(vals))
+	out := make([]string, 0, len(vals))
+	for _, x := range vals {
+		s, ok := x.(string)
+		if !ok {
+			return nil
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// values returns the possible constant values of v as Go values (string,
+// int64, float64 or bool), or nil when v is not resolvable.
+func (r *constResolver) values(v ssa.Value, depth int) []any {
+	if depth > constMaxDepth || r.active[v] {
+		return nil
+	}
+	if vals, ok := r.memo[v]; ok {
+		return vals
+	}
+	r.active[v] = true
+	vals := r.resolve(v, depth)
+	delete(r.active, v)
+	if len(vals) > constMaxValues {
+		return nil
+	}
+	// Failures may come from the depth bound or a cycle through an active
+	// value, so only successes are remembered.
+	if vals != nil {
+		r.memo[v] = vals
+	}
+	return vals
+}
+
+func (r *constResolver) resolve(v ssa.Value, depth int) []any {
+	switch v := v.(type) {
+	case *ssa.Const:
+		if x := constGoValue(v.Value); x != nil {
+			return []any{x}
+		}
+	case *ssa.MakeInterface:
+		return r.values(v.X, depth+1)
+	case *ssa.ChangeType:
+		return r.values(v.X, depth+1)
+	case *ssa.Convert:
+		if isStringValue(v) && isStringValue(v.X) {
+			return r.values(v.X, depth+1)
+		}
+	case *ssa.BinOp:
+		if v.Op != token.ADD || !isStringValue(v) {
+			return nil
+		}
+		xs, ys := r.values(v.X, depth+1), r.values(v.Y, depth+1)
+		if xs == nil || ys == nil || len(xs)*len(ys) > constMaxValues {
+			return nil
+		}
+		var out []any
+		for _, x := range xs {
+			for _, y := range ys {
+				out = append(out, fmt.Sprint(x)+fmt.Sprint(y))
+			}
+		}
+		return out
+	case *ssa.Phi:
+		var out []any
+		for _, e := range v.Edges {
+			vals := r.values(e, depth+1)
+			if vals == nil {
+				return nil
+			}
+			out = append(out, vals...)
+		}
+		return out
+	case *ssa.Parameter:
+		return r.paramValues(v, depth)
+	case *ssa.Call:
+		callee := v.Call.StaticCallee()
+		if callee == nil || callee.Pkg == nil {
+			return nil
+		}
+		if callee.Pkg.Pkg.Path() == "fmt" && callee.Name() == "Sprintf" {
+			return r.sprintf(&v.Call, depth)
+		}
+		if modSet.IsKnownPkg(callee.Pkg.Pkg.Path()) {
+			return r.returnValues(callee, depth)
+		}
+	}
+	return nil
+}
+
+// returnValues unions what a single-result function returns at each of
+// its return statements.
+func (r *constResolver) returnValues(fn *ssa.Function, depth int) []any {
+	if fn.Signature.Results().Len() != 1 || len(fn.Blocks) == 0 {
+		return nil
+	}
+	var out []any
+	for _, b := range fn.Blocks {
+		ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return)
+		if !ok {
+			continue
+		}
+		vals := r.values(ret.Results[0], depth+1)
+		if vals == nil {
+			return nil
+		}
+		out = append(out, vals...)
+	}
+	return out
+}
+
+// paramValues unions the constants passed for p at every call site of its
+// function.
+func (r *constResolver) paramValues(p *ssa.Parameter, depth int) []any {
+	fn := p.Parent()
+	sites, ok := r.knownCallers(fn)
+	if !ok {
+		return nil
+	}
+	idx := -1
+	for i, q := range fn.Params {
+		if q == p {
+			idx = i
+		}
+	}
+	var out []any
+	for _, common := range sites {
+		if idx >= len(common.Args) {
+			return nil
+		}
+		vals := r.values(common.Args[idx], depth+1)
+		if vals == nil {
+			return nil
+		}
+		out = append(out, vals...)
+	}
+	return out
+}
+
+// sprintf evaluates fmt.Sprintf over every combination of constant format
+// and operands.
+func (r *constResolver) sprintf(common *ssa.CallCommon, depth int) []any {
+	if len(common.Args) != 2 {
+		return nil
+	}
+	formats := r.values(common.Args[0], depth+1)
+	if formats == nil {
+		return nil
+	}
+	combos := [][]any{nil}
+	if _, isNil := common.Args[1].(*ssa.Const); !isNil {
+		elems, ok := varargsElems(common.Args[1])
+		if !ok {
+			return nil
+		}
+		for k := 0; k < len(elems); k++ {
+			el, ok := elems[k]
+			if !ok {
+				return nil
+			}
+			// Operands with methods may format through String().
+			if mi, ok := el.(*ssa.MakeInterface); ok {
+				if _, named := mi.X.Type().(*types.Named); named {
+					return nil
+				}
+			}
+			vals := r.values(el, depth+1)
+			if vals == nil || len(combos)*len(vals) > constMaxValues {
+				return nil
+			}
+			var next [][]any
+			for _, c := range combos {
+				for _, x := range vals {
+					next = append(next, append(append([]any(nil), c...), x))
+				}
+			}
+			combos = next
+		}
+	}
+	var out []any
+	for _, f := range formats {
+		format, ok := f.(string)
+		if !ok {
+			return nil
+		}
+		for _, c := range combos {
+			out = append(out, fmt.Sprintf(format, c...))
+		}
+	}
+	return out
+}
+
+// constGoValue converts a constant to a Go value, or nil for nil and
+// complex constants.
+func constGoValue(c constant.Value) any {
+	if c == nil {
+		return nil
+	}
+	switch c.Kind() {
+	case constant.String:
+		return constant.StringVal(c)
+	case constant.Bool:
+		return constant.BoolVal(c)
+	case constant.Int:
+		if i, ok := constant.Int64Val(c); ok {
+			return i
+		}
+	case constant.Float:
+		f, _ := constant.Float64Val(c)
+		return f
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+func TestConstParamValues(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/lib", Dir: "/src/example.com/lib"}, nil)
+
+	prog, _ := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+type store struct{}
+
+func (*store) add(name string)   { sink(name) }
+func (*store) fixed(name string) { sink(name) }
+func (*store) put(name string)   { sink(name) }
+
+type adder interface{ put(name string) }
+
+func sink(string) {}
+
+func use(f func(string), s string) { f(s) }
+
+func run(s *store, a adder, dyn string) {
+	s.add("fixed")
+	use(s.add, dyn) // method value: add has unseen callers
+
+	s.fixed("a")
+	s.fixed("b")
+
+	s.put("fixed")
+	a.put(dyn) // interface call: put has unseen callers
+}
+`})
+
+	pkg := prog.ImportedPackage("example.com/lib")
+	var funcs []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg == pkg && fn.Synthetic == "" && len(fn.Blocks) > 0 {
+			funcs = append(funcs, fn)
+		}
+	}
+	r := newConstResolver(funcs)
+
+	method := func(name string) *ssa.Function {
+		t.Helper()
+		for _, fn := range funcs {
+			if fn.Name() == name && fn.Signature.Recv() != nil {
+				return fn
+			}
+		}
+		t.Fatalf("no method %s", name)
+		return nil
+	}
+	tests := []struct {
+		method string
+		want   []string
+	}{
+		{"add", nil},
+		{"put", nil},
+		{"fixed", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			got := r.strings(method(tt.method).Params[1])
+			if len(got) != len(tt.want) {
+				t.Fatalf("strings = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("strings = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Context origins reported by ctxOrigin. Background and nil contexts are
+// reported; unknown ones are reported when the function has a context
+// parameter of its own it could have passed instead; the others (the
+// function's own context, captured contexts, the request's context and
+// derivations of them) are fine.
+const (
+	ctxOriginBackground = "background" // context.Background() / TODO()
+	ctxOriginNil        = "nil"
+	ctxOriginUnknown    = "unknown" // loaded from a field or global, or returned by a call taking no context
+)
+
+// ctxChainDepth bounds the caller chain recorded with a context_dropped
+// finding.
+const ctxChainDepth = 6
+
+// ctxIssue is one place a function loses its caller's context.
+type ctxIssue struct {
+	kind   string // background_call, nil_call, unrelated_call, goroutine_background, goroutine_no_context, loop_ignores_context
+	instr  ssa.Instruction
+	site   string
+	callee string
+	detail string
+}
+
+// AnalyzeContextPropagation follows context.Context values through the
+// functions that receive one (as a parameter or a captured variable) and
+// reports where the caller's context is dropped:
+//
+//   - a context-accepting call gets context.Background()/TODO() or nil
+//     instead of a context derived from the function's own;
+//   - a function with a context parameter passes a context loaded from a
+//     field or global, or returned by a call taking no context (a server's
+//     base context), instead of its own. These may be deliberate, so they
+//     are reported as unrelated_call at a lower severity. r.Context() is
+//     the caller's context for an HTTP handler and is not reported;
+//   - a go statement starts a long-running goroutine (a loop or a blocking
+//     channel operation) that receives no context, or a background one;
+//   - a loop that blocks (channel operation, select, sleep, network I/O,
+//     WaitGroup.Wait) never consults the context: no ctx.Done()/Err() and no
+//     call it is passed to.
+//
+// Each issue is stored in context_issues on the function with the chain of
+// context-receiving callers leading to it (following call edges), so a
+// dropped context can be traced back to where cancellation originates.
+// Must run after BuildCallGraph and ExtractLoops.
+func AnalyzeContextPropagation(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Analyzing context propagation...")
+
+	ctxFuncs := make(map[string]bool)
+	issuesByFunc := make(map[string][]ctxIssue)
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) == 0 || !receivesContext(fn) {
+			continue
+		}
+		fnID := ssaFuncNodeID(fn, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		ctxFuncs[fnID] = true
+		if issues := contextIssues(fn, fnID, fset, posLookup); len(issues) > 0 {
+			issuesByFunc[fnID] = issues
+		}
+	}
+
+	callers := make(map[string][]string) // callee ID → context-receiving caller IDs
+	for _, e := range cpg.Edges {
+		if e.Kind == "call" && ctxFuncs[e.Source] && e.Source != e.Target {
+			callers[e.Target] = append(callers[e.Target], e.Source)
+		}
+	}
+	for id := range callers {
+		sort.Strings(callers[id])
+	}
+
+	counts := make(map[string]int)
+	propsByFunc := make(map[string][]map[string]any)
+	for fnID, issues := range issuesByFunc {
+		chain := contextCallerChain(fnID, callers)
+		for _, is := range issues {
+			file, line, _ := instrPos(is.instr, fset)
+			propsByFunc[fnID] = append(propsByFunc[fnID], map[string]any{
+				"kind":   is.kind,
+				"site":   is.site,
+				"callee": is.callee,
+				"detail": is.detail,
+				"file":   file,
+				"line":   line,
+				"chain":  chain,
+			})
+			counts[is.kind]++
+		}
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if props, ok := propsByFunc[n.ID]; ok {
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["context_issues"] = props
+		}
+	}
+
+	prog.Log("Context: %d functions receive a context; %d background, %d nil, %d unrelated contexts passed; %d goroutines, %d loops without context",
+		len(ctxFuncs), counts["background_call"], counts["nil_call"], counts["unrelated_call"],
+		counts["goroutine_background"]+counts["goroutine_no_context"], counts["loop_ignores_context"])
+}
+
+// receivesContext reports whether fn has a context.Context parameter or
+// captures one.
+func receivesContext(fn *ssa.Function) bool {
+	if hasContextParam(fn) {
+		return true
+	}
+	for _, fv := range fn.FreeVars {
+		if isContextType(fv.Type()) || isContextType(deref(fv.Type())) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasContextParam reports whether fn has a context.Context parameter.
+func hasContextParam(fn *ssa.Function) bool {
+	for _, p := range fn.Params {
+		if isContextType(p.Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextIssues checks the calls, go statements and loops of fn.
+func contextIssues(fn *ssa.Function, fnID string, fset *token.FileSet, posLookup *PosLookup) []ctxIssue {
+	siteOf := func(instr ssa.Instruction) string {
+		file, line, col := instrPos(instr, fset)
+		if file == "" {
+			return ""
+		}
+		return posLookup.Get(file, line, col)
+	}
+
+	ownParam := hasContextParam(fn)
+	var issues []ctxIssue
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch inst := instr.(type) {
+			case *ssa.Call:
+				if inContextPkg(&inst.Call) {
+					continue
+				}
+				for _, arg := range inst.Call.Args {
+					if !isContextType(arg.Type()) {
+						continue
+					}
+					origin := ctxOrigin(arg, map[ssa.Value]bool{})
+					kind, desc := origin+"_call", origin
+					switch {
+					case origin == ctxOriginBackground || origin == ctxOriginNil:
+					case origin == ctxOriginUnknown && ownParam:
+						kind, desc = "unrelated_call", "an unrelated"
+					default:
+						continue
+					}
+					issues = append(issues, ctxIssue{
+						kind: kind, instr: inst, site: siteOf(inst),
+						callee: calleeName(&inst.Call),
+						detail: "passes " + desc + " context to " + calleeName(&inst.Call),
+					})
+					break
+				}
+			case *ssa.Go:
+				if is, ok := goroutineContextIssue(inst); ok {
+					is.site = siteOf(inst)
+					issues = append(issues, is)
+				}
+			}
+		}
+	}
+
+	for _, l := range naturalLoops(fn.Blocks) {
+		blocking, consults := loopContextUse(fn, l)
+		if blocking == nil || consults {
+			continue
+		}
+		issues = append(issues, ctxIssue{
+			kind: "loop_ignores_context", instr: blocking,
+			site:   LoopID(fnID, l.header),
+			detail: "loop blocks without checking ctx.Done()",
+		})
+	}
+	return issues
+}
+
+// goroutineContextIssue checks a go statement in a context-receiving
+// function: the goroutine should get a context unless it is short-lived.
+func goroutineContextIssue(g *ssa.Go) (ctxIssue, bool) {
+	var body *ssa.Function
+	var ctxArgs []ssa.Value
+	switch v := g.Call.Value.(type) {
+	case *ssa.MakeClosure:
+		body, _ = v.Fn.(*ssa.Function)
+		for _, b := range v.Bindings {
+			if isContextType(b.Type()) || isContextType(deref(b.Type())) {
+				ctxArgs = append(ctxArgs, b)
+			}
+		}
+	case *ssa.Function:
+		body = v
+	default:
+		return ctxIssue{}, false
+	}
+	for _, arg := range g.Call.Args {
+		if isContextType(arg.Type()) {
+			ctxArgs = append(ctxArgs, arg)
+		}
+	}
+	if body == nil || len(body.Blocks) == 0 {
+		return ctxIssue{}, false
+	}
+	name := calleeName(&g.Call)
+	for _, arg := range ctxArgs {
+		if origin := ctxOrigin(arg, map[ssa.Value]bool{}); origin == ctxOriginBackground || origin == ctxOriginNil {
+			return ctxIssue{
+				kind: "goroutine_background", instr: g, callee: name,
+				detail: "starts " + name + " with " + origin + " context",
+			}, true
+		}
+	}
+	if len(ctxArgs) == 0 && longRunning(body) {
+		return ctxIssue{
+			kind: "goroutine_no_context", instr: g, callee: name,
+			detail: "starts long-running " + name + " without a context",
+		}, true
+	}
+	return ctxIssue{}, false
+}
+
+// ctxOrigin classifies where a context value comes from: one of the
+// ctxOrigin* constants, or "derived" for the function's own context and
+// values derived from it.
+func ctxOrigin(v ssa.Value, visited map[ssa.Value]bool) string {
+	if visited[v] {
+		return "derived"
+	}
+	visited[v] = true
+	switch x := v.(type) {
+	case *ssa.Parameter, *ssa.FreeVar:
+		return "derived"
+	case *ssa.Const:
+		if x.IsNil() {
+			return ctxOriginNil
+		}
+	case *ssa.MakeInterface:
+		return ctxOrigin(x.X, visited)
+	case *ssa.ChangeInterface:
+		return ctxOrigin(x.X, visited)
+	case *ssa.TypeAssert:
+		return ctxOrigin(x.X, visited)
+	case *ssa.Extract:
+		return ctxOrigin(x.Tuple, visited)
+	case *ssa.Phi:
+		// The worst incoming context decides.
+		worst := "derived"
+		for _, e := range x.Edges {
+			switch o := ctxOrigin(e, visited); o {
+			case ctxOriginBackground, ctxOriginNil:
+				return o
+			case ctxOriginUnknown:
+				worst = o
+			}
+		}
+		return worst
+	case *ssa.UnOp:
+		if x.Op == token.MUL {
+			switch x.X.(type) {
+			case *ssa.FieldAddr, *ssa.Global:
+				return ctxOriginUnknown
+			}
+			return "derived"
+		}
+	case *ssa.Call:
+		if isRequestContext(&x.Call) {
+			// The request's context is the caller's for an HTTP handler.
+			return "derived"
+		}
+		if callee := x.Call.StaticCallee(); callee != nil && callee.Pkg != nil && callee.Pkg.Pkg.Path() == "context" {
+			switch callee.Name() {
+			case "Background", "TODO":
+				return ctxOriginBackground
+			case "WithoutCancel":
+				// Deliberately detached; not a dropped context.
+				return "derived"
+			}
+		}
+		// context.With*, or any helper taking a context: the result is
+		// derived from its context argument.
+		for _, arg := range x.Call.Args {
+			if isContextType(arg.Type()) {
+				return ctxOrigin(arg, visited)
+			}
+		}
+		if x.Call.IsInvoke() && isContextLike(x.Call.Value.Type()) {
+			return ctxOrigin(x.Call.Value, visited)
+		}
+		return ctxOriginUnknown
+	}
+	return "derived"
+}
+
+// isRequestContext reports whether a call is (*net/http.Request).Context.
+func isRequestContext(common *ssa.CallCommon) bool {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Pkg == nil || callee.Pkg.Pkg.Path() != "net/http" || callee.Name() != "Context" {
+		return false
+	}
+	recv := callee.Signature.Recv()
+	n, ok := deref(recv.Type()).(*types.Named)
+	return ok && n.Obj().Name() == "Request"
+}
+
+// inContextPkg reports whether a call targets the context package itself;
+// those calls derive contexts rather than consume them.
+func inContextPkg(common *ssa.CallCommon) bool {
+	callee := common.StaticCallee()
+	return callee != nil && callee.Pkg != nil && callee.Pkg.Pkg.Path() == "context"
+}
+
+// calleeName renders a call target for messages.
+func calleeName(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		return common.Value.Type().String() + "." + common.Method.Name()
+	}
+	if callee := common.StaticCallee(); callee != nil {
+		if callee.Pkg != nil {
+			return modSet.RelPkg(callee.Pkg.Pkg.Path()) + "." + callee.RelString(callee.Pkg.Pkg)
+		}
+		return callee.String()
+	}
+	return common.Value.Name()
+}
+
+// longRunning reports whether fn has a loop that blocks: a channel
+// operation, a select (a polling one too) or a blocking call inside a
+// natural loop. A single blocking operation outside a loop finishes once
+// it is unblocked.
+func longRunning(fn *ssa.Function) bool {
+	for _, l := range naturalLoops(fn.Blocks) {
+		for _, b := range fn.Blocks {
+			if !l.body[b.Index] {
+				continue
+			}
+			for _, instr := range b.Instrs {
+				if _, ok := instr.(*ssa.Select); ok || blockingOp(instr) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// blockingOp reports whether instr may block: channel send or receive,
+// blocking select, or a blocking call (see blockingCallKind).
+func blockingOp(instr ssa.Instruction) bool {
+	switch inst := instr.(type) {
+	case *ssa.Send:
+		return true
+	case *ssa.UnOp:
+		return inst.Op == token.ARROW
+	case *ssa.Select:
+		return inst.Blocking
+	case *ssa.Call:
+		return blockingCallKind(&inst.Call) != ""
+	}
+	return false
+}
+
+// loopContextUse returns the first blocking instruction in loop l and
+// whether the loop consults a context: calls a method on one (Done, Err)
+// or passes one to a callee.
+func loopContextUse(fn *ssa.Function, l *naturalLoop) (ssa.Instruction, bool) {
+	var blocking ssa.Instruction
+	for _, b := range fn.Blocks {
+		if !l.body[b.Index] {
+			continue
+		}
+		for _, instr := range b.Instrs {
+			if blocking == nil && blockingOp(instr) {
+				blocking = instr
+			}
+			var common *ssa.CallCommon
+			switch inst := instr.(type) {
+			case *ssa.Call:
+				common = &inst.Call
+			case *ssa.Go:
+				common = &inst.Call
+			case *ssa.Defer:
+				common = &inst.Call
+			default:
+				continue
+			}
+			if common.IsInvoke() && isContextLike(common.Value.Type()) {
+				return blocking, true
+			}
+			for _, arg := range common.Args {
+				if isContextType(arg.Type()) || isContextLike(arg.Type()) {
+					return blocking, true
+				}
+			}
+		}
+	}
+	return blocking, false
+}
+
+// contextCallerChain walks up from fnID through callers that also receive
+// a context, returning function IDs outermost first and ending with fnID.
+func contextCallerChain(fnID string, callers map[string][]string) []string {
+	chain := []string{fnID}
+	seen := map[string]bool{fnID: true}
+	for cur := fnID; len(chain) < ctxChainDepth; {
+		next := ""
+		for _, c := range callers[cur] {
+			if !seen[c] {
+				next = c
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+		seen[next] = true
+		chain = append(chain, next)
+		cur = next
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestLongRunning(t *testing.T) {
+	prog, _ := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+import (
+	"sync"
+	"time"
+)
+
+func sendOnce(ch chan int)  { ch <- 1 }
+func recvOnce(ch chan int)  { <-ch }
+func waitOnce(wg *sync.WaitGroup) { wg.Wait() }
+func sleepOnce()            { time.Sleep(time.Second) }
+func selectOnce(a, b chan int) {
+	select {
+	case <-a:
+	case <-b:
+	}
+}
+
+func compute(xs []int) int {
+	s := 0
+	for _, x := range xs {
+		s += x
+	}
+	return s
+}
+
+func recvLoop(ch chan int) {
+	for {
+		<-ch
+	}
+}
+
+func rangeChan(ch chan int) {
+	for range ch {
+	}
+}
+
+func pollLoop(done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
+
+func sleepLoop() {
+	for {
+		time.Sleep(time.Second)
+	}
+}
+
+func sendAfterLoop(ch chan int, xs []int) {
+	for range xs {
+	}
+	ch <- 1
+}
+`})
+
+	tests := []struct {
+		fn   string
+		want bool
+	}{
+		{"sendOnce", false},
+		{"recvOnce", false},
+		{"waitOnce", false},
+		{"sleepOnce", false},
+		{"selectOnce", false},
+		{"compute", false},
+		{"sendAfterLoop", false},
+		{"recvLoop", true},
+		{"rangeChan", true},
+		{"pollLoop", true},
+		{"sleepLoop", true},
+	}
+	pkg := prog.ImportedPackage("example.com/lib")
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			fn := pkg.Func(tt.fn)
+			if fn == nil {
+				t.Fatalf("no function %s", tt.fn)
+			}
+			if got := longRunning(fn); got != tt.want {
+				t.Errorf("longRunning(%s) = %v, want %v", tt.fn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCtxOrigin(t *testing.T) {
+	prog, _ := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+import (
+	"context"
+	"net/http"
+)
+
+type server struct{ ctx context.Context }
+
+var baseCtx = context.Background()
+
+func use(ctx context.Context) {}
+
+func own(ctx context.Context)        { use(ctx) }
+func withTimeout(ctx context.Context) { c, cancel := context.WithCancel(ctx); defer cancel(); use(c) }
+func background(ctx context.Context) { use(context.Background()) }
+func todo(ctx context.Context)       { use(context.TODO()) }
+func nilCtx(ctx context.Context)     { use(nil) }
+func global(ctx context.Context)     { use(baseCtx) }
+func request(ctx context.Context, r *http.Request) { use(r.Context()) }
+func (s *server) field(ctx context.Context)        { use(s.ctx) }
+func phi(ctx context.Context, b bool) {
+	c := ctx
+	if b {
+		c = context.Background()
+	}
+	use(c)
+}
+`})
+
+	tests := []struct {
+		fn   string
+		want string
+	}{
+		{"own", "derived"},
+		{"withTimeout", "derived"},
+		{"request", "derived"},
+		{"background", ctxOriginBackground},
+		{"todo", ctxOriginBackground},
+		{"nilCtx", ctxOriginNil},
+		{"phi", ctxOriginBackground},
+		{"global", ctxOriginUnknown},
+		{"field", ctxOriginUnknown},
+	}
+	pkg := prog.ImportedPackage("example.com/lib")
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			fn := pkg.Func(tt.fn)
+			if fn == nil {
+				typ := pkg.Pkg.Scope().Lookup("server").Type()
+				fn = prog.MethodValue(prog.MethodSets.MethodSet(types.NewPointer(typ)).Lookup(pkg.Pkg, tt.fn))
+			}
+			var got string
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					if call, ok := instr.(*ssa.Call); ok && call.Call.StaticCallee() == pkg.Func("use") {
+						got = ctxOrigin(call.Call.Args[0], map[ssa.Value]bool{})
+					}
+				}
+			}
+			if got != tt.want {
+				t.Errorf("ctxOrigin in %s = %q, want %q", tt.fn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextIssues(t *testing.T) {
+	prog, fset := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+import (
+	"context"
+	"net/http"
+)
+
+var baseCtx = context.Background()
+
+func use(ctx context.Context) {}
+func newCtx() context.Context { return baseCtx }
+
+func own(ctx context.Context)                      { use(ctx) }
+func background(ctx context.Context)               { use(context.Background()) }
+func global(ctx context.Context)                   { use(baseCtx) }
+func unrelated(ctx context.Context)                { use(newCtx()) }
+func request(ctx context.Context, r *http.Request) { use(r.Context()) }
+func noParam()                                     { use(baseCtx) }
+func captured(ctx context.Context) func() {
+	return func() { _ = ctx; use(baseCtx) }
+}
+`})
+
+	tests := []struct {
+		fn   string
+		want string // issue kind, "" for none
+	}{
+		{"own", ""},
+		{"background", "background_call"},
+		{"global", "unrelated_call"},
+		{"unrelated", "unrelated_call"},
+		{"request", ""},
+		{"noParam", ""},
+		{"captured$1", ""},
+	}
+	pkg := prog.ImportedPackage("example.com/lib")
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			fn := pkg.Func(tt.fn)
+			if fn == nil {
+				fn = pkg.Func("captured").AnonFuncs[0]
+			}
+			var got string
+			for _, is := range contextIssues(fn, "fn", fset, NewPosLookup()) {
+				got = is.kind
+			}
+			if got != tt.want {
+				t.Errorf("contextIssues(%s) kind = %q, want %q", tt.fn, got, tt.want)
+			}
+		})
+	}
+}
@@ -12,7 +12,7 @@ import (
 const batchSize = 50000
 
 // WriteDB writes the CPG to a SQLite database file.
-func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []GitFileHistory, validate bool, prog *Progress) error {
+func WriteDB(path string, cpg *CPG, escape *EscapeRun, gitHistory []GitFileHistory, validate bool, prog *Progress) error {
 	prog.Log("Writing SQLite to %s ...", path)
 
 	_ = os.Remove(path) // ignore if doesn't exist
@@ -207,9 +207,9 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 	}
 
 	// Apply escape analysis annotations from the Go compiler
-	if len(escapeResults) > 0 {
+	if escape != nil && len(escape.Results) > 0 {
 		prog.Log("Applying escape analysis annotations...")
-		if err := applyEscapeAnalysis(conn, escapeResults, prog); err != nil {
+		if err := applyEscapeAnalysis(conn, escape.Results, prog); err != nil {
 			prog.Log("Warning: escape analysis failed: %v", err)
 		}
 	}
@@ -277,6 +277,13 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		}
 	}
 
+	// Escape analysis coverage and compiler failures
+	if escape != nil {
+		if err := createEscapeCoverage(conn, escape, prog); err != nil {
+			return err
+		}
+	}
+
 	// Taint flow state materialization for precise taint analysis
 	prog.Log("Computing taint flow states...")
 	if err := createTaintFlowStates(conn, prog); err != nil {
@@ -289,6 +296,102 @@ func WriteDB(path string, cpg *CPG, escapeResults []EscapeResult, gitHistory []G
 		return err
 	}
 
+	// Statement-level program dependence graph for slicing
+	prog.Log("Building program dependence graph...")
+	if err := createProgramDependence(conn, prog); err != nil {
+		return err
+	}
+
+	// Loop findings: defer and lock acquisition inside loops
+	prog.Log("Analyzing loops...")
+	if err := createLoopAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Goroutine spawn tree and leak candidates
+	prog.Log("Analyzing goroutines...")
+	if err := createGoroutineAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Panic propagation: goroutines without recover
+	prog.Log("Analyzing panic propagation...")
+	if err := createPanicAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Lock pairing: double locks, missing unlocks, blocking under lock
+	prog.Log("Analyzing lock pairing...")
+	if err := createLockAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Lock order graph cycles: deadlock candidates
+	prog.Log("Analyzing lock order...")
+	if err := createLockOrder(conn, prog); err != nil {
+		return err
+	}
+
+	// Data race candidates around go statements
+	prog.Log("Analyzing data races...")
+	if err := createRaceAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Guarded-by inference: unguarded accesses of mutex-protected fields
+	prog.Log("Analyzing guarded fields...")
+	if err := createGuardedByAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Context propagation: dropped contexts
+	prog.Log("Analyzing context propagation...")
+	if err := createContextAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Resource lifetimes: unreleased closers
+	prog.Log("Analyzing resource lifetimes...")
+	if err := createResourceAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Unchecked errors
+	prog.Log("Analyzing unchecked errors...")
+	if err := createUncheckedErrorAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Nil dereference risks
+	prog.Log("Analyzing nil dereferences...")
+	if err := createNilDerefAnalysis(conn, prog); err != nil {
+		return err
+	}
+
+	// Unsafe, cgo, reflection and linkname inventory
+	prog.Log("Building unsafe usage inventory...")
+	if err := createUnsafeUsage(conn, prog); err != nil {
+		return err
+	}
+
+	// Inlining near misses and bounds checks in loops
+	prog.Log("Building optimization report...")
+	if err := createOptimizationReport(conn, prog); err != nil {
+		return err
+	}
+
+	// Prometheus metrics catalog
+	prog.Log("Building metrics catalog...")
+	if err := createMetricsCatalog(conn, prog); err != nil {
+		return err
+	}
+
+	// Cross-module API usage
+	prog.Log("Building cross-module usage...")
+	if err := createCrossModuleUsage(conn, prog); err != nil {
+		return err
+	}
+
 	// SCIP-style cross-repository symbol identifiers
 	prog.Log("Building SCIP symbol index...")
 	if err := createSCIPSymbols(conn, prog); err != nil {
@@ -935,6 +1038,33 @@ LEFT JOIN edge_properties ep ON ep.source = e.source AND ep.target = e.target
 WHERE bb.kind = ''basic_block'' AND parent_e.source = :function_id
 ORDER BY bb.line');
 
+INSERT INTO queries (name, description, sql) VALUES
+('function_ssa',
+ 'SSA listing of a function: instructions per basic block in order (requires -ssa-instrs)',
+ 'SELECT
+  json_extract(i.properties, ''$.block'') AS block,
+  json_extract(i.properties, ''$.index'') AS idx,
+  json_extract(i.properties, ''$.opcode'') AS opcode,
+  json_extract(i.properties, ''$.text'') AS text,
+  i.type_info, i.line, i.col
+FROM nodes i
+WHERE i.kind = ''ssa_instr'' AND i.parent_function = :function_id
+ORDER BY block, idx');
+
+INSERT INTO queries (name, description, sql) VALUES
+('package_channels',
+ 'Channels of a package with buffer size and every send/receive/close site (set :package)',
+ 'SELECT c.id AS channel_id, c.name, c.type_info, c.file, c.line,
+  json_extract(c.properties, ''$.buffer'') AS buffer,
+  ep.value AS op, s.id AS site_id, s.file AS site_file, s.line AS site_line, s.parent_function
+FROM nodes c
+JOIN edges e ON e.target = c.id AND e.kind = ''channel_op''
+JOIN edge_properties ep ON ep.source = e.source AND ep.target = e.target
+  AND ep.edge_kind = ''channel_op'' AND ep.key = ''op''
+JOIN nodes s ON s.id = e.source
+WHERE c.kind = ''channel'' AND c.package = :package
+ORDER BY c.file, c.line, ep.value, s.file, s.line');
+
 INSERT INTO queries (name, description, sql) VALUES
 ('cross_package_calls',
  'All function calls that cross package boundaries',
@@ -1324,14 +1454,12 @@ func applyEscapeAnalysis(conn *sqlite.Conn, results []EscapeResult, prog *Progre
 	}
 	inlineable := conn.Changes()
 
-	// Match heap-escaping annotations to parameter/local nodes
+	// Heap escapes: the exact nodes AttachEscapeFlows gave an escape kind
 	if err := sqlitex.ExecuteTransient(conn,
 		`INSERT INTO node_properties (node_id, key, value)
-		 SELECT DISTINCT n.id, 'heap_escapes', 'true'
-		 FROM escape_info ei
-		 JOIN nodes n ON n.file = ei.file AND n.line = ei.line
-		 WHERE ei.kind IN ('leaking_param', 'moved_to_heap', 'escapes_to_heap')
-		   AND n.kind IN ('parameter', 'local', 'function')`,
+		 SELECT n.id, 'heap_escapes', 'true'
+		 FROM nodes n
+		 WHERE json_extract(n.properties, '$.escape') IS NOT NULL`,
 		&sqlitex.ExecOptions{
 			ResultFunc: func(stmt *sqlite.Stmt) error { return nil },
 		}); err != nil {
@@ -1339,12 +1467,12 @@ func applyEscapeAnalysis(conn *sqlite.Conn, results []EscapeResult, prog *Progre
 	}
 	escaping := conn.Changes()
 
-	// Match "does_not_escape" to parameters
+	// Match "does_not_escape" to the parameter or local at its position
 	if err := sqlitex.ExecuteTransient(conn,
 		`INSERT INTO node_properties (node_id, key, value)
 		 SELECT DISTINCT n.id, 'heap_escapes', 'false'
 		 FROM escape_info ei
-		 JOIN nodes n ON n.file = ei.file AND n.line = ei.line
+		 JOIN nodes n ON n.file = ei.file AND n.line = ei.line AND n.col = ei.col
 		 WHERE ei.kind = 'does_not_escape'
 		   AND n.kind IN ('parameter', 'local')
 		   AND NOT EXISTS (
@@ -1358,11 +1486,245 @@ func applyEscapeAnalysis(conn *sqlite.Conn, results []EscapeResult, prog *Progre
 	}
 	notEscaping := conn.Changes()
 
+	// Heap allocations on a line inside a loop: one finding per compiler
+	// diagnostic, attributed to the deepest loop among the line's nodes
+	if err := sqlitex.ExecuteTransient(conn,
+		`INSERT INTO findings (category, severity, node_id, file, line, message, details)
+		 SELECT 'alloc_in_loop', 'warning', node_id, file, line,
+		   'heap allocation in loop (depth ' || depth || '): ' ||
+		     CASE kind WHEN 'moved_to_heap' THEN 'moved to heap: ' || detail
+		               ELSE detail || ' escapes to heap' END,
+		   json_object('escape', kind, 'detail', detail, 'loop', loop_id,
+		     'depth', depth, 'function', parent_function)
+		 FROM (
+		   SELECT ei.file, ei.line, ei.kind, ei.detail, n.id AS node_id,
+		     l.id AS loop_id, l.parent_function,
+		     json_extract(l.properties, '$.depth') AS depth,
+		     ROW_NUMBER() OVER (
+		       PARTITION BY ei.file, ei.line, ei.col, ei.kind, ei.detail
+		       ORDER BY json_extract(l.properties, '$.depth') DESC, n.col
+		     ) AS rn
+		   FROM escape_info ei
+		   JOIN nodes n ON n.file = ei.file AND n.line = ei.line
+		   JOIN edges e ON e.source = n.id AND e.kind = 'in_loop'
+		   JOIN nodes l ON l.id = e.target AND l.kind = 'loop'
+		   WHERE ei.kind IN ('moved_to_heap', 'escapes_to_heap')
+		 )
+		 WHERE rn = 1`,
+		&sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error { return nil },
+		}); err != nil {
+		return err
+	}
+	loopAllocs := conn.Changes()
+
 	// Drop temp table
 	_ = sqlitex.ExecuteTransient(conn, `DROP TABLE IF EXISTS escape_info`, nil)
 
-	prog.Log("Escape: %d inlineable functions, %d heap-escaping, %d stack-bound",
-		inlineable, escaping, notEscaping)
+	prog.Log("Escape: %d inlineable functions, %d heap-escaping, %d stack-bound, %d allocations in loops",
+		inlineable, escaping, notEscaping, loopAllocs)
+	return nil
+}
+
+// createEscapeCoverage records, per module, how the compiler run of escape
+// analysis went and how many annotations resolved to nodes, with the errors
+// of failed runs.
+func createEscapeCoverage(conn *sqlite.Conn, escape *EscapeRun, prog *Progress) error {
+	ddl := `
+CREATE TABLE escape_coverage (
+    module TEXT PRIMARY KEY,
+    exit_code INTEGER NOT NULL,      -- 0 on success, -1 when the build could not start
+    duration_ms INTEGER NOT NULL,
+    annotations INTEGER NOT NULL,    -- annotations in analyzed files
+    matched INTEGER NOT NULL,        -- at the position of an AST or function node
+    unmatched INTEGER NOT NULL,
+    skipped INTEGER NOT NULL,        -- in files the AST walk skipped (tests, generated)
+    diagnostics INTEGER NOT NULL
+);
+
+CREATE TABLE escape_diagnostics (
+    module TEXT NOT NULL,
+    package TEXT,                    -- "# pkg" header of a compile error
+    file TEXT,
+    line INTEGER,
+    message TEXT NOT NULL            -- compile error, go command error or exit status
+);
+
+CREATE INDEX idx_escape_diagnostics_module ON escape_diagnostics(module);
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('table', 'escape_coverage', 'Per module: exit code and duration of the escape analysis build, annotations matched to nodes, unmatched, and in skipped files', 'SELECT module, matched * 100.0 / annotations FROM escape_coverage'),
+('table', 'escape_diagnostics', 'Errors of failed escape analysis builds; escape, inlining and bounds-check data is missing for the packages they name', 'SELECT package, file, line, message FROM escape_diagnostics');
+
+INSERT INTO queries (name, description, sql) VALUES
+('escape_failures',
+ 'Modules whose escape analysis build failed or matched fewer annotations than it missed, with their first errors',
+ 'SELECT c.module, c.exit_code, c.annotations, c.matched, c.unmatched, c.skipped,
+    (SELECT group_concat(COALESCE(d.file || '':'' || d.line || '': '', '''') || d.message, char(10))
+       FROM (SELECT * FROM escape_diagnostics WHERE module = c.module LIMIT 5) d) AS errors
+  FROM escape_coverage c
+  WHERE c.exit_code <> 0 OR c.diagnostics > 0 OR c.unmatched > c.matched
+  ORDER BY c.exit_code <> 0 DESC, c.unmatched DESC');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("escape coverage: %w", err)
+	}
+
+	runStmt, err := conn.Prepare(`INSERT INTO escape_coverage VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer runStmt.Finalize()
+	diagStmt, err := conn.Prepare(`INSERT INTO escape_diagnostics VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer diagStmt.Finalize()
+
+	var failed, diags int
+	for _, m := range escape.Modules {
+		runStmt.BindText(1, m.Module)
+		runStmt.BindInt64(2, int64(m.ExitCode))
+		runStmt.BindInt64(3, m.Duration.Milliseconds())
+		runStmt.BindInt64(4, int64(m.Annotations))
+		runStmt.BindInt64(5, int64(m.Matched))
+		runStmt.BindInt64(6, int64(m.Unmatched))
+		runStmt.BindInt64(7, int64(m.Skipped))
+		runStmt.BindInt64(8, int64(len(m.Diagnostics)))
+		if _, err := runStmt.Step(); err != nil {
+			return err
+		}
+		runStmt.Reset()
+		if m.ExitCode != 0 || len(m.Diagnostics) > 0 {
+			failed++
+		}
+
+		for _, d := range m.Diagnostics {
+			diagStmt.BindText(1, m.Module)
+			bindTextOrNull(diagStmt, 2, d.Package)
+			bindTextOrNull(diagStmt, 3, d.RelFile)
+			bindIntOrNull(diagStmt, 4, d.Line)
+			diagStmt.BindText(5, d.Message)
+			if _, err := diagStmt.Step(); err != nil {
+				return err
+			}
+			diagStmt.Reset()
+			diags++
+		}
+	}
+
+	prog.Log("Escape coverage: %d modules (%d failed), %d diagnostics", len(escape.Modules), failed, diags)
+	return nil
+}
+
+// createOptimizationReport lists the inlining near misses and the bounds
+// checks inside loops from the properties AttachOptimizations set. A near
+// miss is a call of a function refused as too complex whose cost is within
+// a quarter of the budget; each site carries the deepest loop on its line.
+func createOptimizationReport(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+CREATE TABLE optimization_report (
+    category TEXT NOT NULL,          -- inline_near_miss, bounds_check_in_loop
+    node_id TEXT NOT NULL,           -- call, or index/slice expression
+    function_id TEXT,
+    function_name TEXT,
+    package TEXT,
+    file TEXT,
+    line INTEGER,
+    detail TEXT,                     -- callee, or the checks kept: IsInBounds, IsSliceInBounds
+    cost INTEGER,                    -- near misses: callee inlining cost
+    budget INTEGER,                  -- near misses: budget the cost exceeds
+    loop_id TEXT,                    -- deepest loop on the line; NULL outside loops
+    loop_depth INTEGER
+);
+
+CREATE TEMP TABLE line_loops AS
+SELECT file, line, loop_id, depth FROM (
+  SELECT s.file, s.line, l.id AS loop_id,
+    json_extract(l.properties, '$.depth') AS depth,
+    ROW_NUMBER() OVER (
+      PARTITION BY s.file, s.line
+      ORDER BY json_extract(l.properties, '$.depth') DESC
+    ) AS rn
+  FROM edges e
+  JOIN nodes s ON s.id = e.source AND s.kind NOT IN ('loop', 'basic_block')
+  JOIN nodes l ON l.id = e.target AND l.kind = 'loop'
+  WHERE e.kind = 'in_loop'
+)
+WHERE rn = 1;
+
+INSERT INTO optimization_report
+SELECT 'inline_near_miss', c.id, c.parent_function, f.name, c.package, c.file, c.line,
+  json_extract(c.properties, '$.inline_callee'),
+  json_extract(g.properties, '$.inline_cost'), json_extract(g.properties, '$.inline_budget'),
+  ll.loop_id, ll.depth
+FROM nodes c
+JOIN edges e ON e.source = c.id AND e.kind = 'call_site'
+JOIN nodes g ON g.id = e.target
+LEFT JOIN nodes f ON f.id = c.parent_function
+LEFT JOIN line_loops ll ON ll.file = c.file AND ll.line = c.line
+WHERE c.kind = 'call'
+  AND json_extract(c.properties, '$.inlined') = 0
+  AND json_extract(g.properties, '$.inline_budget') IS NOT NULL
+  AND json_extract(g.properties, '$.inline_cost') * 4 <= json_extract(g.properties, '$.inline_budget') * 5;
+
+INSERT INTO optimization_report
+SELECT 'bounds_check_in_loop', n.id, n.parent_function, f.name, n.package, n.file, n.line,
+  (SELECT group_concat(value, ', ') FROM json_each(json_extract(n.properties, '$.bounds_check'))),
+  NULL, NULL, ll.loop_id, ll.depth
+FROM nodes n
+JOIN line_loops ll ON ll.file = n.file AND ll.line = n.line
+LEFT JOIN nodes f ON f.id = n.parent_function
+WHERE json_extract(n.properties, '$.bounds_check') IS NOT NULL;
+
+DROP TABLE line_loops;
+
+CREATE INDEX idx_optimization_report_category ON optimization_report(category);
+CREATE INDEX idx_optimization_report_function ON optimization_report(function_id);
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'inline_cost', 'Inlining cost the compiler computed for the function (needs escape analysis)', '64'),
+('node_property', 'inline_blocked', 'Why the compiler does not inline the function, or the call (a call of such a function, or a recursive cycle)', 'function too complex, marked go:noinline, repeated recursive cycle'),
+('node_property', 'inline_budget', 'Inlining budget a too complex function exceeds', '80'),
+('node_property', 'inlined', 'Call was inlined by the compiler; false when inline_blocked says why not (absent: no decision reported)', 'true/false'),
+('node_property', 'inline_callee', 'Compiler name of the callee of an inlining decision', 'Big'),
+('node_property', 'bounds_check', 'Bounds checks the compiler kept (-d=ssa/check_bce) on this index or slice expression', '["IsInBounds"]'),
+('table', 'optimization_report', 'Inlining near misses (callee cost within 25% over budget) and bounds checks left inside loops, with the deepest loop of each site', 'SELECT * FROM optimization_report ORDER BY loop_depth DESC');
+
+INSERT INTO queries (name, description, sql) VALUES
+('inline_near_misses',
+ 'Functions narrowly too complex to inline, by how many of their call sites sit in loops',
+ 'SELECT detail AS callee, cost, budget, cost - budget AS over_budget,
+    COUNT(*) AS sites, COUNT(loop_id) AS sites_in_loops, MAX(loop_depth) AS max_depth
+  FROM optimization_report
+  WHERE category = ''inline_near_miss''
+  GROUP BY detail, cost, budget
+  ORDER BY sites_in_loops DESC, over_budget'),
+('bounds_checks_in_loops',
+ 'Functions keeping bounds checks inside loops, deepest first',
+ 'SELECT function_name, package, file, COUNT(*) AS checks, MAX(loop_depth) AS max_depth,
+    group_concat(line, '', '') AS lines
+  FROM optimization_report
+  WHERE category = ''bounds_check_in_loop''
+  GROUP BY function_id
+  ORDER BY max_depth DESC, checks DESC');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("optimization report: %w", err)
+	}
+
+	var nearMisses, checks int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(CASE WHEN category = 'inline_near_miss' THEN 1 END),
+		   COUNT(CASE WHEN category = 'bounds_check_in_loop' THEN 1 END)
+		 FROM optimization_report`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			nearMisses = stmt.ColumnInt(0)
+			checks = stmt.ColumnInt(1)
+			return nil
+		}})
+
+	prog.Log("Optimization report: %d inlining near misses, %d bounds checks in loops", nearMisses, checks)
 	return nil
 }
 
@@ -1632,6 +1994,8 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('node_kind', 'field', 'Struct field or interface method', NULL),
 ('node_kind', 'composite_lit', 'Struct/slice/map literal', NULL),
 ('node_kind', 'basic_block', 'SSA basic block (for CFG edges)', NULL),
+('node_kind', 'channel', 'Channel created by one make(chan) site, with all its senders, receivers and closers', 'Properties: {"elem_type":"struct{}","buffer":1,"fields":["Manager.triggerReload"],"senders":[…],"receivers":[…],"closers":[…]}'),
+('node_kind', 'ssa_instr', 'SSA instruction inside a basic block (only with -ssa-instrs)', 'Properties: {"opcode":"Phi","text":"t3 = phi [0: t1, 1: t2] #x","operands":["t1","t2"]}'),
 ('node_kind', 'type_param', 'Generic type parameter (Go 1.18+)', NULL),
 ('node_kind', 'import', 'Import declaration', NULL),
 ('node_kind', 'doc', 'Doc comment', NULL),
@@ -1643,6 +2007,12 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 INSERT INTO schema_docs (category, name, description, example) VALUES
 ('edge_kind', 'ast', 'Parent→child in syntax tree', 'function → parameter'),
 ('edge_kind', 'cfg', 'Control flow: basic_block→basic_block', 'Properties: {"label":"true"/"false"} for if branches'),
+('edge_kind', 'chan_flow', 'Channel send→receive on the same channel (traced through fields, calls, returns, select)', NULL),
+('edge_kind', 'chan_close', 'close(ch)→receive on the same channel it releases', NULL),
+('edge_kind', 'channel_op', 'Send/receive/close site→channel node', 'Properties: {"op":"send"|"recv"|"close"}'),
+('edge_kind', 'block_instr', 'Basic block→SSA instruction, in order', 'Properties: {"index": N}'),
+('edge_kind', 'ssa_operand', 'SSA def-use: defining instruction→instruction using it as an operand', 'Properties: {"operand": N}'),
+('edge_kind', 'ssa_source', 'SSA instruction→AST node at the same position', NULL),
 ('edge_kind', 'cdg', 'Control dependence: block depends on branch', NULL),
 ('edge_kind', 'dom', 'Dominator tree edge', NULL),
 ('edge_kind', 'pdom', 'Post-dominator tree edge', NULL),
@@ -1659,7 +2029,7 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('edge_kind', 'scope', 'Block→enclosing scope (lexical scoping)', NULL),
 ('edge_kind', 'ref', 'Identifier→its definition', NULL),
 ('edge_kind', 'eval_type', 'Expression→its type declaration', NULL),
-('edge_kind', 'argument', 'Call→argument expression', 'Properties: {"index": N}'),
+('edge_kind', 'argument', 'Call→argument expression; string arguments whose possible values are all constant (literals, +, fmt.Sprintf, constant parameters and results) carry const_values', 'Properties: {"index": N, "const_values": ["..."]}'),
 ('edge_kind', 'receiver', 'Method call→receiver expression', NULL),
 ('edge_kind', 'doc', 'Declaration→its doc comment', NULL),
 ('edge_kind', 'initializer', 'Variable→its initializing expression', NULL),
@@ -1687,9 +2057,18 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('node_property', 'sync_kind', 'Call is sync primitive', 'mutex_lock'),
 ('node_property', 'struct_tag', 'Struct field tag', 'json:"name,omitempty"'),
 ('node_property', 'inlineable', 'Function can be inlined by compiler', 'true'),
-('node_property', 'heap_escapes', 'Variable escapes to heap (GC pressure)', 'true/false'),
+('node_property', 'heap_escapes', 'Value escapes to heap (GC pressure); set on the exact node the compiler reports, like escape', 'true/false'),
+('node_property', 'escape', 'Heap escape the compiler reported for this exact local, parameter, call, composite literal or func literal (needs escape analysis)', 'moved_to_heap, escapes_to_heap, leaking_param'),
+('node_property', 'escape_chain', 'Compiler (-m=2) explanation of the escape: [{flow, expr, reason, site, file, line}], site being the AST node of each step', '[{"flow":"x ← &y","expr":"x := &y","reason":"assign",...}]'),
+('edge_kind', 'escape_flow', 'Escape explanation hop between the local/parameter/allocation nodes of its variables (results: the function node); {heap} ends the chain', 'Properties: {"flow":"z ← x","reasons":["assign"],"escape":"<escaping node>"}'),
 ('node_property', 'taint_role', 'Security taint classification', 'source/sink/barrier/propagator'),
-('node_property', 'taint_category', 'Taint category detail', 'http_input, sql_injection');
+('node_property', 'taint_category', 'Taint category detail', 'http_input, sql_injection'),
+('node_property', 'reachable', 'Function is reachable from an entry point (main, init, HTTP handler, library exported API, tests)', 'true/false'),
+('node_property', 'reach_entry', 'Kind of the nearest entry point', 'main, init, http_handler, exported_api, test'),
+('node_property', 'reach_depth', 'Call depth from the nearest entry point', '3'),
+('node_property', 'reach_parent', 'Caller one step closer to the entry point on a shortest call path (absent on entry points); see reachability_witness', 'cmd/prometheus::main@...'),
+('node_property', 'dead_subgraph', 'Root function ID of the dead subgraph this unreachable function belongs to', NULL),
+('node_property', 'dead_root', 'Function is the reported root of a dead subgraph', 'true');
 
 -- Tables
 INSERT INTO schema_docs (category, name, description, example) VALUES
@@ -1722,7 +2101,9 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
 ('view', 'v_package_stability', 'Package stability metrics: afferent/efferent coupling, instability index, abstractness', NULL),
 ('view', 'v_control_flow_profile', 'Control flow breakdown per function: if/for/switch/select/return/defer/go counts', NULL),
 ('finding', 'risk_score', 'Composite bug-risk score combining complexity, LOC, fan-in, fan-out', NULL),
-('finding', 'dead_code', 'Internal functions with zero callers (unreachable code)', NULL),
+('finding', 'unreachable_function', 'Dead subgraph not reachable from main, init, HTTP handlers or library exported API (one finding per subgraph root)', NULL),
+('query', 'reachability_witness', 'Shortest call path from an entry point to a function', NULL),
+('query', 'dead_subgraph_members', 'All functions in the same dead subgraph as a function', NULL),
 ('finding', 'interface_bloat', 'Interfaces with 5+ methods (Go idiom prefers small interfaces)', NULL),
 ('finding', 'similar_function', 'Structurally similar function pairs (potential clones)', NULL),
 ('query', 'dependency_depth', 'Package dependency depth from leaf packages', NULL),
@@ -1899,23 +2280,22 @@ INSERT INTO findings (category, severity, node_id, file, line, message, details)
   ) DESC
   LIMIT 200;
 
--- Dead code: internal functions with zero callers that aren't entry points
+-- Unreachable functions: dead subgraphs not reachable from any entry point
+-- (main, init, HTTP handlers, library exported API). One finding per subgraph,
+-- anchored at its root; the details list the remaining members.
 INSERT INTO findings (category, severity, node_id, file, line, message, details)
-  SELECT 'dead_code', 'warning', n.id, n.file, n.line,
-    'unreachable function ' || n.name || ' (zero callers)',
-    json_object('name', n.name, 'package', n.package)
+  SELECT 'unreachable_function', 'warning', n.id, n.file, n.line,
+    'unreachable function ' || n.name ||
+      CASE WHEN json_extract(n.properties, '$.dead_subgraph_size') > 1
+        THEN ' (dead subgraph of ' || json_extract(n.properties, '$.dead_subgraph_size') || ' functions)'
+        ELSE '' END,
+    json_object('name', n.name, 'package', n.package,
+                'subgraph_size', json_extract(n.properties, '$.dead_subgraph_size'),
+                'roots', json(json_extract(n.properties, '$.dead_subgraph_roots')),
+                'members', json(json_extract(n.properties, '$.dead_subgraph_members')))
   FROM nodes n
-  LEFT JOIN metrics m ON m.function_id = n.id
   WHERE n.kind = 'function'
-    AND COALESCE(m.fan_in, 0) = 0
-    AND n.name NOT GLOB '[A-Z]*'
-    AND n.name NOT IN ('main', 'init')
-    AND n.name NOT LIKE '%Test%'
-    AND n.name NOT LIKE '%Benchmark%'
-    AND n.name NOT LIKE '%Example%'
-    AND n.package IS NOT NULL
-    AND n.package NOT LIKE 'cmd/%'
-    AND n.id NOT LIKE 'ext::%';
+    AND json_extract(n.properties, '$.dead_root') = 1;
 
 -- Interface bloat: interfaces with many methods (Go prefers small interfaces)
 INSERT INTO findings (category, severity, node_id, file, line, message, details)
@@ -2017,6 +2397,31 @@ INSERT INTO queries (name, description, sql) VALUES
   WHERE m1.function_id = :function_id
   ORDER BY ABS(m1.loc - m2.loc), n2.package, n2.name');
 
+INSERT INTO queries (name, description, sql) VALUES
+('reachability_witness',
+ 'Shortest call path from an entry point to a function (empty if unreachable)',
+ 'WITH RECURSIVE witness(id) AS (
+    SELECT id FROM nodes WHERE id = :function_id AND json_extract(properties, ''$.reachable'') = 1
+    UNION ALL
+    SELECT json_extract(n.properties, ''$.reach_parent'')
+    FROM witness w JOIN nodes n ON n.id = w.id
+    WHERE json_extract(n.properties, ''$.reach_parent'') IS NOT NULL
+  )
+  SELECT json_extract(n.properties, ''$.reach_depth'') AS step, n.id AS function_id, n.name, n.package, n.file, n.line,
+    json_extract(n.properties, ''$.reach_entry'') AS entry_kind
+  FROM witness w JOIN nodes n ON n.id = w.id
+  ORDER BY step');
+
+INSERT INTO queries (name, description, sql) VALUES
+('dead_subgraph_members',
+ 'All unreachable functions in the same dead subgraph as a function',
+ 'SELECT n.id, n.name, n.package, n.file, n.line,
+    json_extract(n.properties, ''$.dead_root'') AS is_root
+  FROM nodes f
+  JOIN nodes n ON json_extract(n.properties, ''$.dead_subgraph'') = json_extract(f.properties, ''$.dead_subgraph'')
+  WHERE f.id = :function_id
+  ORDER BY is_root DESC, n.package, n.name');
+
 `
 	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
 		return err
@@ -2029,7 +2434,7 @@ INSERT INTO queries (name, description, sql) VALUES
 		dest *int64
 	}{
 		{"risk_score", &riskCount},
-		{"dead_code", &deadCount},
+		{"unreachable_function", &deadCount},
 		{"interface_bloat", &bloatCount},
 		{"similar_function", &simCount},
 	} {
@@ -2045,7 +2450,7 @@ INSERT INTO queries (name, description, sql) VALUES
 			})
 	}
 
-	prog.Log("Advanced: %d risk scores, %d dead code, %d interface bloat, %d similar pairs, 2 views, 5 queries",
+	prog.Log("Advanced: %d risk scores, %d dead subgraphs, %d interface bloat, %d similar pairs, 2 views, 7 queries",
 		riskCount, deadCount, bloatCount, simCount)
 	return nil
 }
@@ -3438,117 +3843,1178 @@ INSERT INTO queries (name, description, sql) VALUES
 	return nil
 }
 
-// createSCIPSymbols generates SCIP (Source Code Intelligence Protocol) compatible
-// symbol identifiers for cross-repository code navigation.
-func createSCIPSymbols(conn *sqlite.Conn, prog *Progress) error {
+// createProgramDependence materializes a statement-level program dependence
+// graph in pdg_edges (source → target: target depends on source) for slicing.
+// Data dependence comes from dfg plus def→use of parameters and locals via
+// ref; control dependence lifts block-level cdg edges to statements through
+// branch_cond and in_block; param_in/param_out connect call sites to callee
+// parameters and return statements and are marked interprocedural.
+//
+// Interprocedural slices use the two-phase traversal of Horwitz, Reps and
+// Binkley: a backward slice first ascends into callers (skipping param_out),
+// then descends into callees (skipping param_in); forward slices mirror this.
+func createProgramDependence(conn *sqlite.Conn, prog *Progress) error {
 	ddl := `
-CREATE TABLE scip_symbols (
-    node_id TEXT PRIMARY KEY,
-    scip_id TEXT NOT NULL,
+CREATE TABLE pdg_edges (
+    source TEXT NOT NULL,
+    target TEXT NOT NULL,
     kind TEXT NOT NULL,
-    package TEXT,
-    display_name TEXT
-);
+    interprocedural INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (source, target, kind)
+) WITHOUT ROWID;
 
--- Functions: scip-go gomod github.com/prometheus/prometheus v0 package/name().
-INSERT INTO scip_symbols (node_id, scip_id, kind, package, display_name)
-SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
-  REPLACE(n.package, '/', '.') || '/' || n.name || '().',
-  'function', n.package, n.name
-FROM nodes n
-WHERE n.kind = 'function'
-  AND n.name NOT LIKE '%.%'
-  AND n.package IS NOT NULL AND n.name != '';
+-- Data: SSA def→use
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT source, target, 'data' FROM edges WHERE kind = 'dfg' AND source != target;
 
--- Methods: scip-go gomod github.com/prometheus/prometheus v0 package/Type#Method().
-INSERT INTO scip_symbols (node_id, scip_id, kind, package, display_name)
-SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
-  REPLACE(n.package, '/', '.') || '/' ||
-  REPLACE(REPLACE(SUBSTR(n.name, 1, INSTR(n.name, '.') - 1), '(*', ''), ')', '') ||
-  '#' || SUBSTR(n.name, INSTR(n.name, '.') + 1) || '().',
-  'method', n.package, n.name
-FROM nodes n
-WHERE n.kind = 'function'
-  AND n.name LIKE '%.%'
-  AND n.package IS NOT NULL;
+-- Data: parameter/local definition → identifier that reads it
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT e.target, e.source, 'data'
+FROM edges e
+JOIN nodes d ON d.id = e.target AND d.kind IN ('parameter', 'local')
+WHERE e.kind = 'ref' AND e.source != e.target;
+
+-- Data: argument expression → call, RHS → declared/reassigned variable
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT target, source, 'data' FROM edges
+WHERE kind IN ('argument', 'initializer', 'assigned_from') AND source != target;
+
+-- Data: RHS → assignment statement → assigned variable, so the statement
+-- (and whatever controls it) joins slices through the variable
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT e.target, a.source, 'data'
+FROM edges e
+JOIN edges a ON a.target = e.target AND a.kind = 'ast'
+JOIN nodes an ON an.id = a.source AND an.kind = 'assign'
+WHERE e.kind IN ('initializer', 'assigned_from');
 
--- Types: scip-go gomod github.com/prometheus/prometheus v0 package/TypeName#
-INSERT OR IGNORE INTO scip_symbols (node_id, scip_id, kind, package, display_name)
-SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
-  REPLACE(n.package, '/', '.') || '/' || n.name || '#',
-  'type', n.package, n.name
-FROM nodes n
-WHERE n.kind = 'type_decl'
-  AND n.package IS NOT NULL AND n.name != '';
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT a.source, e.source, 'data'
+FROM edges e
+JOIN edges a ON a.target = e.target AND a.kind = 'ast'
+JOIN nodes an ON an.id = a.source AND an.kind = 'assign'
+WHERE e.kind IN ('initializer', 'assigned_from');
 
--- Packages: scip-go gomod github.com/prometheus/prometheus v0 package/
-INSERT OR IGNORE INTO scip_symbols (node_id, scip_id, kind, package, display_name)
-SELECT n.id,
-  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
-  REPLACE(n.package, '/', '.') || '/',
-  'package', n.package, n.name
-FROM nodes n
-WHERE n.kind = 'package'
-  AND n.package IS NOT NULL;
+-- Data: operand → enclosing expression or return statement
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT e.target, e.source, 'data'
+FROM edges e
+JOIN nodes p ON p.id = e.source
+WHERE e.kind = 'ast'
+  AND p.kind IN ('unary_expr', 'binary_expr', 'index_expr', 'slice_expr',
+                 'type_assert_expr', 'key_value_expr', 'composite_lit', 'return', 'send');
+
+-- Control: branch condition → statements in control-dependent blocks
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT bc.target, ib.source, 'control'
+FROM edges cd
+JOIN edges bc ON bc.source = cd.source AND bc.kind = 'branch_cond'
+JOIN edges ib ON ib.target = cd.target AND ib.kind = 'in_block'
+WHERE cd.kind = 'cdg' AND bc.target != ib.source;
+
+-- Control (structural): if/for/switch condition → statements directly in
+-- its body or else branch. Covers statements SSA folds away entirely
+-- (constant assignments, empty branches) and so never lands in a block.
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT c.target, st.target, 'control'
+FROM edges c
+JOIN edges b ON b.source = c.source AND b.kind = 'ast'
+JOIN nodes bn ON bn.id = b.target AND bn.kind = 'block'
+JOIN edges st ON st.source = bn.id AND st.kind = 'ast'
+WHERE c.kind = 'condition' AND st.target != c.target;
+
+INSERT OR IGNORE INTO pdg_edges (source, target, kind)
+SELECT c.target, b.target, 'control'
+FROM edges c
+JOIN edges b ON b.source = c.source AND b.kind = 'ast'
+JOIN nodes bn ON bn.id = b.target AND bn.kind = 'if'
+WHERE c.kind = 'condition';
+
+-- Interprocedural: actual argument → formal parameter
+INSERT OR IGNORE INTO pdg_edges (source, target, kind, interprocedural)
+SELECT source, target, 'param_in', 1 FROM edges WHERE kind = 'param_in';
+
+-- Interprocedural: callee return statement → call site
+INSERT OR IGNORE INTO pdg_edges (source, target, kind, interprocedural)
+SELECT r.id, e.target, 'param_out', 1
+FROM edges e
+JOIN nodes r ON r.parent_function = e.source AND r.kind = 'return'
+WHERE e.kind = 'param_out';
 
-CREATE INDEX idx_scip_kind ON scip_symbols(kind);
-CREATE INDEX idx_scip_pkg ON scip_symbols(package);
+CREATE INDEX idx_pdg_target ON pdg_edges(target);
 
 INSERT INTO schema_docs (category, name, description, example) VALUES
-('table', 'scip_symbols', 'SCIP-compatible symbol identifiers for cross-repository navigation', 'SELECT * FROM scip_symbols WHERE kind = ''method'' AND display_name LIKE ''Manager%''');
+('table', 'pdg_edges', 'Statement-level program dependence graph (data + control + param_in/param_out) for slicing; target depends on source', 'SELECT * FROM pdg_edges WHERE target = :node_id'),
+('edge_kind', 'in_block', 'AST node→basic block containing one of its SSA instructions', NULL),
+('edge_kind', 'branch_cond', 'Branching basic block→AST node of its condition', NULL),
+('edge_kind', 'assigned_from', 'Variable→RHS expression of a later plain or op-assignment (like initializer)', NULL);
 
 INSERT INTO queries (name, description, sql) VALUES
-('scip_lookup', 'Look up SCIP symbol for a node',
- 'SELECT s.scip_id, s.kind, s.display_name, n.file, n.line FROM scip_symbols s JOIN nodes n ON n.id = s.node_id WHERE s.display_name LIKE ? ORDER BY s.kind, s.display_name');
+('program_slice_backward',
+ 'Backward slice: statements affecting :node_id (set :interprocedural to 1 to cross calls)',
+ 'WITH RECURSIVE
+  up(node_id) AS (
+    SELECT :node_id
+    UNION
+    SELECT p.source FROM up JOIN pdg_edges p ON p.target = up.node_id
+    WHERE p.kind != ''param_out'' AND (p.interprocedural = 0 OR :interprocedural = 1)
+  ),
+  down(node_id) AS (
+    SELECT node_id FROM up
+    UNION
+    SELECT p.source FROM down JOIN pdg_edges p ON p.target = down.node_id
+    WHERE p.kind != ''param_in'' AND (p.interprocedural = 0 OR :interprocedural = 1)
+  )
+  SELECT n.id, n.kind, n.name, n.file, n.line, n.parent_function
+  FROM down JOIN nodes n ON n.id = down.node_id
+  ORDER BY n.file, n.line'),
+('program_slice_forward',
+ 'Forward slice: statements affected by :node_id (set :interprocedural to 1 to cross calls)',
+ 'WITH RECURSIVE
+  up(node_id) AS (
+    SELECT :node_id
+    UNION
+    SELECT p.target FROM up JOIN pdg_edges p ON p.source = up.node_id
+    WHERE p.kind != ''param_in'' AND (p.interprocedural = 0 OR :interprocedural = 1)
+  ),
+  down(node_id) AS (
+    SELECT node_id FROM up
+    UNION
+    SELECT p.target FROM down JOIN pdg_edges p ON p.source = down.node_id
+    WHERE p.kind != ''param_out'' AND (p.interprocedural = 0 OR :interprocedural = 1)
+  )
+  SELECT n.id, n.kind, n.name, n.file, n.line, n.parent_function
+  FROM down JOIN nodes n ON n.id = down.node_id
+  ORDER BY n.file, n.line');
 `
 	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
-		return fmt.Errorf("scip symbols: %w", err)
+		return fmt.Errorf("program dependence: %w", err)
 	}
 
-	var total int
-	sqlitex.ExecuteTransient(conn, "SELECT COUNT(*) FROM scip_symbols",
+	counts := map[string]int{}
+	sqlitex.ExecuteTransient(conn, "SELECT kind, COUNT(*) FROM pdg_edges GROUP BY kind",
 		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
-			total = stmt.ColumnInt(0)
+			counts[stmt.ColumnText(0)] = stmt.ColumnInt(1)
 			return nil
 		}})
 
-	var byKind []string
-	sqlitex.ExecuteTransient(conn, "SELECT kind || '=' || COUNT(*) FROM scip_symbols GROUP BY kind ORDER BY kind",
+	prog.Log("PDG: %d data, %d control, %d param_in, %d param_out edges",
+		counts["data"], counts["control"], counts["param_in"], counts["param_out"])
+	return nil
+}
+
+// createLoopAnalysis reports defers and lock acquisitions inside natural
+// loops (in_loop edges from ExtractLoops). Heap allocations in loops are
+// reported by applyEscapeAnalysis, which owns the compiler diagnostics.
+func createLoopAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+-- Deferred calls pile up until the function returns, not per iteration
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'defer_in_loop', 'warning', d.id, d.file, d.line,
+  'defer in loop in ' || COALESCE(f.name, d.parent_function) ||
+    ': runs only when the function returns',
+  json_object('loop', l.id, 'depth', json_extract(l.properties, '$.depth'),
+    'function', d.parent_function)
+FROM nodes d
+JOIN edges e ON e.source = d.id AND e.kind = 'in_loop'
+JOIN nodes l ON l.id = e.target AND l.kind = 'loop'
+LEFT JOIN nodes f ON f.id = d.parent_function
+WHERE d.kind = 'defer';
+
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'lock_in_loop', 'info', c.id, c.file, c.line,
+  c.name || ' acquired in loop in ' || COALESCE(f.name, c.parent_function),
+  json_object('sync_kind', json_extract(c.properties, '$.sync_kind'), 'loop', l.id,
+    'depth', json_extract(l.properties, '$.depth'), 'function', c.parent_function)
+FROM nodes c
+JOIN edges e ON e.source = c.id AND e.kind = 'in_loop'
+JOIN nodes l ON l.id = e.target AND l.kind = 'loop'
+LEFT JOIN nodes f ON f.id = c.parent_function
+WHERE c.kind = 'call'
+  AND json_extract(c.properties, '$.sync_kind') IN ('mutex_lock', 'rwmutex_lock', 'rwmutex_rlock');
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_kind', 'loop', 'Natural loop from a back edge of the SSA CFG, one per header block', 'Properties: {"header":"…::bb1","latches":["…::bb3"],"exits":["…::bb2"],"depth":1,"num_blocks":3,"loop_kind":"range"}'),
+('node_property', 'depth', 'Loop nesting depth (1 = outermost) on loop nodes', '2'),
+('node_property', 'parent_loop', 'Enclosing loop ID on nested loop nodes', NULL),
+('node_property', 'loop_kind', 'for, range, or goto (no enclosing for statement)', 'range'),
+('edge_kind', 'in_loop', 'Basic block or AST node→innermost loop containing it; nested loop→enclosing loop', NULL),
+('edge_kind', 'loop_stmt', 'Loop→for/range statement it was derived from', NULL),
+('finding', 'alloc_in_loop', 'Heap allocation (moved_to_heap / escapes_to_heap) on a line inside a loop (needs escape analysis)', NULL),
+('finding', 'defer_in_loop', 'defer inside a loop body; deferred calls accumulate until return', NULL),
+('finding', 'lock_in_loop', 'Mutex Lock/RLock acquired inside a loop body', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('function_loops',
+ 'Loops of a function with nesting depth and statement count (set :function_id)',
+ 'SELECT l.id, l.name, l.file, l.line,
+    json_extract(l.properties, ''$.depth'') AS depth,
+    json_extract(l.properties, ''$.loop_kind'') AS loop_kind,
+    json_extract(l.properties, ''$.parent_loop'') AS parent_loop,
+    (SELECT COUNT(*) FROM edges e JOIN nodes n ON n.id = e.source
+     WHERE e.target = l.id AND e.kind = ''in_loop'' AND n.kind NOT IN (''basic_block'', ''loop'')) AS statements
+  FROM nodes l
+  WHERE l.kind = ''loop'' AND l.parent_function = :function_id
+  ORDER BY l.line');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("loop analysis: %w", err)
+	}
+
+	counts := map[string]int{}
+	sqlitex.ExecuteTransient(conn,
+		`SELECT category, COUNT(*) FROM findings
+		 WHERE category IN ('alloc_in_loop', 'defer_in_loop', 'lock_in_loop') GROUP BY category`,
 		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
-			byKind = append(byKind, stmt.ColumnText(0))
+			counts[stmt.ColumnText(0)] = stmt.ColumnInt(1)
 			return nil
 		}})
 
-	prog.Log("SCIP symbols: %d total (%s)", total, strings.Join(byKind, ", "))
+	prog.Log("Loops: %d allocations, %d defers, %d lock acquisitions in loops",
+		counts["alloc_in_loop"], counts["defer_in_loop"], counts["lock_in_loop"])
 	return nil
 }
 
-// createCommunicationPatterns builds Honda session type-inspired protocol
-// analysis connecting Prometheus with its ecosystem services (adapter, alertmanager, etc.).
-// Inspired by Honda 1998 (binary session types) and Honda 2008 (multiparty asynchronous session types).
-func createCommunicationPatterns(conn *sqlite.Conn, prog *Progress) error {
+// createGoroutineAnalysis turns the goroutine_leaks recorded by
+// AnalyzeGoroutines into possible_goroutine_leak findings, one per blocking
+// site, and documents the spawn tree properties.
+func createGoroutineAnalysis(conn *sqlite.Conn, prog *Progress) error {
 	ddl := `
--- ═══════════════════════════════════════════════════════════════════
--- Communication Patterns — Honda Session Type Analysis
--- Based on Honda 1998 (binary), Honda 2008 (multiparty), Yoshida & Hou 2024 (corrections)
--- ═══════════════════════════════════════════════════════════════════
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'possible_goroutine_leak', 'warning',
+  COALESCE(NULLIF(json_extract(j.value, '$.site'), ''), g.id),
+  json_extract(j.value, '$.file'), json_extract(j.value, '$.line'),
+  'goroutine ' || g.name || ' may block forever: ' ||
+    CASE json_extract(j.value, '$.op')
+      WHEN 'send' THEN 'send'
+      WHEN 'recv' THEN 'receive'
+      WHEN 'range_recv' THEN 'receive loop'
+      WHEN 'select' THEN 'select'
+      WHEN 'wg_wait' THEN 'WaitGroup.Wait'
+      ELSE json_extract(j.value, '$.op') END ||
+    ' with no ' || json_extract(j.value, '$.missing') ||
+    COALESCE(' (channel made at ' || json_extract(j.value, '$.channel') || ')', ''),
+  json_object('goroutine', g.id, 'op', json_extract(j.value, '$.op'),
+    'missing', json_extract(j.value, '$.missing'),
+    'channel', json_extract(j.value, '$.channel'),
+    'via', json_extract(j.value, '$.via'),
+    'spawn_sites', json(json_extract(g.properties, '$.spawn_sites')))
+FROM nodes g, json_each(json_extract(g.properties, '$.goroutine_leaks')) j
+WHERE g.kind = 'function';
 
--- Protocol definitions with Honda session type notation
--- Client session: "!" = send, "?" = receive, "+" = internal choice, "&" = external choice
--- Server session: dual of client (swap !/?, swap +/&)
-CREATE TABLE comm_protocols (
-    id TEXT PRIMARY KEY,
-    name TEXT NOT NULL,
-    description TEXT,
-    session_type_client TEXT,   -- Honda notation from client perspective
-    session_type_server TEXT,   -- dual type (server perspective)
-    transport TEXT,             -- http, grpc, channel
-    encoding TEXT,              -- json, protobuf, text/plain
-    pattern TEXT,               -- request_response, streaming, fan_out, pipeline
-    is_dual BOOLEAN DEFAULT 1   -- true if client/server types are proper duals
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('edge_kind', 'spawn', 'go statement → reference to the launched function; with function_level, spawning function → spawned function (statically known targets)', 'Properties (function_level): {"site": "<go stmt ID>", "function_level": true}'),
+('node_property', 'goroutine', 'Function is started by at least one go statement', 'true'),
+('node_property', 'spawn_sites', 'go statement IDs that start this function', NULL),
+('node_property', 'spawn_depth', 'Goroutine nesting below the entry point (1 = spawned by main/handler code)', '1'),
+('node_property', 'spawn_parent', 'Goroutine root (entry point or spawned function) whose code spawns this one', NULL),
+('node_property', 'spawn_root', 'Entry point at the root of the spawn tree', NULL),
+('node_property', 'goroutine_leaks', 'Blocking operations with no unblocking counterpart, in the function or a helper it calls (via = helper function ID)', '[{"site":"…","op":"send","missing":"receiver","channel":"cmd/app/main.go:12","via":"…"}]'),
+('finding', 'possible_goroutine_leak', 'Spawned goroutine blocks on a send/receive/select/WaitGroup.Wait nothing can unblock', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('spawn_tree',
+ 'Goroutine spawn tree below an entry point or goroutine (set :function_id)',
+ 'WITH RECURSIVE tree(id, depth) AS (
+    SELECT :function_id, 0
+    UNION
+    SELECT n.id, tree.depth + 1
+    FROM tree JOIN nodes n ON json_extract(n.properties, ''$.spawn_parent'') = tree.id
+    WHERE tree.depth < 20
+  )
+  SELECT t.depth, n.id, n.name, n.package, n.file, n.line,
+    json_array_length(json_extract(n.properties, ''$.goroutine_leaks'')) AS leaks
+  FROM tree t JOIN nodes n ON n.id = t.id
+  ORDER BY t.depth, n.package, n.name');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("goroutine analysis: %w", err)
+	}
+
+	var goroutines, leaks int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT (SELECT COUNT(*) FROM nodes WHERE kind = 'function'
+		           AND json_extract(properties, '$.goroutine') = 1),
+		        (SELECT COUNT(*) FROM findings WHERE category = 'possible_goroutine_leak')`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			goroutines = stmt.ColumnInt(0)
+			leaks = stmt.ColumnInt(1)
+			return nil
+		}})
+
+	prog.Log("Goroutines: %d goroutine functions, %d possible leaks", goroutines, leaks)
+	return nil
+}
+
+// createPanicAnalysis reports spawned goroutines whose panics are not
+// recovered (panic_unrecovered from ComputePanicPropagation): an unrecovered
+// panic in any goroutine terminates the whole process.
+func createPanicAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'goroutine_without_recover', 'warning', g.id, g.file, g.line,
+  'goroutine ' || g.name || ' may panic without recover (' ||
+    json_extract(g.properties, '$.panic_kind') ||
+    COALESCE(' at ' || o.file || ':' || o.line, '') ||
+    CASE WHEN json_extract(g.properties, '$.panic_via') != ''
+      THEN ' via ' || COALESCE(v.name, json_extract(g.properties, '$.panic_via')) ELSE '' END || ')',
+  json_object('panic_kind', json_extract(g.properties, '$.panic_kind'),
+    'origin', json_extract(g.properties, '$.panic_origin'),
+    'via', json_extract(g.properties, '$.panic_via'),
+    'recovers', COALESCE(json_extract(g.properties, '$.recovers'), 0),
+    'spawn_sites', json(json_extract(g.properties, '$.spawn_sites')))
+FROM nodes g
+LEFT JOIN nodes o ON o.id = json_extract(g.properties, '$.panic_origin')
+LEFT JOIN nodes v ON v.id = json_extract(g.properties, '$.panic_via')
+WHERE g.kind = 'function' AND json_extract(g.properties, '$.panic_unrecovered') = 1;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'may_panic', 'Function may panic and the panic escapes any deferred recover. Only modeled origins count: explicit panic, log.Panic*, Must* helpers outside the analyzed modules and stores into a constant-nil map; runtime errors (bounds, nil dereference, type assertion) and other standard library panics are not modeled, so false means none of these was found', 'true'),
+('node_property', 'panic_kind', 'Origin kind: panic, log_panic, must, nil_map_write', 'must'),
+('node_property', 'panic_origin', 'AST node ID of the panic site the may_panic witness starts from', NULL),
+('node_property', 'panic_via', 'Callee the panic propagates through (empty when the function panics itself)', NULL),
+('node_property', 'may_exit', 'Function may terminate the process (os.Exit, log.Fatal*), directly or via callees', 'true'),
+('node_property', 'exit_origin', 'AST node ID of the exit call', NULL),
+('node_property', 'exit_via', 'Callee the exit is reached through', NULL),
+('node_property', 'recovers', 'Function defers a function that calls recover()', 'true'),
+('node_property', 'panic_unrecovered', 'Spawned goroutine that may panic with no recover on some path', 'true'),
+('finding', 'goroutine_without_recover', 'Goroutine entry that may panic with no deferred recover on every path; the panic kills the process', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('panic_path',
+ 'Call chain from a function to the panic it may raise, following panic_via (set :function_id)',
+ 'WITH RECURSIVE chain(id, step) AS (
+    SELECT :function_id, 0
+    UNION ALL
+    SELECT json_extract(n.properties, ''$.panic_via''), chain.step + 1
+    FROM chain JOIN nodes n ON n.id = chain.id
+    WHERE json_extract(n.properties, ''$.panic_via'') != '''' AND chain.step < 50
+  )
+  SELECT c.step, n.id, n.name, n.package, n.file, n.line,
+    json_extract(n.properties, ''$.panic_kind'') AS panic_kind,
+    o.file AS origin_file, o.line AS origin_line
+  FROM chain c JOIN nodes n ON n.id = c.id
+  LEFT JOIN nodes o ON o.id = json_extract(n.properties, ''$.panic_origin'')
+  ORDER BY c.step');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("panic analysis: %w", err)
+	}
+
+	var mayPanic, noRecover int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT (SELECT COUNT(*) FROM nodes WHERE kind = 'function'
+		           AND json_extract(properties, '$.may_panic') = 1),
+		        (SELECT COUNT(*) FROM findings WHERE category = 'goroutine_without_recover')`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			mayPanic = stmt.ColumnInt(0)
+			noRecover = stmt.ColumnInt(1)
+			return nil
+		}})
+
+	prog.Log("Panics: %d functions may panic, %d goroutines without recover", mayPanic, noRecover)
+	return nil
+}
+
+// createLockAnalysis turns the lock_issues recorded by AnalyzeLockPairing
+// into findings, one per issue, with the offending block path in details.
+func createLockAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT json_extract(i.value, '$.kind'),
+  CASE json_extract(i.value, '$.kind') WHEN 'blocking_under_lock' THEN 'warning' ELSE 'error' END,
+  COALESCE(NULLIF(json_extract(i.value, '$.site'), ''), f.id),
+  COALESCE(NULLIF(json_extract(i.value, '$.file'), ''), f.file),
+  COALESCE(NULLIF(json_extract(i.value, '$.line'), 0), f.line),
+  f.name || ': ' || json_extract(i.value, '$.detail'),
+  json_object('function', f.id,
+    'lock', json_extract(i.value, '$.lock'),
+    'path', json(json_extract(i.value, '$.path')))
+FROM nodes f, json_each(json_extract(f.properties, '$.lock_issues')) i
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.lock_issues') IS NOT NULL;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'lock_issues', 'Lock pairing problems in the function: [{kind, lock, site, file, line, path, detail}]; path lists the basic block IDs of the offending path', NULL),
+('finding', 'double_lock', 'Lock (or RLock under a write lock) of a sync.Mutex/RWMutex already held on every path to it', NULL),
+('finding', 'unlock_without_lock', 'Unlock reachable on a path that does not hold the lock, in a function that locks it elsewhere', NULL),
+('finding', 'lock_not_released', 'Return reachable with the lock held and no deferred unlock, in a function that unlocks it on other paths', NULL),
+('finding', 'blocking_under_lock', 'Channel send/receive, blocking select, WaitGroup.Wait, time.Sleep or network I/O while a lock is held', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('lock_issue_paths',
+ 'Lock findings with the offending path of blocks expanded, one row per block (set :function_id)',
+ 'SELECT fi.category, json_extract(fi.details, ''$.lock'') AS lock, p.key AS step,
+    b.id AS block_id, b.line AS block_line
+  FROM findings fi, json_each(json_extract(fi.details, ''$.path'')) p
+  JOIN nodes b ON b.id = p.value
+  WHERE fi.category IN (''double_lock'', ''unlock_without_lock'', ''lock_not_released'', ''blocking_under_lock'')
+    AND json_extract(fi.details, ''$.function'') = :function_id
+  ORDER BY fi.category, fi.line, p.key');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("lock analysis: %w", err)
+	}
+
+	var issues int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*) FROM findings WHERE category IN
+		   ('double_lock', 'unlock_without_lock', 'lock_not_released', 'blocking_under_lock')`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			issues = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	prog.Log("Locks: %d lock pairing findings", issues)
+	return nil
+}
+
+// createLockOrder stores the lock_order cycles found by BuildLockOrder (one
+// per strongly connected component) and reports each as a
+// lock_order_inversion finding carrying the acquisition chain of every
+// edge in the cycle.
+func createLockOrder(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+CREATE TABLE lock_order_cycles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    cycle_path TEXT NOT NULL,      -- lock IDs joined by ' → ', first lock repeated at the end
+    cycle_length INTEGER NOT NULL,
+    locks TEXT NOT NULL,           -- JSON array of lock IDs
+    orders TEXT NOT NULL           -- JSON array of the lock_order edge properties, in cycle order
+);
+
+-- One cycle per strongly connected component, found by BuildLockOrder and
+-- stored on the lock it starts from.
+INSERT INTO lock_order_cycles (cycle_path, cycle_length, locks, orders)
+SELECT (SELECT GROUP_CONCAT(value, ' → ') FROM (
+          SELECT value FROM json_each(json_extract(l.properties, '$.order_cycle.locks')) ORDER BY key))
+         || ' → ' || l.id,
+  json_array_length(json_extract(l.properties, '$.order_cycle.locks')),
+  json_extract(l.properties, '$.order_cycle.locks'),
+  json_extract(l.properties, '$.order_cycle.orders')
+FROM nodes l
+WHERE l.kind = 'lock' AND json_extract(l.properties, '$.order_cycle') IS NOT NULL
+ORDER BY l.id;
+
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'lock_order_inversion', 'warning',
+  COALESCE(s.id, json_extract(c.orders, '$[0].function')),
+  COALESCE(s.file, f.file), COALESCE(s.line, f.line),
+  'lock order inversion: ' || REPLACE(c.cycle_path, 'lock::', '') ||
+    ' (cycle of ' || c.cycle_length || ' locks)',
+  json_object('cycle', json(c.locks), 'orders', json(c.orders))
+FROM lock_order_cycles c
+LEFT JOIN nodes s ON s.id = json_extract(c.orders, '$[0].acquire_site')
+LEFT JOIN nodes f ON f.id = json_extract(c.orders, '$[0].function');
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_kind', 'lock', 'sync.Mutex/RWMutex identified by struct field (shared by all instances) or package variable; located at its declaration', 'Properties: {"lock_sites":4}'),
+('node_property', 'lock_sites', 'Number of Lock/RLock calls on a lock node', '4'),
+('node_property', 'lock', 'Lock node ID on Lock/Unlock calls (alongside sync_kind)', 'lock::scrape.scrapePool.mtx'),
+('edge_kind', 'acquires', 'Function→lock it locks directly', 'Properties: {"site":"<Lock call ID>","mode":"w"|"r"}'),
+('edge_kind', 'lock_order', 'Lock A→lock B: B is acquired (locally or in a callee) while A is held on every path', 'Properties: {"function":"<holder>","held_site":"<A Lock call>","held_mode":"w","mode":"r","acquire_site":"<B Lock call>","chain":["<call site>",…,"<B Lock call>"],"witnesses":2}'),
+('node_property', 'order_cycle', 'On the first lock of a lock_order cycle (one per strongly connected component): {locks, orders} with the lock IDs and lock_order edge properties in cycle order', NULL),
+('table', 'lock_order_cycles', 'One cycle per strongly connected component of the lock_order graph, of any length, with the acquisition chains of each edge', 'SELECT cycle_path, orders FROM lock_order_cycles'),
+('finding', 'lock_order_inversion', 'Locks acquired in conflicting orders on different paths; concurrent execution can deadlock', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('lock_order_graph',
+ 'Lock acquisition order edges with the function that holds the first lock and the chain length',
+ 'SELECT REPLACE(e.source, ''lock::'', '''') AS held, REPLACE(e.target, ''lock::'', '''') AS acquired,
+    f.name AS function, json_array_length(json_extract(e.properties, ''$.chain'')) AS chain_length,
+    json_extract(e.properties, ''$.witnesses'') AS witnesses
+  FROM edges e
+  LEFT JOIN nodes f ON f.id = json_extract(e.properties, ''$.function'')
+  WHERE e.kind = ''lock_order''
+  ORDER BY held, acquired');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("lock order: %w", err)
+	}
+
+	var orders, cycles int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT (SELECT COUNT(*) FROM edges WHERE kind = 'lock_order'),
+		        (SELECT COUNT(*) FROM lock_order_cycles)`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			orders = stmt.ColumnInt(0)
+			cycles = stmt.ColumnInt(1)
+			return nil
+		}})
+
+	prog.Log("Lock order: %d order edges, %d inversion cycles", orders, cycles)
+	return nil
+}
+
+// createRaceAnalysis turns the race_candidates recorded by AnalyzeRaces
+// into race_candidate findings, one per go statement and location, with
+// both access sites and the spawning go statement.
+func createRaceAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'race_candidate', 'warning',
+  COALESCE(NULLIF(json_extract(r.value, '$.goroutine_site'), ''), NULLIF(json_extract(r.value, '$.go_site'), ''), f.id),
+  json_extract(r.value, '$.goroutine_file'), json_extract(r.value, '$.goroutine_line'),
+  'possible data race on ' || json_extract(r.value, '$.location_kind') || ' ' ||
+    json_extract(r.value, '$.location') || ': goroutine ' || json_extract(r.value, '$.goroutine_op') ||
+    ' at line ' || json_extract(r.value, '$.goroutine_line') || ', ' ||
+    CASE json_extract(r.value, '$.other') WHEN 'spawner' THEN f.name ELSE 'another instance' END ||
+    ' ' || json_extract(r.value, '$.other_op') || ' at line ' || json_extract(r.value, '$.other_line'),
+  json_object('function', f.id,
+    'location', json_extract(r.value, '$.location'),
+    'location_kind', json_extract(r.value, '$.location_kind'),
+    'go_site', json_extract(r.value, '$.go_site'),
+    'goroutine_site', json_extract(r.value, '$.goroutine_site'),
+    'goroutine_op', json_extract(r.value, '$.goroutine_op'),
+    'other', json_extract(r.value, '$.other'),
+    'other_site', json_extract(r.value, '$.other_site'),
+    'other_op', json_extract(r.value, '$.other_op'),
+    'other_file', json_extract(r.value, '$.other_file'),
+    'other_line', json_extract(r.value, '$.other_line'))
+FROM nodes f, json_each(json_extract(f.properties, '$.race_candidates')) r
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.race_candidates') IS NOT NULL;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'race_candidates', 'Unsynchronized access pairs around the function''s go statements: [{location, location_kind, go_site, goroutine_site, goroutine_op, other, other_site, other_op, …}]', NULL),
+('finding', 'race_candidate', 'Captured variable, package variable, struct field or map written in a goroutine and accessed by its spawner (or another instance of a loop-spawned goroutine) with no common lock and no channel/WaitGroup ordering', NULL);
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("race analysis: %w", err)
+	}
+
+	var races int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*) FROM findings WHERE category = 'race_candidate'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			races = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	prog.Log("Races: %d race candidates", races)
+	return nil
+}
+
+// createGuardedByAnalysis turns the unguarded_accesses recorded by
+// InferGuardedBy into unguarded_access findings and documents the inferred
+// guarded_by relation.
+func createGuardedByAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'unguarded_access', 'warning',
+  COALESCE(NULLIF(json_extract(u.value, '$.site'), ''), f.id),
+  json_extract(u.value, '$.file'), json_extract(u.value, '$.line'),
+  f.name || ' ' || CASE json_extract(u.value, '$.op') WHEN 'write' THEN 'writes' ELSE 'reads' END || ' ' ||
+    json_extract(u.value, '$.field') ||
+    CASE WHEN json_extract(u.value, '$.rlock') THEN ' holding only the read lock of ' ELSE ' without holding ' END ||
+    json_extract(u.value, '$.mutex'),
+  json_object('function', f.id,
+    'field', json_extract(u.value, '$.field'),
+    'mutex', json_extract(u.value, '$.mutex'),
+    'op', json_extract(u.value, '$.op'),
+    'rlock', json_extract(u.value, '$.rlock'))
+FROM nodes f, json_each(json_extract(f.properties, '$.unguarded_accesses')) u
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.unguarded_accesses') IS NOT NULL;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('edge_kind', 'guarded_by', 'Struct field→mutex field of the same struct that is held on most of its accesses (inferred); field names the guarded field, since a, b int share one field node', 'Properties: {"field":"scrapePool.activeTargets","locked":5,"accesses":6}'),
+('node_property', 'guarded_by', 'Mutex inferred to guard the field, as Type.field', 'scrapePool.mtx'),
+('node_property', 'guard_ratio', 'Fraction of the field''s accesses made while holding its guard', '0.83'),
+('node_property', 'unguarded_accesses', 'Accesses of guarded fields in the function made without their mutex, or writes under its read lock (rlock): [{field, mutex, op, rlock, site, file, line}]', NULL),
+('finding', 'unguarded_access', 'Access of a field inferred as guarded_by a mutex without holding it, or a write holding only its read lock', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('guarded_fields',
+ 'Inferred guarded_by relations with lock coverage',
+ 'SELECT fld.file, fld.line, fld.name AS field, mu.name AS mutex,
+    json_extract(e.properties, ''$.locked'') AS locked,
+    json_extract(e.properties, ''$.accesses'') AS accesses,
+    json_extract(fld.properties, ''$.guard_ratio'') AS guard_ratio
+  FROM edges e
+  JOIN nodes fld ON fld.id = e.source
+  JOIN nodes mu ON mu.id = e.target
+  WHERE e.kind = ''guarded_by''
+  ORDER BY fld.file, fld.line');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("guarded-by analysis: %w", err)
+	}
+
+	var findings int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*) FROM findings WHERE category = 'unguarded_access'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			findings = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	prog.Log("Guarded-by: %d unguarded_access findings written", findings)
+	return nil
+}
+
+// createContextAnalysis turns the context_issues recorded by
+// AnalyzeContextPropagation into context_dropped findings carrying the
+// chain of context-receiving callers.
+func createContextAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'context_dropped',
+  CASE WHEN json_extract(c.value, '$.kind') IN ('loop_ignores_context', 'unrelated_call') THEN 'info' ELSE 'warning' END,
+  COALESCE(NULLIF(json_extract(c.value, '$.site'), ''), f.id),
+  COALESCE(NULLIF(json_extract(c.value, '$.file'), ''), f.file),
+  COALESCE(NULLIF(json_extract(c.value, '$.line'), 0), f.line),
+  f.name || ' ' || json_extract(c.value, '$.detail'),
+  json_object('function', f.id,
+    'kind', json_extract(c.value, '$.kind'),
+    'callee', json_extract(c.value, '$.callee'),
+    'chain', json(json_extract(c.value, '$.chain')))
+FROM nodes f, json_each(json_extract(f.properties, '$.context_issues')) c
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.context_issues') IS NOT NULL;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'context_issues', 'Places a context-receiving function drops its context: [{kind, site, callee, detail, file, line, chain}]; chain lists context-receiving callers, outermost first', NULL),
+('finding', 'context_dropped', 'Function receiving a context.Context passes Background/TODO or nil to a callee (background_call, nil_call) or, having its own context parameter, a context from a field, global or unrelated call (unrelated_call), starts a long-running goroutine without its context (goroutine_no_context, goroutine_background), or blocks in a loop without checking ctx.Done() (loop_ignores_context)', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('context_drops',
+ 'Dropped contexts by kind with the caller chain expanded to function names',
+ 'SELECT json_extract(fi.details, ''$.kind'') AS kind, fi.file, fi.line, fi.message,
+    (SELECT GROUP_CONCAT(n.name, '' → '') FROM json_each(json_extract(fi.details, ''$.chain'')) ch
+     JOIN nodes n ON n.id = ch.value) AS chain
+  FROM findings fi
+  WHERE fi.category = ''context_dropped''
+  ORDER BY kind, fi.file, fi.line');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("context analysis: %w", err)
+	}
+
+	var dropped int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*) FROM findings WHERE category = 'context_dropped'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			dropped = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	prog.Log("Context: %d dropped contexts", dropped)
+	return nil
+}
+
+// createResourceAnalysis turns the resource_leaks recorded by
+// AnalyzeResources into resource_not_closed findings.
+func createResourceAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'resource_not_closed',
+  CASE WHEN json_extract(r.value, '$.error_return') THEN 'error' ELSE 'warning' END,
+  COALESCE(NULLIF(json_extract(r.value, '$.site'), ''), f.id),
+  json_extract(r.value, '$.file'),
+  json_extract(r.value, '$.line'),
+  f.name || ': ' || json_extract(r.value, '$.resource') || ' from ' || json_extract(r.value, '$.creator') ||
+    CASE
+      WHEN json_extract(r.value, '$.never') THEN ' is never released (' || json_extract(r.value, '$.release') || ')'
+      WHEN json_extract(r.value, '$.error_return') THEN ' is not released (' || json_extract(r.value, '$.release') || ') on the error return at line ' || json_extract(r.value, '$.exit_line')
+      ELSE ' is not released (' || json_extract(r.value, '$.release') || ') on the return at line ' || json_extract(r.value, '$.exit_line')
+    END,
+  json_object('function', f.id,
+    'resource', json_extract(r.value, '$.resource'),
+    'creator', json_extract(r.value, '$.creator'),
+    'release', json_extract(r.value, '$.release'),
+    'exit_line', json_extract(r.value, '$.exit_line'),
+    'error_return', json(CASE WHEN json_extract(r.value, '$.error_return') THEN 'true' ELSE 'false' END))
+FROM nodes f, json_each(json_extract(f.properties, '$.resource_leaks')) r
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.resource_leaks') IS NOT NULL;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'resource_leaks', 'Resources created in the function that reach a return unreleased: [{resource, creator, release, site, file, line, exit_line, error_return, never}]', NULL),
+('finding', 'resource_not_closed', 'File, response body, sql.Rows, connection, listener or ticker dropped on some path without Close/Stop or a hand-off to a callee, closure, field or the caller; error severity when the path is an error return', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('resource_leaks_by_creator',
+ 'Unreleased resources grouped by the call that creates them',
+ 'SELECT json_extract(details, ''$.creator'') AS creator, json_extract(details, ''$.resource'') AS resource,
+    COUNT(*) AS leaks, SUM(json_extract(details, ''$.error_return'')) AS on_error_return
+  FROM findings
+  WHERE category = ''resource_not_closed''
+  GROUP BY creator, resource
+  ORDER BY leaks DESC');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("resource analysis: %w", err)
+	}
+
+	var leaks int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*) FROM findings WHERE category = 'resource_not_closed'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			leaks = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	prog.Log("Resources: %d unreleased resources", leaks)
+	return nil
+}
+
+// createUncheckedErrorAnalysis turns the unchecked_errors recorded by
+// AnalyzeUncheckedErrors into unchecked_error findings.
+func createUncheckedErrorAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'unchecked_error',
+  CASE json_extract(u.value, '$.kind') WHEN 'deferred' THEN 'info' ELSE 'warning' END,
+  COALESCE(NULLIF(json_extract(u.value, '$.site'), ''), f.id),
+  json_extract(u.value, '$.file'),
+  json_extract(u.value, '$.line'),
+  f.name || ': error from ' || json_extract(u.value, '$.callee') ||
+    CASE json_extract(u.value, '$.kind')
+      WHEN 'blank' THEN ' is assigned to _'
+      WHEN 'overwritten' THEN ' is overwritten before being checked'
+      WHEN 'deferred' THEN ' is dropped by defer'
+      WHEN 'go' THEN ' is dropped by go statement'
+      ELSE ' is dropped'
+    END,
+  json_object('function', f.id,
+    'kind', json_extract(u.value, '$.kind'),
+    'callee', json_extract(u.value, '$.callee'),
+    'package', json_extract(u.value, '$.package'))
+FROM nodes f, json_each(json_extract(f.properties, '$.unchecked_errors')) u
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.unchecked_errors') IS NOT NULL;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'unchecked_errors', 'Calls whose error result the function never uses: [{kind (blank, dropped, overwritten, deferred: not for defer x.Close() unless x is a file opened for writing, go), callee, package, site, file, line}]', NULL),
+('finding', 'unchecked_error', 'Error result assigned to _, dropped by a call statement (or defer/go), or overwritten before being checked; callees on the allowlist (fmt.Println, (*bytes.Buffer).Write, ... plus -error-allowlist) are skipped', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('unchecked_errors_by_callee',
+ 'Callees whose errors are most often ignored, with the ways they are ignored',
+ 'SELECT json_extract(details, ''$.package'') AS package, json_extract(details, ''$.callee'') AS callee,
+    COUNT(*) AS ignored, GROUP_CONCAT(DISTINCT json_extract(details, ''$.kind'')) AS kinds
+  FROM findings
+  WHERE category = ''unchecked_error''
+  GROUP BY package, callee
+  ORDER BY ignored DESC');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("unchecked error analysis: %w", err)
+	}
+
+	var unchecked int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*) FROM findings WHERE category = 'unchecked_error'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			unchecked = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	prog.Log("Errors: %d unchecked errors", unchecked)
+	return nil
+}
+
+// createNilDerefAnalysis turns the nil_derefs recorded by AnalyzeNilDerefs
+// into possible_nil_deref findings.
+func createNilDerefAnalysis(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+INSERT INTO findings (category, severity, node_id, file, line, message, details)
+SELECT 'possible_nil_deref',
+  CASE json_extract(d.value, '$.source_kind') WHEN 'documented_nil' THEN 'info' ELSE 'warning' END,
+  COALESCE(NULLIF(json_extract(d.value, '$.site'), ''), f.id),
+  json_extract(d.value, '$.file'),
+  json_extract(d.value, '$.line'),
+  f.name || ': ' || json_extract(d.value, '$.op') || ' of a value that may be nil (' ||
+    json_extract(d.value, '$.source') || ', line ' || json_extract(d.value, '$.source_line') || ')',
+  json_object('function', f.id,
+    'source_kind', json_extract(d.value, '$.source_kind'),
+    'source', json_extract(d.value, '$.source'),
+    'source_site', json_extract(d.value, '$.source_site'),
+    'op', json_extract(d.value, '$.op'),
+    'path', json(json_extract(d.value, '$.path')))
+FROM nodes f, json_each(json_extract(f.properties, '$.nil_derefs')) d
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.nil_derefs') IS NOT NULL;
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'nil_derefs', 'Dereferences of possibly nil values with no dominating nil check: [{source_kind, source, source_site, source_line, op, site, file, line, path}]; path lists CFG block IDs from the source to the dereference', NULL),
+('finding', 'possible_nil_deref', 'Load, field access, store, interface method call, func call or map write on a value that may be nil (map_lookup, type_assert with ok ignored, nil_return, nil_with_error before err is checked, documented_nil, uninit_field) without a dominating nil check', NULL);
+
+INSERT INTO queries (name, description, sql) VALUES
+('nil_deref_sources',
+ 'Possible nil dereferences grouped by the source of nilness',
+ 'SELECT json_extract(details, ''$.source_kind'') AS kind, json_extract(details, ''$.source'') AS source,
+    COUNT(*) AS derefs, GROUP_CONCAT(file || '':'' || line, '', '') AS sites
+  FROM findings
+  WHERE category = ''possible_nil_deref''
+  GROUP BY kind, source
+  ORDER BY derefs DESC');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("nil deref analysis: %w", err)
+	}
+
+	var derefs int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*) FROM findings WHERE category = 'possible_nil_deref'`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			derefs = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	prog.Log("Nil: %d possible nil dereferences", derefs)
+	return nil
+}
+
+// createUnsafeUsage builds unsafe_usage from the unsafe_uses recorded on
+// function nodes, and documents the unsoundness region properties.
+func createUnsafeUsage(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+CREATE TABLE unsafe_usage (
+    function_id TEXT NOT NULL,
+    function_name TEXT,
+    package TEXT,
+    reason TEXT NOT NULL,            -- reflect_call, reflect_method, reflect_makefunc, unsafe_pointer, cgo_call, cgo_export, linkname, linkname_export, external_body
+    detail TEXT,                     -- reflect.Value.Call, unsafe.Pointer→*T, C.func, linkname target, ...
+    site TEXT,                       -- AST node of the construct, when one exists
+    file TEXT,
+    line INTEGER
+);
+
+INSERT INTO unsafe_usage
+SELECT f.id, f.name, f.package,
+  json_extract(u.value, '$.reason'), json_extract(u.value, '$.detail'),
+  NULLIF(json_extract(u.value, '$.site'), ''),
+  json_extract(u.value, '$.file'), json_extract(u.value, '$.line')
+FROM nodes f, json_each(json_extract(f.properties, '$.unsafe_uses')) u
+WHERE f.kind = 'function' AND json_extract(f.properties, '$.unsafe_uses') IS NOT NULL;
+
+CREATE INDEX idx_unsafe_usage_function ON unsafe_usage(function_id);
+CREATE INDEX idx_unsafe_usage_reason ON unsafe_usage(reason);
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_property', 'unsound_reason', 'Sorted reasons the call graph cannot model this function: reflect_call, reflect_method, reflect_makefunc, unsafe_pointer, cgo_call, cgo_export, linkname, linkname_export, external_body', '["reflect_call","unsafe_pointer"]'),
+('node_property', 'unsafe_uses', 'Constructs behind unsound_reason: [{reason, detail, site, file, line}]', NULL),
+('node_property', 'callgraph_incomplete', 'Function calling (transitively, incomplete_distance call edges away) incomplete_via, a function whose callees the call graph cannot see; its call, taint and slice results may miss paths', NULL),
+('node_property', 'reachability_incomplete', 'Function reachable from hidden_entry_via, a cgo or linkname export callable from outside the call graph; an unreachable/dead result for it is not trustworthy', NULL),
+('table', 'unsafe_usage', 'One row per reflection, unsafe.Pointer, cgo, go:linkname or body-less construct in the analyzed modules', 'SELECT reason, COUNT(*) FROM unsafe_usage GROUP BY reason');
+
+INSERT INTO queries (name, description, sql) VALUES
+('unsound_callgraph_regions',
+ 'Functions hiding their callees, with how many functions transitively call them',
+ 'SELECT u.function_name, u.package, group_concat(DISTINCT u.reason) AS reasons,
+    (SELECT COUNT(*) FROM nodes c WHERE c.kind = ''function''
+       AND json_extract(c.properties, ''$.incomplete_via'') = u.function_id) AS affected_functions
+  FROM unsafe_usage u
+  GROUP BY u.function_id
+  ORDER BY affected_functions DESC'),
+('untrusted_dead_code',
+ 'Functions reported unreachable that a cgo or linkname export may call',
+ 'SELECT n.name, n.package, n.file, n.line, json_extract(n.properties, ''$.hidden_entry_via'') AS hidden_entry
+  FROM nodes n
+  WHERE n.kind = ''function''
+    AND json_extract(n.properties, ''$.reachable'') = 0
+    AND json_extract(n.properties, ''$.reachability_incomplete'') = 1
+  ORDER BY n.package, n.name');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("unsafe usage: %w", err)
+	}
+
+	var uses, funcs int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*), COUNT(DISTINCT function_id) FROM unsafe_usage`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			uses = stmt.ColumnInt(0)
+			funcs = stmt.ColumnInt(1)
+			return nil
+		}})
+
+	prog.Log("Unsafe usage: %d constructs in %d functions", uses, funcs)
+	return nil
+}
+
+// createMetricsCatalog builds metrics_catalog and metric_uses from the
+// metric nodes and edges emitted by BuildMetricsCatalog.
+func createMetricsCatalog(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+CREATE TABLE metric_uses (
+    metric_id TEXT NOT NULL,
+    call_id TEXT NOT NULL,
+    method TEXT NOT NULL,            -- Inc, Add, Set, Observe, WithLabelValues, ...
+    function_id TEXT,
+    function_name TEXT,
+    package TEXT,
+    file TEXT,
+    line INTEGER,
+    label_values TEXT                -- JSON array; "" for non-constant values
+);
+
+INSERT INTO metric_uses
+SELECT e.target, e.source, json_extract(e.properties, '$.method'),
+  NULLIF(json_extract(e.properties, '$.function'), ''), f.name, c.package, c.file, c.line,
+  json_extract(e.properties, '$.label_values')
+FROM edges e
+LEFT JOIN nodes c ON c.id = e.source
+LEFT JOIN nodes f ON f.id = NULLIF(json_extract(e.properties, '$.function'), '')
+WHERE e.kind = 'metric_use';
+
+CREATE INDEX idx_metric_uses_metric ON metric_uses(metric_id);
+CREATE INDEX idx_metric_uses_function ON metric_uses(function_id);
+
+CREATE TABLE metrics_catalog (
+    metric_id TEXT PRIMARY KEY,
+    name TEXT,                       -- namespace_subsystem_name; NULL when not constant
+    type TEXT NOT NULL,              -- counter, gauge, histogram, summary, untyped
+    vec INTEGER NOT NULL,
+    help TEXT,
+    labels TEXT NOT NULL,            -- JSON array of label names
+    labels_resolved INTEGER NOT NULL,
+    variable TEXT,                   -- package variables or Type.fields holding the metric, comma-separated
+    constructor TEXT,
+    package TEXT,
+    file TEXT,
+    line INTEGER,
+    definition_sites TEXT NOT NULL,  -- JSON array of constructor call IDs
+    registration_sites TEXT NOT NULL,-- JSON array of Register/MustRegister (or promauto constructor) call IDs
+    auto_registered INTEGER NOT NULL,
+    use_count INTEGER NOT NULL
+);
+
+INSERT INTO metrics_catalog
+SELECT m.id, NULLIF(json_extract(m.properties, '$.metric_name'), ''),
+  json_extract(m.properties, '$.metric_type'),
+  json_extract(m.properties, '$.vec'),
+  NULLIF(json_extract(m.properties, '$.help'), ''),
+  json_extract(m.properties, '$.labels'),
+  json_extract(m.properties, '$.labels_resolved'),
+  NULLIF(json_extract(m.properties, '$.variable'), ''),
+  json_extract(m.properties, '$.constructor'),
+  m.package, m.file, m.line,
+  (SELECT json_group_array(d.source) FROM edges d WHERE d.kind = 'defines_metric' AND d.target = m.id),
+  json_extract(m.properties, '$.registrations'),
+  json_extract(m.properties, '$.auto_registered'),
+  (SELECT COUNT(*) FROM metric_uses u WHERE u.metric_id = m.id)
+FROM nodes m
+WHERE m.kind = 'metric';
+
+CREATE INDEX idx_metrics_catalog_name ON metrics_catalog(name);
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('node_kind', 'metric', 'Prometheus client_golang metric, identified by its full name (namespace_subsystem_name) or, when not constant, by its constructor call', 'metric::prometheus_tsdb_head_series'),
+('edge_kind', 'defines_metric', 'Constructor call (prometheus.New*, promauto.New*, promauto.With(r).New*)→metric', NULL),
+('edge_kind', 'registers_metric', 'Register/MustRegister call→metric it registers', NULL),
+('edge_kind', 'metric_use', 'Call of Inc/Add/Set/Observe/WithLabelValues/... on a metric→metric', 'Properties: {"method":"Inc","function":"<caller>","label_values":["oom"]}'),
+('table', 'metrics_catalog', 'One row per metric: name, type, help, label names, holding variable, definition and registration sites, use count', 'SELECT * FROM metrics_catalog WHERE name = ''prometheus_tsdb_head_series'''),
+('table', 'metric_uses', 'Call sites updating or selecting a metric, with the calling function and constant label values', 'SELECT function_name, method, file, line FROM metric_uses WHERE metric_id = ''metric::prometheus_tsdb_head_series''');
+
+INSERT INTO queries (name, description, sql) VALUES
+('metric_emitters',
+ 'Functions that emit each metric, by metric name',
+ 'SELECT c.name AS metric, c.type, u.function_name, u.method, u.file, u.line
+  FROM metrics_catalog c
+  JOIN metric_uses u ON u.metric_id = c.metric_id
+  ORDER BY c.name, u.file, u.line'),
+('unused_metrics',
+ 'Metrics defined but never updated anywhere in the analyzed code',
+ 'SELECT name, type, variable, file, line FROM metrics_catalog
+  WHERE use_count = 0
+  ORDER BY name');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("metrics catalog: %w", err)
+	}
+
+	var metrics, uses int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT (SELECT COUNT(*) FROM metrics_catalog), (SELECT COUNT(*) FROM metric_uses)`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			metrics = stmt.ColumnInt(0)
+			uses = stmt.ColumnInt(1)
+			return nil
+		}})
+
+	prog.Log("Metrics catalog: %d metrics, %d use sites", metrics, uses)
+	return nil
+}
+
+// createCrossModuleUsage builds cross_module_usage from api_use edges: one
+// row per exported symbol of a provider module and consumer module using it.
+func createCrossModuleUsage(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+CREATE TABLE cross_module_usage (
+    symbol_id TEXT NOT NULL,
+    symbol TEXT NOT NULL,               -- package.Name (methods: package.Recv.Name)
+    symbol_kind TEXT NOT NULL,          -- node kind of the declaration
+    provider_module TEXT NOT NULL,
+    provider_package TEXT,
+    file TEXT,
+    line INTEGER,
+    consumer_module TEXT NOT NULL,
+    consumer_packages TEXT NOT NULL,    -- JSON array
+    consumer_functions INTEGER NOT NULL,
+    reference_count INTEGER NOT NULL,
+    call_count INTEGER NOT NULL,
+    call_sites TEXT NOT NULL,           -- JSON array of file:line
+    PRIMARY KEY (symbol_id, consumer_module)
+);
+
+INSERT INTO cross_module_usage
+SELECT e.target, COALESCE(t.package || '.', '') || t.name, t.kind,
+  json_extract(e.properties, '$.provider_module'), t.package, t.file, t.line,
+  json_extract(e.properties, '$.consumer_module'),
+  json_group_array(DISTINCT c.package),
+  COUNT(DISTINCT e.source),
+  SUM(json_extract(e.properties, '$.refs')),
+  SUM(json_extract(e.properties, '$.calls')),
+  (SELECT json_group_array(s.value)
+   FROM edges e2, json_each(e2.properties, '$.call_sites') s
+   WHERE e2.target = e.target AND e2.kind = 'api_use'
+     AND json_extract(e2.properties, '$.consumer_module') = json_extract(e.properties, '$.consumer_module'))
+FROM edges e
+JOIN nodes t ON t.id = e.target
+JOIN nodes c ON c.id = e.source
+WHERE e.kind = 'api_use'
+GROUP BY e.target, json_extract(e.properties, '$.consumer_module');
+
+CREATE INDEX idx_cross_module_usage_provider ON cross_module_usage(provider_module, symbol);
+CREATE INDEX idx_cross_module_usage_consumer ON cross_module_usage(consumer_module);
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('edge_kind', 'api_use', 'Function (or file, for package-level initializers)→exported declaration of another analyzed module it references', 'Properties: {"consumer_module":"prometheus","provider_module":"client_golang","refs":3,"calls":2,"call_sites":["scrape/scrape.go:120"]}'),
+('table', 'cross_module_usage', 'One row per exported symbol of a provider module and consumer module using it: reference and call counts, consuming packages, call sites', 'SELECT symbol, consumer_module, call_count FROM cross_module_usage WHERE provider_module = ''client_golang'' ORDER BY call_count DESC');
+
+INSERT INTO queries (name, description, sql) VALUES
+('module_api_surface',
+ 'Exported symbols of each module used by other modules, with their consumers',
+ 'SELECT provider_module, symbol, symbol_kind, group_concat(consumer_module, '', '') AS consumers,
+         SUM(call_count) AS calls, SUM(reference_count) AS refs
+  FROM cross_module_usage
+  GROUP BY provider_module, symbol_id
+  ORDER BY provider_module, refs DESC');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("cross-module usage: %w", err)
+	}
+
+	var rows, symbols int
+	sqlitex.ExecuteTransient(conn,
+		`SELECT COUNT(*), COUNT(DISTINCT symbol_id) FROM cross_module_usage`,
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			rows = stmt.ColumnInt(0)
+			symbols = stmt.ColumnInt(1)
+			return nil
+		}})
+
+	prog.Log("Cross-module usage: %d exported symbols used across modules (%d symbol/consumer pairs)", symbols, rows)
+	return nil
+}
+
+// createSCIPSymbols generates SCIP (Source Code Intelligence Protocol) compatible
+// symbol identifiers for cross-repository code navigation.
+func createSCIPSymbols(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+CREATE TABLE scip_symbols (
+    node_id TEXT PRIMARY KEY,
+    scip_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    package TEXT,
+    display_name TEXT
+);
+
+-- Functions: scip-go gomod github.com/prometheus/prometheus v0 package/name().
+INSERT INTO scip_symbols (node_id, scip_id, kind, package, display_name)
+SELECT n.id,
+  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  REPLACE(n.package, '/', '.') || '/' || n.name || '().',
+  'function', n.package, n.name
+FROM nodes n
+WHERE n.kind = 'function'
+  AND n.name NOT LIKE '%.%'
+  AND n.package IS NOT NULL AND n.name != '';
+
+-- Methods: scip-go gomod github.com/prometheus/prometheus v0 package/Type#Method().
+INSERT INTO scip_symbols (node_id, scip_id, kind, package, display_name)
+SELECT n.id,
+  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  REPLACE(n.package, '/', '.') || '/' ||
+  REPLACE(REPLACE(SUBSTR(n.name, 1, INSTR(n.name, '.') - 1), '(*', ''), ')', '') ||
+  '#' || SUBSTR(n.name, INSTR(n.name, '.') + 1) || '().',
+  'method', n.package, n.name
+FROM nodes n
+WHERE n.kind = 'function'
+  AND n.name LIKE '%.%'
+  AND n.package IS NOT NULL;
+
+-- Types: scip-go gomod github.com/prometheus/prometheus v0 package/TypeName#
+INSERT OR IGNORE INTO scip_symbols (node_id, scip_id, kind, package, display_name)
+SELECT n.id,
+  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  REPLACE(n.package, '/', '.') || '/' || n.name || '#',
+  'type', n.package, n.name
+FROM nodes n
+WHERE n.kind = 'type_decl'
+  AND n.package IS NOT NULL AND n.name != '';
+
+-- Packages: scip-go gomod github.com/prometheus/prometheus v0 package/
+INSERT OR IGNORE INTO scip_symbols (node_id, scip_id, kind, package, display_name)
+SELECT n.id,
+  'scip-go gomod github.com/prometheus/prometheus v0 ' ||
+  REPLACE(n.package, '/', '.') || '/',
+  'package', n.package, n.name
+FROM nodes n
+WHERE n.kind = 'package'
+  AND n.package IS NOT NULL;
+
+CREATE INDEX idx_scip_kind ON scip_symbols(kind);
+CREATE INDEX idx_scip_pkg ON scip_symbols(package);
+
+INSERT INTO schema_docs (category, name, description, example) VALUES
+('table', 'scip_symbols', 'SCIP-compatible symbol identifiers for cross-repository navigation', 'SELECT * FROM scip_symbols WHERE kind = ''method'' AND display_name LIKE ''Manager%''');
+
+INSERT INTO queries (name, description, sql) VALUES
+('scip_lookup', 'Look up SCIP symbol for a node',
+ 'SELECT s.scip_id, s.kind, s.display_name, n.file, n.line FROM scip_symbols s JOIN nodes n ON n.id = s.node_id WHERE s.display_name LIKE ? ORDER BY s.kind, s.display_name');
+`
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return fmt.Errorf("scip symbols: %w", err)
+	}
+
+	var total int
+	sqlitex.ExecuteTransient(conn, "SELECT COUNT(*) FROM scip_symbols",
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			total = stmt.ColumnInt(0)
+			return nil
+		}})
+
+	var byKind []string
+	sqlitex.ExecuteTransient(conn, "SELECT kind || '=' || COUNT(*) FROM scip_symbols GROUP BY kind ORDER BY kind",
+		&sqlitex.ExecOptions{ResultFunc: func(stmt *sqlite.Stmt) error {
+			byKind = append(byKind, stmt.ColumnText(0))
+			return nil
+		}})
+
+	prog.Log("SCIP symbols: %d total (%s)", total, strings.Join(byKind, ", "))
+	return nil
+}
+
+// createCommunicationPatterns builds Honda session type-inspired protocol
+// analysis connecting Prometheus with its ecosystem services (adapter, alertmanager, etc.).
+// Inspired by Honda 1998 (binary session types) and Honda 2008 (multiparty asynchronous session types).
+func createCommunicationPatterns(conn *sqlite.Conn, prog *Progress) error {
+	ddl := `
+-- ═══════════════════════════════════════════════════════════════════
+-- Communication Patterns — Honda Session Type Analysis
+-- Based on Honda 1998 (binary), Honda 2008 (multiparty), Yoshida & Hou 2024 (corrections)
+-- ═══════════════════════════════════════════════════════════════════
+
+-- Protocol definitions with Honda session type notation
+-- Client session: "!" = send, "?" = receive, "+" = internal choice, "&" = external choice
+-- Server session: dual of client (swap !/?, swap +/&)
+CREATE TABLE comm_protocols (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    session_type_client TEXT,   -- Honda notation from client perspective
+    session_type_server TEXT,   -- dual type (server perspective)
+    transport TEXT,             -- http, grpc, channel
+    encoding TEXT,              -- json, protobuf, text/plain
+    pattern TEXT,               -- request_response, streaming, fan_out, pipeline
+    is_dual BOOLEAN DEFAULT 1   -- true if client/server types are proper duals
 );
 
 -- Protocol participants: which components play which roles
@@ -3635,6 +5101,14 @@ CREATE TABLE comm_graph (
     PRIMARY KEY (source_component, target_component, protocol_id)
 );
 
+-- URL paths served by each protocol's server role (GLOB patterns), used to
+-- attribute discovered HTTP routes to protocols
+CREATE TABLE comm_protocol_routes (
+    protocol_id TEXT NOT NULL REFERENCES comm_protocols(id),
+    path_glob TEXT NOT NULL,
+    PRIMARY KEY (protocol_id, path_glob)
+);
+
 -- ═══════════════════════════════════════════════════════════════════
 -- Protocol Definitions
 -- ═══════════════════════════════════════════════════════════════════
@@ -3738,6 +5212,18 @@ INSERT INTO comm_protocols VALUES
  '?HTTP{GET|POST, /api/v1/query|query_range, query=PromQL}; !JSON{status, data}; end',
  'http', 'json', 'request_response', 1);
 
+INSERT INTO comm_protocol_routes VALUES
+('scrape', '/metrics'),
+('remote_write', '/api/v1/write'),
+('remote_read', '/api/v1/read'),
+('alertmanager_notify', '/api/v2/alerts'),
+('adapter_query', '/api/v1/query'),
+('adapter_query_range', '/api/v1/query_range'),
+('adapter_series', '/api/v1/series'),
+('federation', '/federate'),
+('otlp_ingest', '/api/v1/otlp/v1/metrics'),
+('promql_api', '/api/v1/*');
+
 -- ═══════════════════════════════════════════════════════════════════
 -- Participants
 -- ═══════════════════════════════════════════════════════════════════
@@ -3808,6 +5294,54 @@ INSERT INTO comm_session_steps VALUES
 -- Endpoint Detection (from CPG nodes)
 -- ═══════════════════════════════════════════════════════════════════
 
+-- Server endpoints: every discovered HTTP route (http_route nodes), attributed
+-- to a protocol by its path. Routes under an unresolved prefix match on the
+-- protocol path's suffix. An exact protocol path takes precedence: a
+-- wildcard (promql_api's /api/v1/*) only claims routes no exact path matched.
+WITH route_protocols AS (
+  SELECT r.id AS route_id, pr.protocol_id, pr.path_glob GLOB '*[*?[]*' AS wildcard
+  FROM nodes r
+  JOIN comm_protocol_routes pr ON
+         (json_extract(r.properties, '$.prefix_resolved')
+          AND json_extract(r.properties, '$.path') GLOB pr.path_glob)
+      OR (NOT json_extract(r.properties, '$.prefix_resolved')
+          AND json_extract(r.properties, '$.path_resolved')
+          AND length(json_extract(r.properties, '$.path')) > 1
+          AND pr.path_glob NOT GLOB '*[*?[]*'
+          AND substr(pr.path_glob, -length(json_extract(r.properties, '$.path'))) = json_extract(r.properties, '$.path'))
+  WHERE r.kind = 'http_route'
+)
+INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, http_method, confidence)
+SELECT rp.protocol_id, json_extract(r.properties, '$.component'), 'server', 'http_handler',
+       h.id, h.name, COALESCE(h.package, r.package), COALESCE(h.file, r.file), COALESCE(h.line, r.line),
+       NULLIF(json_extract(r.properties, '$.path'), ''),
+       NULLIF(json_extract(r.properties, '$.method'), ''),
+       json_extract(r.properties, '$.confidence')
+FROM nodes r
+LEFT JOIN edges e ON e.source = r.id AND e.kind = 'routes_to'
+LEFT JOIN nodes h ON h.id = e.target
+LEFT JOIN route_protocols rp ON rp.route_id = r.id
+  AND NOT (rp.wildcard AND EXISTS (
+    SELECT 1 FROM route_protocols x WHERE x.route_id = rp.route_id AND NOT x.wildcard))
+WHERE r.kind = 'http_route';
+
+-- Client endpoints: callers of discovered routes (remote_call edges), in the
+-- protocols of the server endpoints they reach
+INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, http_method, confidence)
+SELECT DISTINCT s.protocol_id, json_extract(e.properties, '$.component'), 'client', 'http_client',
+       f.id, f.name, f.package, COALESCE(c.file, f.file), COALESCE(c.line, f.line),
+       json_extract(e.properties, '$.url'),
+       NULLIF(json_extract(e.properties, '$.method'), ''),
+       json_extract(e.properties, '$.confidence')
+FROM edges e
+JOIN nodes f ON f.id = e.source
+JOIN nodes r ON r.id = json_extract(e.properties, '$.route')
+LEFT JOIN nodes c ON c.id = json_extract(e.properties, '$.site')
+JOIN comm_endpoints s ON s.role = 'server' AND s.function_id = e.target
+  AND s.url_path IS NULLIF(json_extract(r.properties, '$.path'), '')
+  AND s.http_method IS NULLIF(json_extract(r.properties, '$.method'), '')
+WHERE e.kind = 'remote_call';
+
 -- Prometheus server endpoints: scrape loop (client role in scrape protocol)
 INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, confidence)
 SELECT 'scrape', 'prometheus', 'client', 'http_client',
@@ -3825,14 +5359,6 @@ WHERE n.kind = 'function' AND n.package = 'storage/remote'
   AND (n.name LIKE '*QueueManager.sendBatch%' OR n.name LIKE '*QueueManager.Start%'
        OR n.name LIKE '%Client.Store%');
 
--- Remote write: server handler
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'remote_write', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line, '/api/v1/write', 1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'storage/remote'
-  AND n.name LIKE '*writeHandler%';
-
 -- Remote read: client
 INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, confidence)
 SELECT 'remote_read', 'prometheus', 'client', 'http_client',
@@ -3841,14 +5367,6 @@ FROM nodes n
 WHERE n.kind = 'function' AND n.package = 'storage/remote'
   AND (n.name LIKE '*Client.Read%' OR n.name LIKE '*readHandler%');
 
--- Remote read: server handler
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'remote_read', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line, '/api/v1/read', 1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'storage/remote'
-  AND n.name LIKE '*readHandler.ServeHTTP%';
-
 -- Alertmanager notification: client
 INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, confidence)
 SELECT 'alertmanager_notify', 'prometheus', 'client', 'http_client',
@@ -3858,42 +5376,6 @@ WHERE n.kind = 'function' AND n.package = 'notifier'
   AND (n.name LIKE '*sendLoop.sendAll%' OR n.name LIKE '*sendLoop.sendOne%'
        OR n.name LIKE '*Manager.Send%');
 
--- OTLP ingestion: server handler
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'otlp_ingest', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line, '/api/v1/otlp/v1/metrics', 1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'storage/remote'
-  AND n.name LIKE '*otlpWriteHandler.ServeHTTP%';
-
--- PromQL API: server endpoints
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'promql_api', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line,
-       CASE WHEN n.name LIKE '*API.query' THEN '/api/v1/query'
-            WHEN n.name LIKE '*API.queryRange%' THEN '/api/v1/query_range'
-            WHEN n.name LIKE '*API.series%' THEN '/api/v1/series'
-            WHEN n.name LIKE '*API.labelValues%' THEN '/api/v1/label/*/values'
-            WHEN n.name LIKE '*API.labelNames%' THEN '/api/v1/label/__name__/values'
-            WHEN n.name LIKE '*API.targets%' THEN '/api/v1/targets'
-            ELSE '/api/v1/*'
-       END,
-       1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'web/api/v1'
-  AND n.name IN ('*API.query', '*API.queryRange', '*API.series',
-                  '*API.labelValues', '*API.labelNames', '*API.targets',
-                  '*API.alerts', '*API.rules', '*API.alertmanagers',
-                  '*API.remoteWrite', '*API.remoteRead', '*API.otlpWrite');
-
--- Federation: server endpoint
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'federation', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line, '/federate', 1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'web'
-  AND n.name LIKE '*Handler.federation%';
-
 -- Discovery: all Discoverer implementations (client role querying providers)
 INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, confidence)
 SELECT 'discovery', 'prometheus', 'client', 'http_client',
@@ -3938,27 +5420,6 @@ WHERE n.kind = 'function'
   AND json_extract(n.properties, '$.project') = 'adapter'
   AND n.name LIKE '%httpAPIClient%.Do';
 
--- Prometheus API v1 server endpoints serving the adapter's requests.
--- The adapter calls /api/v1/query, /api/v1/query_range, /api/v1/series —
--- these are served by *API.query, *API.queryRange, *API.series in web/api/v1.
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'adapter_query', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line, '/api/v1/query', 1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'web/api/v1' AND n.name = '*API.query';
-
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'adapter_query_range', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line, '/api/v1/query_range', 1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'web/api/v1' AND n.name = '*API.queryRange';
-
-INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, url_path, confidence)
-SELECT 'adapter_series', 'prometheus', 'server', 'http_handler',
-       n.id, n.name, n.package, n.file, n.line, '/api/v1/series', 1.0
-FROM nodes n
-WHERE n.kind = 'function' AND n.package = 'web/api/v1' AND n.name = '*API.series';
-
 -- Adapter: provider factory functions that wire up the Kubernetes API server
 INSERT INTO comm_endpoints (protocol_id, component, role, endpoint_type, function_id, function_name, package, file, line, confidence)
 SELECT 'k8s_custom_metrics', 'adapter', 'server', 'api_provider',
@@ -4068,6 +5529,15 @@ INSERT OR IGNORE INTO comm_graph VALUES
 ('external_service', 'prometheus', 'otlp_ingest', '→', 'OTLP push'),
 ('external_client', 'prometheus', 'promql_api', '→', 'PromQL HTTP API');
 
+-- Edges observed in code: client components reaching another component's routes
+INSERT OR IGNORE INTO comm_graph
+SELECT DISTINCT c.component, s.component, s.protocol_id, '→', 'HTTP ' || COALESCE(s.http_method || ' ', '') || s.url_path
+FROM comm_endpoints c
+JOIN comm_endpoints s ON s.role = 'server' AND s.protocol_id = c.protocol_id
+  AND s.url_path IS NOT NULL AND c.url_path IS NOT NULL
+WHERE c.role = 'client' AND c.endpoint_type = 'http_client' AND c.component != s.component
+  AND EXISTS (SELECT 1 FROM edges e WHERE e.kind = 'remote_call' AND e.source = c.function_id AND e.target = s.function_id);
+
 -- ═══════════════════════════════════════════════════════════════════
 -- Channel Patterns (intra-service Honda binary session types)
 -- ═══════════════════════════════════════════════════════════════════
@@ -4228,7 +5698,7 @@ SELECT
     e.url_path,
     e.confidence
 FROM comm_endpoints e
-JOIN comm_protocols p ON p.id = e.protocol_id
+LEFT JOIN comm_protocols p ON p.id = e.protocol_id
 ORDER BY e.protocol_id, e.component, e.role;
 
 -- Honda session type verification: duality check
@@ -4277,8 +5747,27 @@ INSERT INTO schema_docs (category, name, description, example) VALUES
  'SELECT * FROM comm_participants WHERE protocol_id = ''adapter_query'''),
 ('table', 'comm_session_steps', 'Step-by-step message sequence for each protocol in Honda session type notation (! = send, ? = receive).',
  'SELECT * FROM comm_session_steps WHERE protocol_id = ''scrape'' ORDER BY step_order'),
-('table', 'comm_endpoints', 'Detected code endpoints (functions/handlers) implementing communication protocols.',
- 'SELECT protocol_id, component, role, function_name, url_path FROM comm_endpoints ORDER BY protocol_id'),
+('table', 'comm_endpoints', 'Detected code endpoints (functions/handlers) implementing communication protocols. Server endpoints come from discovered HTTP routes (protocol_id NULL when no protocol path matches), with the route confidence.',
+ 'SELECT protocol_id, component, role, function_name, http_method, url_path, confidence FROM comm_endpoints ORDER BY protocol_id'),
+('table', 'comm_protocol_routes', 'URL path GLOB patterns served by each protocol, used to attribute discovered HTTP routes to protocols.',
+ 'SELECT * FROM comm_protocol_routes'),
+('node_kind', 'http_route', 'HTTP route registered on a router (net/http, prometheus route, gorilla/mux, chi, httprouter): method, full path, prefix, handlers, confidence', 'route::GET /api/v1/query@web/api/v1::@api.go:310:7:call'),
+('edge_kind', 'registers_route', 'Route registration call→http_route', NULL),
+('edge_kind', 'routes_to', 'http_route→handler function serving it (through HandlerFunc conversions, method values and wrapper calls)', NULL),
+('edge_kind', 'remote_call', 'Function making an HTTP request→handler function of the route its URL matches, possibly in another module; with -grpc also gRPC client stub call→implementing server method (protocol "grpc")', 'Properties: {"site":"<call>","method":"POST","url":"*/api/v2/alerts","route":"<http_route>","component":"prometheus","confidence":0.72}'),
+('node_kind', 'rpc_service', 'gRPC service parsed from a .proto file (-grpc); go_import_path is the package of its generated code', 'proto::prometheus.RemoteRead'),
+('node_kind', 'rpc_method', 'rpc of a gRPC service: full_method, request/response message names, streaming flags', 'proto::prometheus.RemoteRead/Read'),
+('node_kind', 'message', 'Protobuf message parsed from a .proto file, nested messages as Outer.Inner', 'proto::prometheus.WriteRequest'),
+('node_kind', 'generated', 'Top-level type or function of a .pb.go file skipped by -skip-generated, kept as a lightweight declaration (-grpc)', NULL),
+('edge_kind', 'has_rpc', 'rpc_service→rpc_method', NULL),
+('edge_kind', 'rpc_request', 'rpc_method→request message', NULL),
+('edge_kind', 'rpc_response', 'rpc_method→response message', NULL),
+('edge_kind', 'generated_from', 'Generated Go declaration→proto definition: message structs→message; XServer, XClient, RegisterXServer, NewXClient→rpc_service', NULL),
+('edge_kind', 'implements_service', 'Module type implementing a generated XServer interface→rpc_service', NULL),
+('edge_kind', 'implements_rpc', 'Method of a service implementation→rpc_method it serves (promoted Unimplemented stubs excluded)', NULL),
+('edge_kind', 'registers_service', 'RegisterXServer call→rpc_service', NULL),
+('edge_kind', 'registers_impl', 'RegisterXServer call→module type passed as the service implementation', NULL),
+('edge_kind', 'rpc_call', 'XClient stub method call→rpc_method', NULL),
 ('table', 'comm_channel_patterns', 'Internal Go channel communication patterns within each service, classified by type (fan_out, pipeline, signal, etc.).',
  'SELECT * FROM comm_channel_patterns WHERE component = ''prometheus'''),
 ('table', 'comm_causality', 'Honda 2008 causality edges (II/IO/OO). Cycles indicate potential deadlocks.',
@@ -4305,6 +5794,15 @@ INSERT INTO queries (name, description, sql) VALUES
 ('comm_adapter_flow', 'Trace the adapter→prometheus→kubernetes data flow',
  'SELECT g1.source_component, g1.target_component, p1.name, g2.source_component AS upstream, g2.target_component AS downstream, p2.name AS upstream_protocol FROM comm_graph g1 JOIN comm_protocols p1 ON p1.id = g1.protocol_id JOIN comm_graph g2 ON g2.target_component = g1.source_component JOIN comm_protocols p2 ON p2.id = g2.protocol_id WHERE g1.source_component = ''adapter'''),
 
+('http_routes', 'Discovered HTTP routes with their handlers, least certain first',
+ 'SELECT r.name AS route, json_extract(r.properties, ''$.component'') AS component, h.name AS handler, json_extract(r.properties, ''$.confidence'') AS confidence, r.file || '':'' || r.line AS registered_at FROM nodes r LEFT JOIN edges e ON e.source = r.id AND e.kind = ''routes_to'' LEFT JOIN nodes h ON h.id = e.target WHERE r.kind = ''http_route'' ORDER BY confidence, r.file, r.line'),
+
+('remote_calls', 'HTTP client calls resolved to the server handlers they reach',
+ 'SELECT f.name AS client, json_extract(e.properties, ''$.component'') AS client_component, json_extract(e.properties, ''$.method'') AS method, json_extract(e.properties, ''$.url'') AS url, h.name AS handler, h.package AS handler_package, json_extract(e.properties, ''$.confidence'') AS confidence FROM edges e JOIN nodes f ON f.id = e.source JOIN nodes h ON h.id = e.target WHERE e.kind = ''remote_call'' ORDER BY confidence DESC'),
+
+('grpc_services', 'gRPC rpcs with their implementing methods and client call counts (-grpc)',
+ 'SELECT s.name AS service, m.name AS rpc, json_extract(m.properties, ''$.full_method'') AS full_method, (SELECT group_concat(f.name, '', '') FROM edges i JOIN nodes f ON f.id = i.source WHERE i.target = m.id AND i.kind = ''implements_rpc'') AS implementations, (SELECT COUNT(*) FROM edges c WHERE c.target = m.id AND c.kind = ''rpc_call'') AS client_calls FROM nodes s JOIN edges h ON h.source = s.id AND h.kind = ''has_rpc'' JOIN nodes m ON m.id = h.target WHERE s.kind = ''rpc_service'' ORDER BY s.name, m.name'),
+
 ('comm_protocol_endpoints', 'Find all code endpoints implementing a specific protocol',
  'SELECT e.protocol_id, e.component, e.role, e.function_name, e.package, e.file || '':'' || e.line AS location, e.url_path FROM comm_endpoints e ORDER BY e.protocol_id, e.component'),
 
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// flagErrorAllowlist holds extra callees whose errors may be ignored, in
+// the form printed by unchecked_error findings ("fmt.Println",
+// "(*bytes.Buffer).Write", "(io.Writer).Write"). Set by main before any
+// pipeline phase runs.
+var flagErrorAllowlist []string
+
+// defaultErrorAllowlist lists callees whose error results are routinely
+// ignored: printing to stdout, and writers documented never to fail.
+var defaultErrorAllowlist = []string{
+	"fmt.Print",
+	"fmt.Printf",
+	"fmt.Println",
+	"(*bytes.Buffer).Write",
+	"(*bytes.Buffer).WriteByte",
+	"(*bytes.Buffer).WriteRune",
+	"(*bytes.Buffer).WriteString",
+	"(*strings.Builder).Write",
+	"(*strings.Builder).WriteByte",
+	"(*strings.Builder).WriteRune",
+	"(*strings.Builder).WriteString",
+	"(hash.Hash).Write",
+	"(hash.Hash32).Write",
+	"(hash.Hash64).Write",
+	"math/rand.Read",
+}
+
+// Ways an error result goes unchecked.
+const (
+	errIgnoredBlank       = "blank"       // assigned to _
+	errIgnoredDropped     = "dropped"     // call used as a statement
+	errIgnoredOverwritten = "overwritten" // reassigned before any use
+	errIgnoredDeferred    = "deferred"    // defer f()
+	errIgnoredGo          = "go"          // go f()
+)
+
+// AnalyzeUncheckedErrors finds calls whose error result is never looked
+// at: assigned to _, dropped by using the call as a statement (including
+// defer and go statements), or overwritten before any use. The SSA
+// referrers of the error value decide whether it is used: any use other
+// than flowing into a phi counts as a check (comparison, return, passing it
+// on, storing it). The source statement tells a blank assignment from a
+// dropped call and an overwrite. Callees in defaultErrorAllowlist or
+// flagErrorAllowlist are skipped, and so is the `defer x.Close()` idiom
+// unless x is a file opened for writing (see idiomaticDeferredClose).
+//
+// Each case is recorded in unchecked_errors on the calling function with
+// the callee and its package.
+func AnalyzeUncheckedErrors(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Analyzing unchecked errors...")
+
+	allow := make(map[string]bool)
+	for _, name := range defaultErrorAllowlist {
+		allow[name] = true
+	}
+	for _, name := range flagErrorAllowlist {
+		allow[name] = true
+	}
+
+	byFunc := make(map[string][]map[string]any)
+	counts := make(map[string]int)
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+		var stmts map[token.Pos]string
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				common := call.Common()
+				callee := errCalleeName(common)
+				if callee == "" || allow[callee] {
+					continue
+				}
+				kind := ""
+				switch c := call.(type) {
+				case *ssa.Defer:
+					if returnsErrorResult(common) && !idiomaticDeferredClose(common) {
+						kind = errIgnoredDeferred
+					}
+				case *ssa.Go:
+					if returnsErrorResult(common) {
+						kind = errIgnoredGo
+					}
+				case *ssa.Call:
+					if !errorChecked(c) {
+						if stmts == nil {
+							stmts = errStatements(fn)
+						}
+						kind = stmts[c.Pos()]
+						if kind == "" {
+							kind = errIgnoredDropped
+						}
+					}
+				}
+				if kind == "" {
+					continue
+				}
+				file, line, col := instrPos(call, fset)
+				if file == "" {
+					continue
+				}
+				fnID := ssaFuncNodeID(fn, fset, funcLookup)
+				if fnID == "" {
+					continue
+				}
+				byFunc[fnID] = append(byFunc[fnID], map[string]any{
+					"kind":    kind,
+					"callee":  callee,
+					"package": errCalleePkg(common),
+					"site":    posLookup.Get(file, line, col),
+					"file":    file,
+					"line":    line,
+				})
+				counts[kind]++
+			}
+		}
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if errs, ok := byFunc[n.ID]; ok && n.Kind == "function" {
+			sort.SliceStable(errs, func(i, j int) bool { return errs[i]["line"].(int) < errs[j]["line"].(int) })
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["unchecked_errors"] = errs
+		}
+	}
+
+	prog.Log("Unchecked errors: %d blank, %d dropped, %d overwritten, %d deferred, %d in go statements",
+		counts[errIgnoredBlank], counts[errIgnoredDropped], counts[errIgnoredOverwritten],
+		counts[errIgnoredDeferred], counts[errIgnoredGo])
+}
+
+// returnsErrorResult reports whether the call's last result is an error.
+func returnsErrorResult(common *ssa.CallCommon) bool {
+	res := common.Signature().Results()
+	return res.Len() > 0 && isErrorType(res.At(res.Len()-1).Type())
+}
+
+// errorChecked reports whether every error result of call is used. Calls
+// without an error result count as checked.
+func errorChecked(call *ssa.Call) bool {
+	tup, ok := call.Type().(*types.Tuple)
+	if !ok {
+		return !isErrorType(call.Type()) || valueUsed(call)
+	}
+	for i := 0; i < tup.Len(); i++ {
+		if !isErrorType(tup.At(i).Type()) {
+			continue
+		}
+		used := false
+		if call.Referrers() != nil {
+			for _, ref := range *call.Referrers() {
+				if ex, ok := ref.(*ssa.Extract); ok && ex.Index == i && valueUsed(ex) {
+					used = true
+				}
+			}
+		}
+		if !used {
+			return false
+		}
+	}
+	return true
+}
+
+// valueUsed reports whether v reaches an instruction other than a phi.
+func valueUsed(v ssa.Value) bool {
+	seen := make(map[ssa.Value]bool)
+	work := []ssa.Value{v}
+	for len(work) > 0 {
+		v := work[len(work)-1]
+		work = work[:len(work)-1]
+		if seen[v] || v.Referrers() == nil {
+			continue
+		}
+		seen[v] = true
+		for _, ref := range *v.Referrers() {
+			phi, ok := ref.(*ssa.Phi)
+			if !ok {
+				return true
+			}
+			work = append(work, phi)
+		}
+	}
+	return false
+}
+
+// errStatements classifies the calls of fn's source by the statement that
+// discards their error, keyed by the call's Lparen (the SSA call position):
+// a call statement drops it, an assignment to _ blanks it, and an
+// assignment to a variable that is never read before the next assignment
+// overwrites it.
+func errStatements(fn *ssa.Function) map[token.Pos]string {
+	stmts := make(map[token.Pos]string)
+	syntax := fn.Syntax()
+	if syntax == nil {
+		return stmts
+	}
+	ast.Inspect(syntax, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.ExprStmt:
+			if call, ok := ast.Unparen(s.X).(*ast.CallExpr); ok {
+				stmts[call.Lparen] = errIgnoredDropped
+			}
+		case *ast.AssignStmt:
+			if len(s.Rhs) != 1 {
+				return true
+			}
+			call, ok := ast.Unparen(s.Rhs[0]).(*ast.CallExpr)
+			if !ok || len(s.Lhs) == 0 {
+				return true
+			}
+			// The error is conventionally the last result.
+			if id, ok := s.Lhs[len(s.Lhs)-1].(*ast.Ident); ok && id.Name == "_" {
+				stmts[call.Lparen] = errIgnoredBlank
+			} else {
+				stmts[call.Lparen] = errIgnoredOverwritten
+			}
+		}
+		return true
+	})
+	return stmts
+}
+
+// writeFlags are the os.OpenFile flags that open a file for writing.
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_TRUNC
+
+// idiomaticDeferredClose reports whether a deferred call is a Close whose
+// error may go unchecked: anything but an *os.File opened for writing,
+// where Close reports the write-back errors.
+func idiomaticDeferredClose(common *ssa.CallCommon) bool {
+	var recv ssa.Value
+	if common.IsInvoke() {
+		if common.Method.Name() != "Close" {
+			return false
+		}
+		recv = common.Value
+	} else {
+		callee := common.StaticCallee()
+		if callee == nil || callee.Name() != "Close" || callee.Signature.Recv() == nil || len(common.Args) == 0 {
+			return false
+		}
+		recv = common.Args[0]
+	}
+	return !writableFile(recv, map[ssa.Value]bool{})
+}
+
+// writableFile reports whether v may be a file opened by os.Create,
+// os.CreateTemp, or os.OpenFile with write flags (or flags that are not
+// constant).
+func writableFile(v ssa.Value, visited map[ssa.Value]bool) bool {
+	if visited[v] {
+		return false
+	}
+	visited[v] = true
+	switch x := v.(type) {
+	case *ssa.MakeInterface:
+		return writableFile(x.X, visited)
+	case *ssa.ChangeInterface:
+		return writableFile(x.X, visited)
+	case *ssa.ChangeType:
+		return writableFile(x.X, visited)
+	case *ssa.Phi:
+		for _, e := range x.Edges {
+			if writableFile(e, visited) {
+				return true
+			}
+		}
+	case *ssa.UnOp:
+		// A variable captured by a closure or whose address is taken lives
+		// in an Alloc; the load reads back a file stored into it.
+		if x.Op != token.MUL {
+			return false
+		}
+		if alloc, ok := x.X.(*ssa.Alloc); ok {
+			for _, ref := range *alloc.Referrers() {
+				if st, ok := ref.(*ssa.Store); ok && st.Addr == alloc && writableFile(st.Val, visited) {
+					return true
+				}
+			}
+		}
+	case *ssa.Extract:
+		return writableFile(x.Tuple, visited)
+	case *ssa.Call:
+		callee := x.Call.StaticCallee()
+		if callee == nil || callee.Pkg == nil || callee.Pkg.Pkg.Path() != "os" {
+			return false
+		}
+		switch callee.Name() {
+		case "Create", "CreateTemp":
+			return true
+		case "OpenFile":
+			if len(x.Call.Args) < 2 {
+				return false
+			}
+			flag, ok := x.Call.Args[1].(*ssa.Const)
+			if !ok || flag.Value == nil {
+				return true
+			}
+			return flag.Int64()&int64(writeFlags) != 0
+		}
+	}
+	return false
+}
+
+// errCalleeName renders a call target the way allowlist entries are
+// written: "pkg.Func", "(*pkg.Type).Method" or "(pkg.Iface).Method", with
+// analyzed packages relative. Returns "" for calls through function values.
+func errCalleeName(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		iface := common.Value.Type().String()
+		if n, ok := common.Value.Type().(*types.Named); ok && n.Obj().Pkg() != nil && modSet.IsKnownPkg(n.Obj().Pkg().Path()) {
+			path := n.Obj().Pkg().Path()
+			iface = strings.Replace(iface, path, modSet.RelPkg(path), 1)
+		}
+		return "(" + iface + ")." + common.Method.Name()
+	}
+	if callee := common.StaticCallee(); callee != nil {
+		name := callee.String()
+		// Closures and instantiations keep their parent's name.
+		if i := strings.IndexAny(name, "$["); i > 0 {
+			name = name[:i]
+		}
+		if callee.Pkg != nil && modSet.IsKnownPkg(callee.Pkg.Pkg.Path()) {
+			path := callee.Pkg.Pkg.Path()
+			name = strings.Replace(name, path, modSet.RelPkg(path), 1)
+		}
+		return name
+	}
+	return ""
+}
+
+// errCalleePkg is the package of a call target, relative for analyzed
+// modules.
+func errCalleePkg(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		if n, ok := common.Value.Type().(*types.Named); ok && n.Obj().Pkg() != nil {
+			return modSet.RelPkg(n.Obj().Pkg().Path())
+		}
+		if pkg := common.Method.Pkg(); pkg != nil {
+			return modSet.RelPkg(pkg.Path())
+		}
+		return ""
+	}
+	if callee := common.StaticCallee(); callee != nil && callee.Pkg != nil {
+		return modSet.RelPkg(callee.Pkg.Pkg.Path())
+	}
+	return ""
+}
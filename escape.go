@@ -2,66 +2,232 @@ package main
 
 import (
 	"bufio"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// EscapeResult holds one escape analysis annotation from the Go compiler.
+// EscapeResult holds one escape analysis, inlining or bounds-check
+// annotation from the Go compiler.
 type EscapeResult struct {
 	RelFile string
 	Line    int
 	Col     int
-	Kind    string // "leaking_param", "moved_to_heap", "does_not_escape", "inlineable"
-	Detail  string // variable or function name
+	Kind    string       // "leaking_param", "moved_to_heap", "escapes_to_heap", "does_not_escape", "inlineable", "not_inlineable", "inlined_call", "call_not_inlined", "bounds_check"
+	Detail  string       // variable or function name; callee for calls; IsInBounds or IsSliceInBounds
+	Reason  string       // why a function or call is not inlined
+	Cost    int          // inlining cost, when reported
+	Budget  int          // inlining budget a too complex function exceeds
+	Flow    []EscapeStep // -m=2 explanation of why the value escapes, in order
 }
 
-// RunEscapeAnalysis runs `go build -gcflags=-m` on each module directory
-// and parses the compiler's escape analysis decisions.
-func RunEscapeAnalysis(prog *Progress) []EscapeResult {
-	prog.Log("Running Go escape analysis (-gcflags=-m) across %d modules...", len(modSet.Dirs()))
+// EscapeStep is one step of a -m=2 escape explanation: a value flowing
+// from Src to Dst because of the expression Expr at the given position.
+type EscapeStep struct {
+	Dst, Src string // "flow: Dst ← Src"; Dst is {heap}, ~rN or a variable
+	Expr     string // "x := &y"
+	Reason   string // assign, address-of, return, spill, interface-converted, ...
+	RelFile  string
+	Line     int
+	Col      int
+}
+
+var (
+	escLineRe   = regexp.MustCompile(`^(?:\./)?([^:]+):(\d+):(\d+): (.+)$`)
+	escHeaderRe = regexp.MustCompile(`^(?:parameter \S+ leaks to \S+ for \S+ with derefs=-?\d+|.+ escapes to heap in \S+):$`)
+	escFlowRe   = regexp.MustCompile(`^flow: (.+) ← (.+):$`)
+	escFromRe   = regexp.MustCompile(`^from (.*) \(([^()]+)\) at (?:\./)?([^:]+):(\d+):(\d+)$`)
+
+	inlCostRe     = regexp.MustCompile(`^can inline (\S+) with cost (\d+)`)
+	noInlCallRe   = regexp.MustCompile(`^cannot inline (\S+) into \S+: (.+)$`)
+	noInlFuncRe   = regexp.MustCompile(`^cannot inline (\S+): (.+)$`)
+	inlBudgetRe   = regexp.MustCompile(`^(.+): cost (\d+) exceeds budget (\d+)$`)
+	boundsCheckRe = regexp.MustCompile(`^Found (Is(?:Slice)?InBounds)$`)
+)
+
+// EscapeRun is the outcome of escape analysis across all modules.
+type EscapeRun struct {
+	Results []EscapeResult    // annotations in the files the AST walk kept
+	Modules []EscapeModuleRun // one per module, in modSet order
+}
+
+// EscapeModuleRun records how the compiler run over one module went and how
+// many of its annotations resolved to CPG nodes.
+type EscapeModuleRun struct {
+	Module      string
+	ExitCode    int // -1 when the build could not be started
+	Duration    time.Duration
+	Annotations int // annotations in analyzed files
+	Matched     int // at the position of an AST or function node
+	Unmatched   int // at a position without a node
+	Skipped     int // in files the AST walk skipped (tests, generated, other modules)
+	Diagnostics []EscapeDiagnostic
+}
 
-	var allResults []EscapeResult
+// EscapeDiagnostic is one reason a module's compiler run failed: a compile
+// error, a go command error or, when neither was printed, the exit status.
+type EscapeDiagnostic struct {
+	Package string // from the "# pkg" header preceding a compile error
+	RelFile string
+	Line    int
+	Message string
+}
 
-	for _, mod := range modSet.Dirs() {
-		results := runEscapeForDir(mod.Dir, mod.Prefix, prog)
-		allResults = append(allResults, results...)
+// RunEscapeAnalysis runs `go build -gcflags=-m=2` on each module directory,
+// in parallel, against the workspace and build tags LoadPackages used, and
+// parses the compiler's escape analysis decisions together with their
+// explanation chains, its inlining decisions and the bounds checks
+// (-d=ssa/check_bce) left after prove. Annotations in files the AST walk
+// skipped are dropped; the others are counted as matched when a node sits
+// at their position.
+func RunEscapeAnalysis(goworkPath string, posLookup *PosLookup, funcLookup *FuncLookup, prog *Progress) *EscapeRun {
+	mods := modSet.Dirs()
+	prog.Log("Running Go escape analysis (-gcflags=-m=2 -d=ssa/check_bce) across %d modules...", len(mods))
+
+	runs := make([]EscapeModuleRun, len(mods))
+	results := make([][]EscapeResult, len(mods))
+	var wg sync.WaitGroup
+	for i, mod := range mods {
+		wg.Go(func() {
+			start := time.Now()
+			runs[i], results[i] = runEscapeForModule(mod, goworkPath)
+			runs[i].Duration = time.Since(start)
+		})
 	}
+	wg.Wait()
 
-	prog.Log("Escape analysis: %d annotations total", len(allResults))
-	return allResults
+	run := &EscapeRun{Modules: runs}
+	var matched, unmatched, skipped, failed int
+	for i := range runs {
+		m := &runs[i]
+		for _, r := range results[i] {
+			if posLookup.Get(r.RelFile, r.Line, r.Col) != "" || funcLookup.Get(r.RelFile, r.Line, r.Col) != "" {
+				m.Matched++
+			} else {
+				m.Unmatched++
+			}
+		}
+		run.Results = append(run.Results, results[i]...)
+		matched += m.Matched
+		unmatched += m.Unmatched
+		skipped += m.Skipped
+		if m.ExitCode != 0 || len(m.Diagnostics) > 0 {
+			failed++
+			msg := "no error output"
+			if len(m.Diagnostics) > 0 {
+				msg = m.Diagnostics[0].Message
+			}
+			prog.Log("  warning: escape analysis for %s failed (exit %d, %d diagnostics): %s",
+				m.Module, m.ExitCode, len(m.Diagnostics), msg)
+		}
+		prog.Verbose("  %s: %d annotations (%d matched), %d in skipped files, %s",
+			m.Module, m.Annotations, m.Matched, m.Skipped, m.Duration.Round(time.Millisecond))
+	}
+
+	prog.Log("Escape analysis: %d annotations (%d matched, %d unmatched), %d in skipped files, %d of %d modules failed",
+		len(run.Results), matched, unmatched, skipped, failed, len(mods))
+	return run
 }
 
-func runEscapeForDir(dir, prefix string, prog *Progress) []EscapeResult {
-	cmd := exec.Command("go", "build", "-gcflags=-m", "./...")
+// escapeBuild returns the go build command for dir with the loader's
+// workspace and build tags and the given extra arguments.
+func escapeBuild(dir, goworkPath string, args ...string) *exec.Cmd {
+	args = append(append([]string{"build"}, buildFlags()...), args...)
+	cmd := exec.Command("go", append(args, "./...")...)
 	cmd.Dir = dir
-	cmd.Env = replaceEnv(os.Environ(), "GOFLAGS", "-buildvcs=false")
+	env := replaceEnv(os.Environ(), "GOFLAGS", "-buildvcs=false")
+	cmd.Env = replaceEnv(env, "GOWORK", goworkPath)
+	return cmd
+}
+
+func runEscapeForModule(mod ModuleInfo, goworkPath string) (EscapeModuleRun, []EscapeResult) {
+	run := EscapeModuleRun{Module: mod.ModPath}
+	cmd := escapeBuild(mod.Dir, goworkPath, "-gcflags=-m=2 -d=ssa/check_bce/debug=1")
 	cmd.Stdout = nil // discard
 
 	stderrPipe, err := cmd.StderrPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
 	if err != nil {
-		prog.Verbose("Escape analysis for %s: failed to create stderr pipe: %v", dir, err)
-		return nil
+		run.ExitCode = -1
+		run.Diagnostics = []EscapeDiagnostic{{Message: err.Error()}}
+		return run, nil
 	}
-	if err := cmd.Start(); err != nil {
-		prog.Verbose("Escape analysis for %s: failed to start: %v", dir, err)
-		return nil
+
+	// Compiler paths are relative to the module directory; files the AST
+	// walk skipped map to "".
+	relFiles := make(map[string]string)
+	rel := func(file string) string {
+		relFile, ok := relFiles[file]
+		if !ok {
+			abs := file
+			if !filepath.IsAbs(abs) {
+				abs = filepath.Join(mod.Dir, abs)
+			}
+			if relFile = modSet.RelFile(abs); shouldSkipFile(relFile) {
+				relFile = ""
+			}
+			relFiles[file] = relFile
+		}
+		return relFile
 	}
 
-	lineRe := regexp.MustCompile(`^(?:\./)?([^:]+):(\d+):(\d+): (.+)$`)
+	results, skipped, err := parseEscapeOutput(stderrPipe, rel)
+	run.Annotations, run.Skipped = len(results), skipped
 
-	var results []EscapeResult
-	scanner := bufio.NewScanner(stderrPipe)
+	// A line over the scanner's cap ends the scan; drain the rest so the
+	// compiler does not block on a full pipe, and record the lost output.
+	if err != nil {
+		run.Diagnostics = append(run.Diagnostics, EscapeDiagnostic{
+			Message: "reading compiler output: " + err.Error() + "; later annotations are missing",
+		})
+	}
+	_, _ = io.Copy(io.Discard, stderrPipe)
+
+	if err := cmd.Wait(); err != nil {
+		run.ExitCode = -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			run.ExitCode = exitErr.ExitCode()
+		}
+		diags := escapeBuildErrors(mod.Dir, goworkPath, rel)
+		if len(diags) == 0 {
+			diags = []EscapeDiagnostic{{Message: err.Error()}}
+		}
+		run.Diagnostics = append(run.Diagnostics, diags...)
+	}
+	return run, results
+}
+
+// parseEscapeOutput reads the compiler's -m=2 and check_bce output. rel maps
+// a compiler path to the file's relative path, "" for files to skip;
+// annotations in skipped files are counted but not returned. The error is
+// the scanner's: output after an overlong line is not read.
+func parseEscapeOutput(r io.Reader, rel func(string) string) (results []EscapeResult, skipped int, err error) {
+	// Explanations precede the summary line at the same position; a
+	// position may be explained several times (a parameter leaking to the
+	// heap and to a result).
+	pending := make(map[string][]EscapeStep) // file:line:col → explanation
+	var cur string
+	var dst, src string
+
+	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
 	for scanner.Scan() {
 		text := scanner.Text()
-		if strings.HasPrefix(text, "#") || strings.HasPrefix(text, "/") {
+		if strings.HasPrefix(text, "#") {
 			continue
 		}
-		m := lineRe.FindStringSubmatch(text)
+		m := escLineRe.FindStringSubmatch(text)
 		if m == nil {
 			continue
 		}
@@ -70,8 +236,34 @@ func runEscapeForDir(dir, prefix string, prog *Progress) []EscapeResult {
 		line, _ := strconv.Atoi(m[2])
 		col, _ := strconv.Atoi(m[3])
 		msg := m[4]
+		key := fmt.Sprintf("%s:%d:%d", file, line, col)
+
+		if body := strings.TrimLeft(msg, " "); body != msg {
+			// Indented: part of the explanation being read
+			if cur == "" {
+				continue
+			}
+			if fm := escFlowRe.FindStringSubmatch(body); fm != nil {
+				dst, src = fm[1], fm[2]
+			} else if fm := escFromRe.FindStringSubmatch(body); fm != nil {
+				l, _ := strconv.Atoi(fm[4])
+				c, _ := strconv.Atoi(fm[5])
+				pending[cur] = append(pending[cur], EscapeStep{
+					Dst: dst, Src: src, Expr: fm[1], Reason: fm[2],
+					RelFile: rel(fm[3]), Line: l, Col: c,
+				})
+			}
+			continue
+		}
+		cur = ""
+		if escHeaderRe.MatchString(msg) {
+			cur = key
+			dst, src = "", ""
+			continue
+		}
 
-		var kind, detail string
+		var kind, detail, reason string
+		var cost, budget int
 		switch {
 		case strings.Contains(msg, "leaking param:"):
 			kind = "leaking_param"
@@ -92,25 +284,262 @@ func runEscapeForDir(dir, prefix string, prog *Progress) []EscapeResult {
 		case strings.HasPrefix(msg, "can inline "):
 			kind = "inlineable"
 			detail = strings.TrimPrefix(msg, "can inline ")
+			if im := inlCostRe.FindStringSubmatch(msg); im != nil {
+				detail = im[1]
+				cost, _ = strconv.Atoi(im[2])
+			}
+		case strings.HasPrefix(msg, "inlining call to "):
+			kind = "inlined_call"
+			detail = strings.TrimPrefix(msg, "inlining call to ")
+		case noInlCallRe.MatchString(msg):
+			im := noInlCallRe.FindStringSubmatch(msg)
+			kind, detail, reason = "call_not_inlined", im[1], im[2]
+		case noInlFuncRe.MatchString(msg):
+			im := noInlFuncRe.FindStringSubmatch(msg)
+			kind, detail, reason = "not_inlineable", im[1], im[2]
+			if bm := inlBudgetRe.FindStringSubmatch(reason); bm != nil {
+				reason = bm[1]
+				cost, _ = strconv.Atoi(bm[2])
+				budget, _ = strconv.Atoi(bm[3])
+			}
+		case boundsCheckRe.MatchString(msg):
+			kind = "bounds_check"
+			detail = boundsCheckRe.FindStringSubmatch(msg)[1]
 		default:
 			continue
 		}
 
-		// Prefix the file path for non-primary modules
-		relFile := file
-		if prefix != "" {
-			relFile = prefix + "/" + file
+		relFile := rel(file)
+		if relFile == "" {
+			skipped++
+			delete(pending, key)
+			continue
 		}
-
-		results = append(results, EscapeResult{
+		r := EscapeResult{
 			RelFile: relFile,
 			Line:    line,
 			Col:     col,
 			Kind:    kind,
 			Detail:  detail,
-		})
+			Reason:  reason,
+			Cost:    cost,
+			Budget:  budget,
+		}
+		if kind == "leaking_param" || kind == "moved_to_heap" || kind == "escapes_to_heap" {
+			r.Flow = pending[key]
+			delete(pending, key)
+		}
+		results = append(results, r)
 	}
+	return results, skipped, scanner.Err()
+}
+
+// escapeBuildErrors repeats a failed build without -gcflags to collect its
+// errors, which the -m output cannot be told apart from. Packages that did
+// compile come from the build cache.
+func escapeBuildErrors(dir, goworkPath string, rel func(string) string) []EscapeDiagnostic {
+	out, _ := escapeBuild(dir, goworkPath).CombinedOutput()
 
-	_ = cmd.Wait()
-	return results
+	var diags []EscapeDiagnostic
+	var pkg string
+	for _, text := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.TrimSpace(text) == "":
+		case strings.HasPrefix(text, "# "):
+			pkg = strings.TrimPrefix(text, "# ")
+		case strings.HasPrefix(text, "\t") || strings.HasPrefix(text, " "):
+			// Continuation of the previous error ("have ... want ...")
+			if len(diags) > 0 {
+				diags[len(diags)-1].Message += "\n" + strings.TrimSpace(text)
+			}
+		default:
+			d := EscapeDiagnostic{Package: pkg, Message: text}
+			if m := escLineRe.FindStringSubmatch(text); m != nil {
+				if relFile := rel(m[1]); relFile != "" {
+					d.RelFile = relFile
+					d.Line, _ = strconv.Atoi(m[2])
+					d.Message = m[4]
+				}
+			}
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// storageRe matches the compiler's name for the memory of an allocation:
+// "&{storage for make([]int, n)}".
+var storageRe = regexp.MustCompile(`^&?\{storage for (.*)\}$`)
+
+// AttachEscapeFlows attaches heap escapes to the exact AST nodes they
+// concern and turns their explanations into escape_flow edges. The
+// escaping value is the local, parameter, call (make, new, append),
+// composite literal (for &T{...}, the literal under the address-of) or
+// func literal at the annotation's position; it gets escape (the kind)
+// and escape_chain, the explanation steps with the AST node of each step's
+// expression.
+//
+// Each "flow: dst ← src" hop becomes an escape_flow edge between the
+// nodes of its variables, resolved by name within the enclosing function
+// to the last declaration of that name before the step, so a shadowing
+// variable wins over the one it shadows: the allocation for "{storage for
+// ...}", the function node for results (~r0), and no edge for {heap},
+// where the chain ends.
+func AttachEscapeFlows(results []EscapeResult, posLookup *PosLookup, cpg *CPG, prog *Progress) {
+	nodeByID := make(map[string]*Node, len(cpg.Nodes))
+	vars := make(map[string][]*Node) // parent function + "\x00" + name → locals/parameters
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		nodeByID[n.ID] = n
+		if (n.Kind == "local" || n.Kind == "parameter") && n.ParentFunction != "" {
+			key := n.ParentFunction + "\x00" + n.Name
+			vars[key] = append(vars[key], n)
+		}
+	}
+	for _, decls := range vars {
+		sort.Slice(decls, func(i, j int) bool { return posBefore(decls[i].Line, decls[i].Col, decls[j].Line, decls[j].Col) })
+	}
+	// declaredAt resolves a name to its last declaration at or before the
+	// position, or the first one when none precedes it.
+	declaredAt := func(key string, line, col int) string {
+		decls := vars[key]
+		if len(decls) == 0 {
+			return ""
+		}
+		best := decls[0]
+		for _, d := range decls[1:] {
+			if posBefore(line, col, d.Line, d.Col) {
+				break
+			}
+			best = d
+		}
+		return best.ID
+	}
+	children := make(map[string][]string)
+	for _, e := range cpg.Edges {
+		if e.Kind == "ast" {
+			children[e.Source] = append(children[e.Source], e.Target)
+		}
+	}
+
+	// valueNode resolves an annotation position to the node of the value.
+	valueNode := func(file string, line, col int) *Node {
+		n := nodeByID[posLookup.Get(file, line, col)]
+		if n == nil {
+			return nil
+		}
+		if n.Kind == "unary_expr" {
+			for _, c := range children[n.ID] {
+				if child := nodeByID[c]; child != nil && child.Kind == "composite_lit" {
+					return child
+				}
+			}
+		}
+		return n
+	}
+
+	// Allocations by function and compiler name, for "{storage for X}"
+	// naming another escaping value (the func literal capturing a local).
+	isEscape := func(r EscapeResult) bool {
+		return r.Kind == "moved_to_heap" || r.Kind == "escapes_to_heap" || r.Kind == "leaking_param"
+	}
+	storage := make(map[string]string)
+	for _, r := range results {
+		if isEscape(r) {
+			if n := valueNode(r.RelFile, r.Line, r.Col); n != nil {
+				storage[n.ParentFunction+"\x00"+r.Detail] = n.ID
+			}
+		}
+	}
+
+	var attached, flows int
+	for _, r := range results {
+		if !isEscape(r) {
+			continue
+		}
+		n := valueNode(r.RelFile, r.Line, r.Col)
+		if n == nil {
+			continue
+		}
+		if n.Properties == nil {
+			n.Properties = map[string]any{}
+		}
+		if _, done := n.Properties["escape"]; done {
+			continue
+		}
+		attached++
+		n.Properties["escape"] = r.Kind
+		if len(r.Flow) == 0 {
+			continue
+		}
+
+		fn := n.ParentFunction
+		resolve := func(name string, s EscapeStep) string {
+			name = strings.TrimPrefix(name, "&")
+			if m := storageRe.FindStringSubmatch(name); m != nil && m[1] != r.Detail {
+				return storage[fn+"\x00"+m[1]]
+			}
+			switch {
+			case storageRe.MatchString(name), name == r.Detail:
+				return n.ID
+			case name == "{heap}":
+				return ""
+			case strings.HasPrefix(name, "~r"):
+				return fn
+			}
+			return declaredAt(fn+"\x00"+name, s.Line, s.Col)
+		}
+
+		chain := make([]map[string]any, 0, len(r.Flow))
+		type hop struct{ src, dst string }
+		hopSteps := make(map[hop][]EscapeStep)
+		var hops []hop
+		for _, s := range r.Flow {
+			chain = append(chain, map[string]any{
+				"flow":   s.Dst + " ← " + s.Src,
+				"expr":   s.Expr,
+				"reason": s.Reason,
+				"site":   posLookup.Get(s.RelFile, s.Line, s.Col),
+				"file":   s.RelFile,
+				"line":   s.Line,
+			})
+			h := hop{resolve(s.Src, s), resolve(s.Dst, s)}
+			if h.src == "" || h.dst == "" || h.src == h.dst {
+				continue
+			}
+			if _, seen := hopSteps[h]; !seen {
+				hops = append(hops, h)
+			}
+			hopSteps[h] = append(hopSteps[h], s)
+		}
+		n.Properties["escape_chain"] = chain
+		for _, h := range hops {
+			steps := hopSteps[h]
+			reasons := make([]string, 0, len(steps))
+			for _, s := range steps {
+				reasons = append(reasons, s.Reason)
+			}
+			cpg.AddEdge(Edge{
+				Source: h.src,
+				Target: h.dst,
+				Kind:   "escape_flow",
+				Properties: map[string]any{
+					"flow":    steps[0].Dst + " ← " + steps[0].Src,
+					"reasons": reasons,
+					"escape":  n.ID,
+				},
+			})
+			flows++
+		}
+	}
+
+	prog.Log("Escape flows: %d escaping values attached to nodes, %d escape_flow edges", attached, flows)
+}
+
+// posBefore reports whether line:col comes before line2:col2.
+func posBefore(line, col, line2, col2 int) bool {
+	if line != line2 {
+		return line < line2
+	}
+	return col < col2
 }
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRel maps compiler paths as rel does in runEscapeForModule, skipping
+// generated files.
+func testRel(file string) string {
+	if strings.HasSuffix(file, "_gen.go") {
+		return ""
+	}
+	return "pkg/" + file
+}
+
+func TestParseEscapeOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		out         string
+		want        []EscapeResult
+		wantSkipped int
+	}{
+		{
+			name: "summary lines",
+			out: `# example.com/m/esc
+esc/esc.go:8:11: leaking param: p
+esc/esc.go:11:2: moved to heap: t
+esc/esc.go:16:9: &T{...} escapes to heap
+esc/esc.go:23:11: p does not escape
+esc/esc.go:30:6: some other note
+`,
+			want: []EscapeResult{
+				{RelFile: "pkg/esc/esc.go", Line: 8, Col: 11, Kind: "leaking_param", Detail: "p"},
+				{RelFile: "pkg/esc/esc.go", Line: 11, Col: 2, Kind: "moved_to_heap", Detail: "t"},
+				{RelFile: "pkg/esc/esc.go", Line: 16, Col: 9, Kind: "escapes_to_heap", Detail: "&T{...}"},
+				{RelFile: "pkg/esc/esc.go", Line: 23, Col: 11, Kind: "does_not_escape", Detail: "p"},
+			},
+		},
+		{
+			name: "flows attach to the summary at the same position",
+			out: `esc/esc.go:8:11: parameter p leaks to {heap} for Leak with derefs=0:
+esc/esc.go:8:11:   flow: {heap} ← p:
+esc/esc.go:8:11:     from global = p (assign) at esc/esc.go:8:26
+esc/esc.go:8:11: leaking param: p
+esc/esc.go:26:2: y escapes to heap in Chain:
+esc/esc.go:26:2:   flow: x ← &y:
+esc/esc.go:26:2:     from &y (address-of) at esc/esc.go:27:7
+esc/esc.go:26:2:   flow: {heap} ← x:
+esc/esc.go:26:2:     from x (interface-converted) at esc/esc.go:29:9
+esc/esc.go:26:2: moved to heap: y
+esc/esc.go:23:11: p does not escape
+`,
+			want: []EscapeResult{
+				{RelFile: "pkg/esc/esc.go", Line: 8, Col: 11, Kind: "leaking_param", Detail: "p", Flow: []EscapeStep{
+					{Dst: "{heap}", Src: "p", Expr: "global = p", Reason: "assign", RelFile: "pkg/esc/esc.go", Line: 8, Col: 26},
+				}},
+				{RelFile: "pkg/esc/esc.go", Line: 26, Col: 2, Kind: "moved_to_heap", Detail: "y", Flow: []EscapeStep{
+					{Dst: "x", Src: "&y", Expr: "&y", Reason: "address-of", RelFile: "pkg/esc/esc.go", Line: 27, Col: 7},
+					{Dst: "{heap}", Src: "x", Expr: "x", Reason: "interface-converted", RelFile: "pkg/esc/esc.go", Line: 29, Col: 9},
+				}},
+				{RelFile: "pkg/esc/esc.go", Line: 23, Col: 11, Kind: "does_not_escape", Detail: "p"},
+			},
+		},
+		{
+			name: "explanation of another position is not attached",
+			out: `esc/esc.go:39:9: func literal escapes to heap in Closure:
+esc/esc.go:39:9:   flow: ~r0 ← &{storage for func literal}:
+esc/esc.go:39:9:     from return func literal (return) at esc/esc.go:39:2
+esc/esc.go:38:2: c escapes to heap in Closure:
+esc/esc.go:38:2:   flow: {storage for func literal} ← &c:
+esc/esc.go:38:2:     from c (captured by a closure) at esc/esc.go:39:22
+esc/esc.go:38:2: moved to heap: c
+esc/esc.go:39:9: func literal escapes to heap
+`,
+			want: []EscapeResult{
+				{RelFile: "pkg/esc/esc.go", Line: 38, Col: 2, Kind: "moved_to_heap", Detail: "c", Flow: []EscapeStep{
+					{Dst: "{storage for func literal}", Src: "&c", Expr: "c", Reason: "captured by a closure", RelFile: "pkg/esc/esc.go", Line: 39, Col: 22},
+				}},
+				{RelFile: "pkg/esc/esc.go", Line: 39, Col: 9, Kind: "escapes_to_heap", Detail: "func literal", Flow: []EscapeStep{
+					{Dst: "~r0", Src: "&{storage for func literal}", Expr: "return func literal", Reason: "return", RelFile: "pkg/esc/esc.go", Line: 39, Col: 2},
+				}},
+			},
+		},
+		{
+			name: "skipped files are counted",
+			out: `esc/x_gen.go:4:2: x escapes to heap in F:
+esc/x_gen.go:4:2:   flow: {heap} ← x:
+esc/x_gen.go:4:2:     from sink = x (assign) at esc/x_gen.go:5:7
+esc/x_gen.go:4:2: moved to heap: x
+esc/x_gen.go:9:8: q does not escape
+esc/esc.go:23:11: p does not escape
+`,
+			want: []EscapeResult{
+				{RelFile: "pkg/esc/esc.go", Line: 23, Col: 11, Kind: "does_not_escape", Detail: "p"},
+			},
+			wantSkipped: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped, err := parseEscapeOutput(strings.NewReader(tt.out), testRel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results:\n got %+v\nwant %+v", got, tt.want)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("skipped = %d, want %d", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestParseEscapeOutputLongLine(t *testing.T) {
+	out := "esc/esc.go:1:1: p does not escape\n" +
+		"esc/esc.go:2:1: " + strings.Repeat("x", 2*1024*1024) + "\n" +
+		"esc/esc.go:3:1: q does not escape\n"
+	got, _, err := parseEscapeOutput(strings.NewReader(out), testRel)
+	if err == nil {
+		t.Fatal("no error for a line over the scanner's cap")
+	}
+	if len(got) != 1 || got[0].Detail != "p" {
+		t.Errorf("results before the long line = %+v", got)
+	}
+}
+
+func TestParseEscapeOutputOptimizations(t *testing.T) {
+	out := `esc/hot.go:22:6: can inline Big with cost 64 as: func(int) int { return x * 2 }
+esc/hot.go:3:6: can inline Sum
+esc/hot.go:11:6: cannot inline Pick: function too complex: cost 95 exceeds budget 80
+esc/call.go:13:6: cannot inline NoInl: marked go:noinline
+esc/hot.go:15:11: inlining call to Big
+esc/call.go:15:41: cannot inline Rec into CallNo: repeated recursive cycle
+esc/hot.go:14:10: Found IsInBounds
+esc/hot.go:44:36: Found IsSliceInBounds
+esc/x_gen.go:2:6: can inline Gen with cost 2 as: func() {  }
+`
+	want := []EscapeResult{
+		{RelFile: "pkg/esc/hot.go", Line: 22, Col: 6, Kind: "inlineable", Detail: "Big", Cost: 64},
+		{RelFile: "pkg/esc/hot.go", Line: 3, Col: 6, Kind: "inlineable", Detail: "Sum"},
+		{RelFile: "pkg/esc/hot.go", Line: 11, Col: 6, Kind: "not_inlineable", Detail: "Pick", Reason: "function too complex", Cost: 95, Budget: 80},
+		{RelFile: "pkg/esc/call.go", Line: 13, Col: 6, Kind: "not_inlineable", Detail: "NoInl", Reason: "marked go:noinline"},
+		{RelFile: "pkg/esc/hot.go", Line: 15, Col: 11, Kind: "inlined_call", Detail: "Big"},
+		{RelFile: "pkg/esc/call.go", Line: 15, Col: 41, Kind: "call_not_inlined", Detail: "Rec", Reason: "repeated recursive cycle"},
+		{RelFile: "pkg/esc/hot.go", Line: 14, Col: 10, Kind: "bounds_check", Detail: "IsInBounds"},
+		{RelFile: "pkg/esc/hot.go", Line: 44, Col: 36, Kind: "bounds_check", Detail: "IsSliceInBounds"},
+	}
+	got, skipped, err := parseEscapeOutput(strings.NewReader(out), testRel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d:\n%+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("result %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+}
+
+func TestAttachEscapeFlowsShadowed(t *testing.T) {
+	// func F(c bool) any {
+	//	x := 0      // 4:2
+	//	z := 1      // 5:2
+	//	if c {
+	//		x := &z // 7:3, flow at 7:8
+	//		return x
+	//	}
+	//	return x
+	// }
+	cpg := NewCPG()
+	for _, n := range []Node{
+		{ID: "f:F", Kind: "function", Name: "F"},
+		{ID: "l:x4", Kind: "local", Name: "x", Line: 4, Col: 2, ParentFunction: "f:F"},
+		{ID: "l:z5", Kind: "local", Name: "z", Line: 5, Col: 2, ParentFunction: "f:F"},
+		{ID: "l:x7", Kind: "local", Name: "x", Line: 7, Col: 3, ParentFunction: "f:F"},
+	} {
+		cpg.AddNode(n)
+	}
+	posLookup := NewPosLookup()
+	posLookup.Set("f.go", 5, 2, "l:z5")
+
+	results := []EscapeResult{{
+		RelFile: "f.go", Line: 5, Col: 2, Kind: "moved_to_heap", Detail: "z",
+		Flow: []EscapeStep{
+			{Dst: "x", Src: "&z", Expr: "&z", Reason: "address-of", RelFile: "f.go", Line: 7, Col: 8},
+			{Dst: "~r0", Src: "x", Expr: "return x", Reason: "return", RelFile: "f.go", Line: 8, Col: 3},
+		},
+	}}
+	AttachEscapeFlows(results, posLookup, cpg, NewProgress(false))
+
+	var got []string
+	for _, e := range cpg.Edges {
+		if e.Kind == "escape_flow" {
+			got = append(got, e.Source+" → "+e.Target)
+		}
+	}
+	want := []string{"l:z5 → l:x7", "l:x7 → f:F"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("escape_flow edges = %q, want %q", got, want)
+	}
+}
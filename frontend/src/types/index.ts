@@ -93,6 +93,7 @@ export interface CallGraphEdge {
   source: string;
   target: string;
   kind: string;
+  kinds: string[];
 }
 
 export interface CallGraph {
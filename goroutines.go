@@ -0,0 +1,593 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// AnalyzeGoroutines builds the goroutine spawn tree and looks for goroutines
+// that may block forever.
+//
+// Every go statement with a statically known target yields a spawn edge
+// from the spawning function to the spawned one, marked function_level to
+// tell it from the go statement's own spawn edge. The tree is rooted at the
+// entry points found by ComputeReachability: level 0 is everything the
+// entries call, level k+1 the functions spawned from level k. Spawned
+// functions get goroutine=true, spawn_sites, and when reachable
+// spawn_depth, spawn_parent (the goroutine whose code spawned them) and
+// spawn_root (the entry point).
+//
+// A blocking operation in a spawned function — a channel send or receive,
+// a select without default, sync.WaitGroup.Wait — is a possible leak when
+// nothing in the program can unblock it: no receiver for a send on an
+// unbuffered channel, no sender or close for a receive, no close for a
+// receive looping until the channel is closed, no ready case in a select
+// (ctx.Done() always counts as ready), no Done for a Wait. Operations on
+// channels that cannot be traced to a make(chan) are assumed to unblock.
+// Helpers the spawned function calls are checked too, with via naming the
+// helper that blocks. Leaks are stored in goroutine_leaks on the spawned
+// function.
+//
+// Must run after ComputeReachability.
+func AnalyzeGoroutines(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Analyzing goroutines...")
+
+	progSSA := ssaResult.Prog
+	nodeID := func(fn *ssa.Function) string {
+		fn = sourceFunc(progSSA, fn)
+		if fn == nil {
+			return ""
+		}
+		return ssaFuncNodeID(fn, fset, funcLookup)
+	}
+
+	// Go sites, keyed by spawning function ID.
+	type spawnSite struct {
+		siteID   string
+		callee   *ssa.Function
+		calleeID string
+	}
+	spawnsBy := make(map[string][]spawnSite)
+	// Spawned functions by node ID: instantiations of a generic function
+	// share their origin's node and are analyzed once, as the origin when
+	// it was analyzed, else as the first instantiation by name.
+	spawned := make(map[string]*ssa.Function)
+	siteIDs := make(map[string][]string) // spawned ID → go statement IDs
+	var spawnEdges int
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		srcID := ssaFuncNodeID(fn, fset, funcLookup)
+		if srcID == "" {
+			continue
+		}
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				g, ok := instr.(*ssa.Go)
+				if !ok {
+					continue
+				}
+				callee := g.Call.StaticCallee()
+				if callee == nil {
+					continue
+				}
+				calleeID := nodeID(callee)
+				if calleeID == "" {
+					continue
+				}
+				siteID := ""
+				if file, line, col := instrPos(g, fset); file != "" {
+					siteID = posLookup.Get(file, line, col)
+				}
+				spawnsBy[srcID] = append(spawnsBy[srcID], spawnSite{siteID, callee, calleeID})
+				if src := sourceFunc(progSSA, callee); ssaResult.AllFuncs[src] {
+					spawned[calleeID] = src
+				} else if prev, ok := spawned[calleeID]; !ok || callee.String() < prev.String() {
+					spawned[calleeID] = callee
+				}
+				if siteID != "" {
+					siteIDs[calleeID] = append(siteIDs[calleeID], siteID)
+				}
+				cpg.AddEdge(Edge{
+					Source: srcID, Target: calleeID, Kind: "spawn",
+					Properties: map[string]any{"site": siteID, "function_level": true},
+				})
+				spawnEdges++
+			}
+		}
+	}
+
+	// Call edges also cover go statements; drop those so a spawned function
+	// is not owned by its spawner's goroutine.
+	spawnPair := make(map[[2]string]bool)
+	for src, sites := range spawnsBy {
+		for _, s := range sites {
+			spawnPair[[2]string{src, s.calleeID}] = true
+		}
+	}
+	callSuccs := make(map[string][]string)
+	for _, e := range cpg.Edges {
+		if e.Kind == "call" && !spawnPair[[2]string{e.Source, e.Target}] {
+			callSuccs[e.Source] = append(callSuccs[e.Source], e.Target)
+		}
+	}
+
+	// Entry points: depth 0 of the reachability BFS.
+	var entries []string
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if n.Kind != "function" || n.Properties == nil {
+			continue
+		}
+		if d, ok := n.Properties["reach_depth"].(int); ok && d == 0 && n.Properties["reachable"] == true {
+			entries = append(entries, n.ID)
+		}
+	}
+	sort.Strings(entries)
+
+	// Level-by-level: each goroutine root owns the functions it calls that
+	// no earlier root owns; go sites in owned functions spawn the next level.
+	owner := make(map[string]string)
+	type rootInfo struct {
+		depth        int
+		parent, root string
+	}
+	roots := make(map[string]rootInfo)
+	level := entries
+	for _, id := range entries {
+		roots[id] = rootInfo{depth: 0, root: id}
+	}
+	for depth := 0; len(level) > 0; depth++ {
+		queue := make([]string, 0, len(level))
+		for _, r := range level {
+			if _, ok := owner[r]; !ok || roots[r].depth > 0 {
+				owner[r] = r
+				queue = append(queue, r)
+			}
+		}
+		var next []string
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, s := range spawnsBy[cur] {
+				if _, ok := roots[s.calleeID]; ok {
+					continue
+				}
+				parent := owner[cur]
+				roots[s.calleeID] = rootInfo{depth: depth + 1, parent: parent, root: roots[parent].root}
+				next = append(next, s.calleeID)
+			}
+			for _, dst := range callSuccs[cur] {
+				if _, ok := owner[dst]; ok {
+					continue
+				}
+				owner[dst] = owner[cur]
+				queue = append(queue, dst)
+			}
+		}
+		sort.Strings(next)
+		level = next
+	}
+
+	chans, chanIndex := traceChannels(ssaResult)
+	waitGroups := newWaitGroupIndex(ssaResult)
+
+	type leakEntry struct {
+		file      string
+		line, col int
+		props     map[string]any
+	}
+	leaks := make(map[string][]leakEntry)
+	var leakCount int
+	for calleeID, callee := range spawned {
+		for _, l := range goroutineLeaks(callee, chanIndex, waitGroups) {
+			file, line, col := instrPos(l.instr, fset)
+			site := ""
+			if file != "" {
+				site = posLookup.Get(file, line, col)
+			}
+			leak := map[string]any{
+				"site":    site,
+				"file":    file,
+				"line":    line,
+				"op":      l.op,
+				"missing": l.missing,
+			}
+			if l.ch != nil {
+				if mf, ml, _ := instrPos(l.ch.make, fset); mf != "" {
+					leak["channel"] = fmt.Sprintf("%s:%d", mf, ml)
+				}
+			}
+			if l.fn != callee {
+				if via := nodeID(l.fn); via != "" {
+					leak["via"] = via
+				}
+			}
+			leaks[calleeID] = append(leaks[calleeID], leakEntry{file, line, col, leak})
+			leakCount++
+		}
+	}
+
+	var goroutines int
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if _, ok := spawned[n.ID]; !ok {
+			continue
+		}
+		sites := siteIDs[n.ID]
+		if sites == nil {
+			sites = []string{}
+		}
+		if n.Properties == nil {
+			n.Properties = map[string]any{}
+		}
+		goroutines++
+		n.Properties["goroutine"] = true
+		sort.Strings(sites)
+		n.Properties["spawn_sites"] = sites
+		if ri, ok := roots[n.ID]; ok {
+			n.Properties["spawn_depth"] = ri.depth
+			n.Properties["spawn_parent"] = ri.parent
+			n.Properties["spawn_root"] = ri.root
+		}
+		if l := leaks[n.ID]; l != nil {
+			sort.Slice(l, func(i, j int) bool {
+				if l[i].line != l[j].line {
+					return l[i].line < l[j].line
+				}
+				if l[i].file != l[j].file {
+					return l[i].file < l[j].file
+				}
+				if l[i].col != l[j].col {
+					return l[i].col < l[j].col
+				}
+				return l[i].props["site"].(string) < l[j].props["site"].(string)
+			})
+			props := make([]map[string]any, len(l))
+			for i, e := range l {
+				props[i] = e.props
+			}
+			n.Properties["goroutine_leaks"] = props
+		}
+	}
+
+	prog.Log("Goroutines: %d function-level spawn edges, %d goroutine functions, %d traced channels, %d possible leaks",
+		spawnEdges, goroutines, len(chans), leakCount)
+}
+
+// goroutineLeak is a blocking operation with no unblocking counterpart.
+type goroutineLeak struct {
+	instr   ssa.Instruction
+	fn      *ssa.Function // function containing instr
+	op      string
+	missing string
+	ch      *chanIdent
+}
+
+// goroutineLeaks checks the blocking operations in a spawned function and
+// in the helpers it calls statically, up to constMaxDepth calls deep.
+// Functions of unknown packages are not entered.
+func goroutineLeaks(fn *ssa.Function, chanIndex map[chanOpKey][]*chanIdent, wgs *waitGroupIndex) []goroutineLeak {
+	var leaks []goroutineLeak
+	seen := map[*ssa.Function]bool{fn: true}
+	level := []*ssa.Function{fn}
+	for depth := 0; len(level) > 0 && depth <= constMaxDepth; depth++ {
+		var next []*ssa.Function
+		for _, f := range level {
+			leaks = append(leaks, blockingLeaks(f, chanIndex, wgs)...)
+			for _, block := range f.Blocks {
+				for _, instr := range block.Instrs {
+					call, ok := instr.(*ssa.Call)
+					if !ok {
+						continue
+					}
+					callee := call.Call.StaticCallee()
+					if callee == nil || seen[callee] || len(callee.Blocks) == 0 {
+						continue
+					}
+					if callee.Pkg == nil || !modSet.IsKnownPkg(callee.Pkg.Pkg.Path()) {
+						continue
+					}
+					seen[callee] = true
+					next = append(next, callee)
+				}
+			}
+		}
+		level = next
+	}
+	return leaks
+}
+
+// blockingLeaks checks the blocking operations in fn's own body. A receive
+// that is the condition of a loop (range over a channel, or v, ok := <-ch
+// inside a loop) needs a close to end.
+func blockingLeaks(fn *ssa.Function, chanIndex map[chanOpKey][]*chanIdent, wgs *waitGroupIndex) []goroutineLeak {
+	inLoop := make(map[int]bool)
+	if len(fn.Blocks) >= 2 {
+		for _, l := range naturalLoops(fn.Blocks) {
+			for b := range l.body {
+				inLoop[b] = true
+			}
+		}
+	}
+
+	var leaks []goroutineLeak
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			switch inst := instr.(type) {
+			case *ssa.Send:
+				chs := chanIndex[chanOpKey{inst, -1}]
+				if len(chs) == 0 {
+					continue
+				}
+				if blocked, ch := allBlocked(chs, func(c *chanIdent) bool {
+					return !c.buffered() && !c.has("recv")
+				}); blocked {
+					leaks = append(leaks, goroutineLeak{inst, fn, "send", "receiver", ch})
+				}
+			case *ssa.UnOp:
+				if inst.Op != token.ARROW {
+					continue
+				}
+				chs := chanIndex[chanOpKey{inst, -1}]
+				if len(chs) == 0 {
+					continue
+				}
+				if inst.CommaOk && inLoop[block.Index] {
+					if blocked, ch := allBlocked(chs, func(c *chanIdent) bool {
+						return !c.has("close")
+					}); blocked {
+						leaks = append(leaks, goroutineLeak{inst, fn, "range_recv", "close", ch})
+					}
+					continue
+				}
+				if blocked, ch := allBlocked(chs, func(c *chanIdent) bool {
+					return !c.has("send", "close")
+				}); blocked {
+					leaks = append(leaks, goroutineLeak{inst, fn, "recv", "sender or close", ch})
+				}
+			case *ssa.Select:
+				if !inst.Blocking || len(inst.States) == 0 {
+					continue
+				}
+				ready := false
+				var first *chanIdent
+				for i, st := range inst.States {
+					if isContextDone(st.Chan) {
+						ready = true
+						break
+					}
+					chs := chanIndex[chanOpKey{inst, i}]
+					if len(chs) == 0 {
+						ready = true
+						break
+					}
+					need := []string{"send", "close"}
+					if st.Dir == types.SendOnly {
+						need = []string{"recv"}
+					}
+					blocked, ch := allBlocked(chs, func(c *chanIdent) bool {
+						return !c.has(need...) && !(st.Dir == types.SendOnly && c.buffered())
+					})
+					if !blocked {
+						ready = true
+						break
+					}
+					if first == nil {
+						first = ch
+					}
+				}
+				if !ready {
+					leaks = append(leaks, goroutineLeak{inst, fn, "select", "ready case or ctx.Done()", first})
+				}
+			case *ssa.Call:
+				recv, ok := waitGroupCall(&inst.Call, "Wait")
+				if !ok {
+					continue
+				}
+				if wgs.unresolvedDone {
+					continue
+				}
+				keys := wgs.resolve(recv)
+				if len(keys) == 0 {
+					continue
+				}
+				done := false
+				for _, k := range keys {
+					if wgs.done[k] {
+						done = true
+						break
+					}
+				}
+				if !done {
+					leaks = append(leaks, goroutineLeak{inst, fn, "wg_wait", "wg.Done", nil})
+				}
+			}
+		}
+	}
+	return leaks
+}
+
+// allBlocked reports whether blocked holds for every channel the operation
+// may act on, returning the first one.
+func allBlocked(chs []*chanIdent, blocked func(*chanIdent) bool) (bool, *chanIdent) {
+	for _, c := range chs {
+		if !blocked(c) {
+			return false, nil
+		}
+	}
+	return true, chs[0]
+}
+
+// isContextDone reports whether v is the result of calling Done on a
+// context.Context (or a type implementing it).
+func isContextDone(v ssa.Value) bool {
+	call, ok := v.(*ssa.Call)
+	if !ok {
+		return false
+	}
+	if call.Call.IsInvoke() {
+		return call.Call.Method.Name() == "Done" && isContextLike(call.Call.Value.Type())
+	}
+	callee := call.Call.StaticCallee()
+	if callee == nil || callee.Name() != "Done" || callee.Signature.Recv() == nil {
+		return false
+	}
+	return isContextLike(callee.Signature.Recv().Type())
+}
+
+// isContextLike reports whether t is context.Context or implements it.
+func isContextLike(t types.Type) bool {
+	if isContextType(t) {
+		return true
+	}
+	ms := types.NewMethodSet(t)
+	return ms.Lookup(nil, "Done") != nil && ms.Lookup(nil, "Deadline") != nil && ms.Lookup(nil, "Err") != nil
+}
+
+// waitGroupCall returns the receiver of a call to (*sync.WaitGroup).name.
+func waitGroupCall(common *ssa.CallCommon, name string) (ssa.Value, bool) {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Name() != name || len(common.Args) == 0 {
+		return nil, false
+	}
+	recv := callee.Signature.Recv()
+	if recv == nil {
+		return nil, false
+	}
+	n, ok := deref(recv.Type()).(*types.Named)
+	if !ok || n.Obj().Pkg() == nil || n.Obj().Pkg().Path() != "sync" || n.Obj().Name() != "WaitGroup" {
+		return nil, false
+	}
+	return common.Args[0], true
+}
+
+// waitGroupIndex resolves sync.WaitGroup pointers to the variable or struct
+// field that holds the WaitGroup, and records which of those see a Done.
+type waitGroupIndex struct {
+	freeVars map[*ssa.FreeVar]ssa.Value
+	args     map[*ssa.Parameter][]ssa.Value
+	done     map[any]bool
+	// unresolvedDone is set when some Done call cannot be tied to a
+	// WaitGroup; Waits are then assumed to be released.
+	unresolvedDone bool
+}
+
+func newWaitGroupIndex(ssaResult *SSAResult) *waitGroupIndex {
+	wgs := &waitGroupIndex{
+		freeVars: make(map[*ssa.FreeVar]ssa.Value),
+		args:     make(map[*ssa.Parameter][]ssa.Value),
+		done:     make(map[any]bool),
+	}
+	var doneRecvs []ssa.Value
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				switch inst := instr.(type) {
+				case *ssa.MakeClosure:
+					if cf, ok := inst.Fn.(*ssa.Function); ok {
+						for i, b := range inst.Bindings {
+							if i < len(cf.FreeVars) {
+								wgs.freeVars[cf.FreeVars[i]] = b
+							}
+						}
+					}
+				case ssa.CallInstruction:
+					common := inst.Common()
+					if callee := common.StaticCallee(); callee != nil && !common.IsInvoke() {
+						for i, a := range common.Args {
+							if i < len(callee.Params) {
+								p := callee.Params[i]
+								wgs.args[p] = append(wgs.args[p], a)
+							}
+						}
+					}
+					// WaitGroup.Go (Go 1.25) calls Done itself.
+					for _, m := range []string{"Done", "Go"} {
+						if recv, ok := waitGroupCall(common, m); ok {
+							doneRecvs = append(doneRecvs, recv)
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, recv := range doneRecvs {
+		keys := wgs.resolve(recv)
+		if len(keys) == 0 {
+			wgs.unresolvedDone = true
+		}
+		for _, k := range keys {
+			wgs.done[k] = true
+		}
+	}
+	return wgs
+}
+
+// resolve returns the identities a *sync.WaitGroup value may refer to, or
+// nil when any path leads somewhere untraceable.
+func (wgs *waitGroupIndex) resolve(v ssa.Value) []any {
+	var keys []any
+	ok := wgs.resolveInto(v, map[ssa.Value]bool{}, &keys)
+	if !ok {
+		return nil
+	}
+	return keys
+}
+
+func (wgs *waitGroupIndex) resolveInto(v ssa.Value, seen map[ssa.Value]bool, keys *[]any) bool {
+	if seen[v] {
+		return true
+	}
+	seen[v] = true
+	switch x := v.(type) {
+	case *ssa.Alloc, *ssa.Global:
+		*keys = append(*keys, x)
+		return true
+	case *ssa.FieldAddr:
+		*keys = append(*keys, fieldKey{deref(x.X.Type()).String(), x.Field})
+		return true
+	case *ssa.FreeVar:
+		b, ok := wgs.freeVars[x]
+		return ok && wgs.resolveInto(b, seen, keys)
+	case *ssa.Parameter:
+		args := wgs.args[x]
+		if len(args) == 0 {
+			return false
+		}
+		for _, a := range args {
+			if !wgs.resolveInto(a, seen, keys) {
+				return false
+			}
+		}
+		return true
+	case *ssa.Phi:
+		for _, e := range x.Edges {
+			if !wgs.resolveInto(e, seen, keys) {
+				return false
+			}
+		}
+		return true
+	case *ssa.ChangeType:
+		return wgs.resolveInto(x.X, seen, keys)
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+func TestGoroutineLeaks(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/lib", Dir: "/src/example.com/lib"}, nil)
+
+	prog, _ := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+import (
+	"context"
+	"sync"
+)
+
+func Start(ctx context.Context) {
+	go SendNoReceiver()
+	go RecvNoSender()
+	go RangeNoClose()
+	go SelectBlocked()
+	go SelectCtx(ctx)
+	go WaitNoDone()
+	go Helper()
+	go Buffered()
+}
+
+func SendNoReceiver() {
+	ch := make(chan int)
+	ch <- 1
+}
+
+func RecvNoSender() {
+	ch := make(chan int)
+	<-ch
+}
+
+var work = make(chan int)
+
+func Produce() { work <- 1 }
+
+func RangeNoClose() {
+	for range work {
+	}
+}
+
+func SelectBlocked() {
+	a, b := make(chan int), make(chan int)
+	select {
+	case <-a:
+	case <-b:
+	}
+}
+
+func SelectCtx(ctx context.Context) {
+	a := make(chan int)
+	select {
+	case <-a:
+	case <-ctx.Done():
+	}
+}
+
+func WaitNoDone() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	wg.Wait()
+}
+
+func Helper() { block() }
+
+func block() {
+	ch := make(chan int)
+	<-ch
+}
+
+func Buffered() {
+	ch := make(chan int, 1)
+	ch <- 1
+}
+`})
+
+	ssaResult := &SSAResult{Prog: prog, AllFuncs: ssautil.AllFunctions(prog)}
+	_, chanIndex := traceChannels(ssaResult)
+	wgs := newWaitGroupIndex(ssaResult)
+	pkg := prog.ImportedPackage("example.com/lib")
+
+	tests := []struct {
+		fn   string
+		want []string // op in function
+	}{
+		{"SendNoReceiver", []string{"send in SendNoReceiver"}},
+		{"RecvNoSender", []string{"recv in RecvNoSender"}},
+		{"RangeNoClose", []string{"range_recv in RangeNoClose"}},
+		{"SelectBlocked", []string{"select in SelectBlocked"}},
+		{"SelectCtx", nil},
+		{"WaitNoDone", []string{"wg_wait in WaitNoDone"}},
+		{"Helper", []string{"recv in block"}},
+		{"Buffered", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			fn, ok := pkg.Members[tt.fn].(*ssa.Function)
+			if !ok {
+				t.Fatalf("no function %s", tt.fn)
+			}
+			var got []string
+			for _, l := range goroutineLeaks(fn, chanIndex, wgs) {
+				got = append(got, l.op+" in "+l.fn.Name())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("leaks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
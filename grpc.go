@@ -0,0 +1,875 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// flagGRPC enables gRPC/protobuf modeling: .proto parsing, generated nodes
+// for skipped .pb.go declarations and service linking. Set by main before
+// any pipeline phase runs.
+var flagGRPC bool
+
+// protoFile is the parsed content of one .proto source.
+type protoFile struct {
+	relFile   string
+	pkg       string // proto package
+	goPackage string // import path from option go_package, "" if absent
+	messages  []protoMessage
+	services  []protoService
+}
+
+// protoMessage is a message definition; nested messages are flattened with
+// their dotted name ("Outer.Inner").
+type protoMessage struct {
+	name   string
+	line   int
+	fields []string
+}
+
+// protoService is a service definition and its RPCs.
+type protoService struct {
+	name    string
+	line    int
+	methods []protoRPC
+}
+
+// protoRPC is one rpc of a service.
+type protoRPC struct {
+	name            string
+	line            int
+	request         string
+	response        string
+	clientStreaming bool
+	serverStreaming bool
+}
+
+// ModelProtobuf parses the .proto files of the analyzed modules into
+// rpc_service, rpc_method and message nodes, and emits a lightweight
+// generated node for every top-level type and function of the .pb.go
+// files the AST walk skipped. Generated message structs, server and
+// client interfaces, RegisterXServer and NewXClient get generated_from
+// edges to their proto definitions. Generated types are registered in
+// posLookup so type relationship extraction links implementations to the
+// XServer interfaces.
+func ModelProtobuf(
+	pkgs []*packages.Package,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Modeling protobuf definitions...")
+
+	var files []*protoFile
+	for _, m := range modSet.Dirs() {
+		filepath.WalkDir(m.Dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				switch d.Name() {
+				case "vendor", "node_modules", "testdata", ".git":
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".proto") {
+				return nil
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			relFile := modSet.RelFile(path)
+			if relFile == "" {
+				return nil
+			}
+			pf := parseProto(string(src))
+			pf.relFile = relFile
+			files = append(files, pf)
+			return nil
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].relFile < files[j].relFile })
+
+	// Proto definitions, and the Go names their generated code uses,
+	// keyed by the directory holding the .proto file and its go_package.
+	type goDef struct {
+		id    string
+		kind  string // message or service
+		name  string
+		props map[string]any
+	}
+	byGoName := make(map[string]goDef) // dir or import path + "." + Go name → definition
+	var messages, services, rpcs int
+	for _, pf := range files {
+		qual := func(name string) string {
+			if pf.pkg == "" {
+				return name
+			}
+			return pf.pkg + "." + name
+		}
+		dir := filepath.Dir(pf.relFile)
+		keys := []string{dir}
+		if pf.goPackage != "" {
+			keys = append(keys, pf.goPackage)
+		}
+		known := make(map[string]bool, len(pf.messages))
+		for _, msg := range pf.messages {
+			known[msg.name] = true
+		}
+		for _, msg := range pf.messages {
+			id := ProtoID(qual(msg.name))
+			cpg.AddNode(Node{
+				ID:   id,
+				Kind: "message",
+				Name: msg.name,
+				File: pf.relFile,
+				Line: msg.line,
+				Properties: map[string]any{
+					"proto_package": pf.pkg,
+					"full_name":     qual(msg.name),
+					"fields":        append([]string{}, msg.fields...),
+				},
+			})
+			for _, k := range keys {
+				byGoName[k+"."+strings.ReplaceAll(msg.name, ".", "_")] = goDef{id: id, kind: "message", name: msg.name}
+			}
+			messages++
+		}
+		for _, svc := range pf.services {
+			svcID := ProtoID(qual(svc.name))
+			methods := make([]string, 0, len(svc.methods))
+			for _, rpc := range svc.methods {
+				methods = append(methods, rpc.name)
+			}
+			props := map[string]any{
+				"proto_package": pf.pkg,
+				"full_name":     qual(svc.name),
+				"go_package":    pf.goPackage,
+				"methods":       methods,
+			}
+			cpg.AddNode(Node{
+				ID:         svcID,
+				Kind:       "rpc_service",
+				Name:       svc.name,
+				File:       pf.relFile,
+				Line:       svc.line,
+				Properties: props,
+			})
+			for _, k := range keys {
+				for _, goName := range []string{svc.name + "Server", svc.name + "Client", "Register" + svc.name + "Server", "New" + svc.name + "Client"} {
+					byGoName[k+"."+goName] = goDef{id: svcID, kind: "service", name: svc.name, props: props}
+				}
+			}
+			services++
+			for _, rpc := range svc.methods {
+				methodID := ProtoID(qual(svc.name) + "/" + rpc.name)
+				cpg.AddNode(Node{
+					ID:   methodID,
+					Kind: "rpc_method",
+					Name: rpc.name,
+					File: pf.relFile,
+					Line: rpc.line,
+					Properties: map[string]any{
+						"service":          qual(svc.name),
+						"full_method":      "/" + qual(svc.name) + "/" + rpc.name,
+						"request":          rpc.request,
+						"response":         rpc.response,
+						"client_streaming": rpc.clientStreaming,
+						"server_streaming": rpc.serverStreaming,
+					},
+				})
+				cpg.AddEdge(Edge{Source: svcID, Target: methodID, Kind: "has_rpc"})
+				for _, ref := range [...]struct{ kind, typ string }{
+					{"rpc_request", rpc.request},
+					{"rpc_response", rpc.response},
+				} {
+					if msg := resolveProtoType(ref.typ, pf.pkg, known); msg != "" {
+						cpg.AddEdge(Edge{Source: methodID, Target: ProtoID(msg), Kind: ref.kind})
+					}
+				}
+				rpcs++
+			}
+		}
+	}
+
+	// Generated Go declarations. Files the AST walk skipped get
+	// generated nodes; walked ones already have their declaration nodes.
+	var generated, linked int
+	for _, pkg := range pkgs {
+		relPkg := modSet.RelPkg(pkg.PkgPath)
+		for i, file := range pkg.Syntax {
+			if i >= len(pkg.CompiledGoFiles) {
+				continue
+			}
+			relFile := modSet.RelFile(pkg.CompiledGoFiles[i])
+			if relFile == "" || !strings.HasSuffix(relFile, ".pb.go") {
+				continue
+			}
+			skipped := shouldSkipFile(relFile)
+			dir := filepath.Dir(relFile)
+			link := func(name, declKind, typeKind string, pos token.Pos) {
+				var id string
+				if skipped {
+					id = emitGenerated(cpg, fset, posLookup, relPkg, relFile, name, declKind, typeKind, pos)
+					generated++
+				} else {
+					p := fset.Position(pos)
+					id = posLookup.Get(relFile, p.Line, p.Column)
+				}
+				def, ok := lookupGoDef(byGoName, dir, pkg.PkgPath, name)
+				if !ok || id == "" || declKind == "func" && def.kind != "service" {
+					return
+				}
+				cpg.AddEdge(Edge{Source: id, Target: def.id, Kind: "generated_from"})
+				if def.kind == "service" && name == def.name+"Server" {
+					def.props["go_import_path"] = pkg.PkgPath
+				}
+				linked++
+			}
+			for _, decl := range file.Decls {
+				switch d := decl.(type) {
+				case *ast.FuncDecl:
+					if d.Recv == nil {
+						link(d.Name.Name, "func", "", d.Name.Pos())
+					}
+				case *ast.GenDecl:
+					if d.Tok != token.TYPE {
+						continue
+					}
+					for _, spec := range d.Specs {
+						ts := spec.(*ast.TypeSpec)
+						typeKind := "alias"
+						switch ts.Type.(type) {
+						case *ast.StructType:
+							typeKind = "struct"
+						case *ast.InterfaceType:
+							typeKind = "interface"
+						}
+						link(ts.Name.Name, "type", typeKind, ts.Name.Pos())
+					}
+				}
+			}
+		}
+	}
+
+	prog.Log("Protobuf: %d files, %d services, %d rpcs, %d messages; %d generated declarations (%d linked to proto)",
+		len(files), services, rpcs, messages, generated, linked)
+}
+
+// lookupGoDef finds the proto definition a generated Go name comes from,
+// by go_package import path or by the .proto file sitting in the same
+// directory.
+func lookupGoDef[T any](byGoName map[string]T, dir, pkgPath, name string) (T, bool) {
+	if def, ok := byGoName[pkgPath+"."+name]; ok {
+		return def, true
+	}
+	def, ok := byGoName[dir+"."+name]
+	return def, ok
+}
+
+// emitGenerated adds a generated node for a top-level .pb.go declaration
+// and returns its ID.
+func emitGenerated(cpg *CPG, fset *token.FileSet, posLookup *PosLookup, relPkg, relFile, name, declKind, typeKind string, pos token.Pos) string {
+	p := fset.Position(pos)
+	id := StmtID(relPkg, BaseName(relFile), p.Line, p.Column, "generated")
+	props := map[string]any{
+		"decl":     declKind,
+		"exported": token.IsExported(name),
+	}
+	if typeKind != "" {
+		props["type_kind"] = typeKind
+	}
+	cpg.AddNode(Node{
+		ID:         id,
+		Kind:       "generated",
+		Name:       name,
+		Package:    relPkg,
+		File:       relFile,
+		Line:       p.Line,
+		Col:        p.Column,
+		Properties: props,
+	})
+	if declKind == "type" {
+		posLookup.Set(relFile, p.Line, p.Column, id)
+	}
+	return id
+}
+
+// resolveProtoType resolves a message type reference from package pkg to
+// the fully qualified name of a message defined in the same file, or "".
+func resolveProtoType(typ, pkg string, known map[string]bool) string {
+	typ = strings.TrimPrefix(typ, ".")
+	if pkg != "" {
+		if rel, ok := strings.CutPrefix(typ, pkg+"."); ok && known[rel] {
+			return typ
+		}
+		if known[typ] {
+			return pkg + "." + typ
+		}
+		return ""
+	}
+	if known[typ] {
+		return typ
+	}
+	return ""
+}
+
+// protoToken is a lexical token of a .proto file.
+type protoToken struct {
+	text string
+	line int
+}
+
+// parseProto extracts the package, go_package option, messages (with
+// nested ones) and services of a .proto source. It is a tolerant
+// scanner, not a validating parser: unknown constructs are skipped.
+func parseProto(src string) *protoFile {
+	p := &protoParser{toks: tokenizeProto(src)}
+	pf := &protoFile{}
+	for !p.done() {
+		switch t := p.next(); t.text {
+		case "package":
+			pf.pkg = p.next().text
+			p.skipStatement()
+		case "option":
+			name := p.next().text
+			if p.peek().text == "=" {
+				p.next()
+			}
+			val := p.next().text
+			if name == "go_package" {
+				path := strings.Trim(val, `"`)
+				if i := strings.IndexByte(path, ';'); i >= 0 {
+					path = path[:i]
+				}
+				pf.goPackage = path
+			}
+			p.skipStatement()
+		case "message":
+			p.parseMessage("", pf)
+		case "service":
+			pf.services = append(pf.services, p.parseService())
+		case "{":
+			p.skipBlock()
+		case ";":
+		default:
+			p.skipStatement()
+		}
+	}
+	return pf
+}
+
+// protoParser walks a token stream.
+type protoParser struct {
+	toks []protoToken
+	i    int
+}
+
+func (p *protoParser) done() bool { return p.i >= len(p.toks) }
+
+func (p *protoParser) peek() protoToken {
+	if p.done() {
+		return protoToken{}
+	}
+	return p.toks[p.i]
+}
+
+func (p *protoParser) next() protoToken {
+	t := p.peek()
+	p.i++
+	return t
+}
+
+// skipStatement skips to the end of the current statement: past the next
+// ";" or over the next block.
+func (p *protoParser) skipStatement() {
+	for !p.done() {
+		switch p.next().text {
+		case ";":
+			return
+		case "{":
+			p.skipBlock()
+			return
+		}
+	}
+}
+
+// skipBlock skips to the "}" closing a block whose "{" was consumed.
+func (p *protoParser) skipBlock() {
+	for depth := 1; !p.done() && depth > 0; {
+		switch p.next().text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+}
+
+// parseMessage parses a message after its keyword, appending it and its
+// nested messages to pf.
+func (p *protoParser) parseMessage(outer string, pf *protoFile) {
+	t := p.next()
+	name := t.text
+	if outer != "" {
+		name = outer + "." + name
+	}
+	idx := len(pf.messages)
+	pf.messages = append(pf.messages, protoMessage{name: name, line: t.line})
+	if p.next().text != "{" {
+		return
+	}
+	var fields []string
+	for !p.done() {
+		switch t := p.next(); t.text {
+		case "}":
+			pf.messages[idx].fields = fields
+			return
+		case "message":
+			p.parseMessage(name, pf)
+		case "enum", "extend", "extensions", "reserved", "option":
+			p.skipStatement()
+		case "oneof":
+			p.next()
+			if p.next().text == "{" {
+				fields = append(fields, p.parseFields()...)
+			}
+		case ";":
+		default:
+			// [repeated|optional|required] type name = N [options];
+			// map<K, V> name = N;
+			toks := []string{t.text}
+			for !p.done() && p.peek().text != "=" && p.peek().text != ";" && p.peek().text != "}" {
+				toks = append(toks, p.next().text)
+			}
+			if p.peek().text == "=" {
+				fields = append(fields, toks[len(toks)-1])
+			}
+			if p.peek().text != "}" {
+				p.skipStatement()
+			}
+		}
+	}
+	pf.messages[idx].fields = fields
+}
+
+// parseFields parses the fields of a oneof block up to its "}".
+func (p *protoParser) parseFields() []string {
+	var fields []string
+	for !p.done() {
+		t := p.next()
+		if t.text == "}" {
+			return fields
+		}
+		if t.text == "option" {
+			p.skipStatement()
+			continue
+		}
+		name := p.next().text
+		if p.peek().text == "=" {
+			fields = append(fields, name)
+		}
+		p.skipStatement()
+	}
+	return fields
+}
+
+// parseService parses a service after its keyword.
+func (p *protoParser) parseService() protoService {
+	t := p.next()
+	svc := protoService{name: t.text, line: t.line}
+	if p.next().text != "{" {
+		return svc
+	}
+	for !p.done() {
+		switch t := p.next(); t.text {
+		case "}":
+			return svc
+		case "rpc":
+			name := p.next()
+			rpc := protoRPC{name: name.text, line: name.line}
+			rpc.request, rpc.clientStreaming = p.parseRPCType()
+			if p.peek().text == "returns" {
+				p.next()
+			}
+			rpc.response, rpc.serverStreaming = p.parseRPCType()
+			svc.methods = append(svc.methods, rpc)
+			p.skipStatement()
+		case ";":
+		default:
+			p.skipStatement()
+		}
+	}
+	return svc
+}
+
+// parseRPCType parses "( [stream] Type )".
+func (p *protoParser) parseRPCType() (string, bool) {
+	if p.peek().text != "(" {
+		return "", false
+	}
+	p.next()
+	stream := false
+	typ := p.next().text
+	if typ == "stream" && p.peek().text != ")" {
+		stream = true
+		typ = p.next().text
+	}
+	for !p.done() && p.next().text != ")" {
+	}
+	return typ, stream
+}
+
+// tokenizeProto splits a .proto source into identifiers (dotted names
+// included), string literals, numbers and punctuation, dropping comments.
+func tokenizeProto(src string) []protoToken {
+	var toks []protoToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 4
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(src) {
+				j++
+			}
+			toks = append(toks, protoToken{src[i:j], line})
+			i = j
+		case c == '_' || c == '.' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || src[j] == '-' && j > i && unicode.IsDigit(rune(src[j-1])) || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			toks = append(toks, protoToken{src[i:j], line})
+			i = j
+		default:
+			toks = append(toks, protoToken{string(c), line})
+			i++
+		}
+	}
+	return toks
+}
+
+// grpcService is a proto service as seen from its generated Go package.
+type grpcService struct {
+	id      string
+	name    string
+	pkgPath string              // import path of the generated package
+	iface   *types.Interface    // generated XServer interface
+	rpcs    []string            // rpc names in .proto order
+	methods map[string]string   // rpc name → rpc_method ID
+	impls   map[string][]string // rpc name → implementing function IDs
+}
+
+// LinkGRPCServices links gRPC services to the code serving and calling
+// them. Module types implementing a generated XServer interface get
+// implements_service edges, and their methods implements_rpc edges to the
+// rpc methods; RegisterXServer calls get registers_service edges, and
+// registers_impl edges to the concrete type they are given. Calls of
+// XClient stub methods get rpc_call edges from the call site to the rpc
+// method, plus remote_call edges from the calling function to the
+// implementing methods, like HTTP clients reaching their handlers.
+func LinkGRPCServices(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Linking gRPC services...")
+
+	// Services whose generated XServer interface was found.
+	services := make(map[string]*grpcService) // import path + "." + service name
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if n.Kind != "rpc_service" {
+			continue
+		}
+		pkgPath, _ := n.Properties["go_import_path"].(string)
+		if pkgPath == "" {
+			continue
+		}
+		full, _ := n.Properties["full_name"].(string)
+		names, _ := n.Properties["methods"].([]string)
+		methods := make(map[string]string, len(names))
+		for _, m := range names {
+			methods[m] = ProtoID(full + "/" + m)
+		}
+		svc := &grpcService{
+			id: n.ID, name: n.Name, pkgPath: pkgPath,
+			rpcs: names, methods: methods, impls: make(map[string][]string),
+		}
+		if svc.iface = grpcServerIface(ssaResult.Prog, svc); svc.iface != nil {
+			services[pkgPath+"."+n.Name] = svc
+		}
+	}
+	if len(services) == 0 {
+		prog.Log("gRPC: no generated services")
+		return
+	}
+	svcKeys := make([]string, 0, len(services))
+	for k := range services {
+		svcKeys = append(svcKeys, k)
+	}
+	sort.Strings(svcKeys)
+
+	// Implementations: named types of the analyzed modules (outside
+	// generated files) implementing XServer.
+	var implTypes int
+	typeIDs := make(map[*types.TypeName]string)
+	pkgs := ssaResult.Prog.AllPackages()
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Pkg.Path() < pkgs[j].Pkg.Path() })
+	for _, p := range pkgs {
+		if !modSet.IsKnownPkg(p.Pkg.Path()) {
+			continue
+		}
+		scope := p.Pkg.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || types.IsInterface(obj.Type()) {
+				continue
+			}
+			pos := fset.Position(obj.Pos())
+			relFile := modSet.RelFile(pos.Filename)
+			if relFile == "" || strings.HasSuffix(relFile, ".pb.go") {
+				continue
+			}
+			typeID := posLookup.Get(relFile, pos.Line, pos.Column)
+			for _, k := range svcKeys {
+				svc := services[k]
+				ptr := types.NewPointer(obj.Type())
+				if !types.Implements(obj.Type(), svc.iface) && !types.Implements(ptr, svc.iface) {
+					continue
+				}
+				if typeID != "" {
+					cpg.AddEdge(Edge{Source: typeID, Target: svc.id, Kind: "implements_service"})
+					typeIDs[obj] = typeID
+				}
+				implTypes++
+				mset := ssaResult.Prog.MethodSets.MethodSet(ptr)
+				for _, rpc := range svc.rpcs {
+					methodID := svc.methods[rpc]
+					sel := mset.Lookup(obj.Pkg(), rpc)
+					if sel == nil {
+						continue
+					}
+					fn := ssaResult.Prog.MethodValue(sel)
+					if fn = sourceFunc(ssaResult.Prog, fn); fn == nil {
+						continue
+					}
+					// Promoted methods of an embedded Unimplemented stub
+					// do not serve the rpc.
+					fnPos := fset.Position(fn.Pos())
+					if strings.HasSuffix(fnPos.Filename, ".pb.go") {
+						continue
+					}
+					if fnID := ssaFuncNodeID(fn, fset, funcLookup); fnID != "" {
+						cpg.AddEdge(Edge{Source: fnID, Target: methodID, Kind: "implements_rpc"})
+						svc.impls[rpc] = append(svc.impls[rpc], fnID)
+					}
+				}
+			}
+		}
+	}
+
+	// Registrations and client stub calls.
+	var registrations, registeredImpls, stubCalls int
+	for _, fn := range knownFuncs(ssaResult) {
+		fnID := ""
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				common := call.Common()
+				file, line, col := instrPos(call, fset)
+				if file == "" {
+					continue
+				}
+				if svc, ok := grpcRegistration(common, services); ok {
+					if site := posLookup.Get(file, line, col); site != "" {
+						cpg.AddEdge(Edge{Source: site, Target: svc.id, Kind: "registers_service"})
+						registrations++
+						if len(common.Args) == 2 {
+							for _, obj := range grpcRegisteredTypes(common.Args[1], 0) {
+								if typeID := typeIDs[obj]; typeID != "" {
+									cpg.AddEdge(Edge{Source: site, Target: typeID, Kind: "registers_impl"})
+									registeredImpls++
+								}
+							}
+						}
+					}
+					continue
+				}
+				svc, rpc := grpcStubCall(common, services)
+				if svc == nil {
+					continue
+				}
+				site := posLookup.Get(file, line, col)
+				if site != "" {
+					cpg.AddEdge(Edge{Source: site, Target: svc.methods[rpc], Kind: "rpc_call"})
+				}
+				stubCalls++
+				if fn.Synthetic != "" {
+					continue
+				}
+				if fnID == "" {
+					fnID = ssaFuncNodeID(fn, fset, funcLookup)
+				}
+				impls := svc.impls[rpc]
+				conf := 1.0
+				if len(impls) > 1 {
+					conf = 0.5
+				}
+				for _, impl := range impls {
+					if fnID == "" || impl == fnID {
+						continue
+					}
+					cpg.AddEdge(Edge{
+						Source: fnID, Target: impl, Kind: "remote_call",
+						Properties: map[string]any{
+							"site":       site,
+							"protocol":   "grpc",
+							"rpc":        svc.methods[rpc],
+							"component":  modSet.Component(fn.Pkg.Pkg.Path()),
+							"confidence": conf,
+						},
+					})
+				}
+			}
+		}
+	}
+
+	prog.Log("gRPC: %d services, %d implementing types, %d registrations (%d with a resolved implementation), %d client stub calls",
+		len(services), implTypes, registrations, registeredImpls, stubCalls)
+}
+
+// grpcRegisteredTypes resolves the implementation argument of a
+// RegisterXServer call to the named types converted to the XServer
+// interface, following interface conversions and phis.
+func grpcRegisteredTypes(v ssa.Value, depth int) []*types.TypeName {
+	if depth > constMaxDepth {
+		return nil
+	}
+	switch x := v.(type) {
+	case *ssa.MakeInterface:
+		if named, ok := deref(x.X.Type()).(*types.Named); ok {
+			return []*types.TypeName{named.Origin().Obj()}
+		}
+	case *ssa.ChangeInterface:
+		return grpcRegisteredTypes(x.X, depth+1)
+	case *ssa.Phi:
+		var out []*types.TypeName
+		for _, e := range x.Edges {
+			for _, obj := range grpcRegisteredTypes(e, depth+1) {
+				if !slices.Contains(out, obj) {
+					out = append(out, obj)
+				}
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// grpcServerIface returns the generated XServer interface of svc.
+func grpcServerIface(prog *ssa.Program, svc *grpcService) *types.Interface {
+	p := prog.ImportedPackage(svc.pkgPath)
+	if p == nil {
+		return nil
+	}
+	obj, ok := p.Pkg.Scope().Lookup(svc.name + "Server").(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	iface, _ := obj.Type().Underlying().(*types.Interface)
+	return iface
+}
+
+// grpcRegistration recognizes a generated RegisterXServer call.
+func grpcRegistration(common *ssa.CallCommon, services map[string]*grpcService) (*grpcService, bool) {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Pkg == nil || callee.Signature.Recv() != nil {
+		return nil, false
+	}
+	name, ok := strings.CutPrefix(callee.Name(), "Register")
+	if !ok {
+		return nil, false
+	}
+	name, ok = strings.CutSuffix(name, "Server")
+	if !ok {
+		return nil, false
+	}
+	svc, ok := services[callee.Pkg.Pkg.Path()+"."+name]
+	return svc, ok
+}
+
+// grpcStubCall recognizes a call of an rpc through a generated XClient:
+// the interface method, or the method of the generated client struct.
+func grpcStubCall(common *ssa.CallCommon, services map[string]*grpcService) (*grpcService, string) {
+	var named *types.Named
+	var method string
+	if common.IsInvoke() {
+		named, _ = common.Value.Type().(*types.Named)
+		method = common.Method.Name()
+	} else if callee := common.StaticCallee(); callee != nil && callee.Signature.Recv() != nil {
+		named, _ = deref(callee.Signature.Recv().Type()).(*types.Named)
+		method = callee.Name()
+	}
+	if named == nil || named.Obj().Pkg() == nil {
+		return nil, ""
+	}
+	name, ok := strings.CutSuffix(named.Obj().Name(), "Client")
+	if !ok || name == "" {
+		return nil, ""
+	}
+	// The generated struct is the unexported xClient.
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	svc, ok := services[named.Obj().Pkg().Path()+"."+string(r)]
+	if !ok {
+		return nil, ""
+	}
+	if _, ok := svc.methods[method]; !ok {
+		return nil, ""
+	}
+	return svc, method
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestParseProto(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want *protoFile
+	}{
+		{
+			name: "package and go_package",
+			src: `syntax = "proto3";
+package prometheus.rules;
+option go_package = "github.com/prometheus/prometheus/prompb;prompb";
+option java_package = "io.prometheus";
+import "google/protobuf/empty.proto";
+`,
+			want: &protoFile{pkg: "prometheus.rules", goPackage: "github.com/prometheus/prometheus/prompb"},
+		},
+		{
+			name: "messages, nested messages, oneof and comments",
+			src: `package p;
+// A sample.
+message Sample {
+  double value = 1; // trailing comment
+  int64 timestamp = 2 [deprecated = true];
+  repeated Label labels = 3;
+  map<string, string> annotations = 4;
+  /* block
+     comment */
+  enum Kind { GAUGE = 0; COUNTER = 1; }
+  reserved 5, 6;
+  oneof payload {
+    string text = 7;
+    bytes raw = 8;
+  }
+  message Label {
+    string name = 1;
+    string value = 2;
+  }
+}
+`,
+			want: &protoFile{
+				pkg: "p",
+				messages: []protoMessage{
+					{name: "Sample", line: 3, fields: []string{"value", "timestamp", "labels", "annotations", "text", "raw"}},
+					{name: "Sample.Label", line: 16, fields: []string{"name", "value"}},
+				},
+			},
+		},
+		{
+			name: "service with streaming rpcs and options",
+			src: `package p;
+service RuleEvaluator {
+  option deprecated = false;
+  rpc Evaluate (EvalRequest) returns (EvalResponse);
+  rpc Watch(stream WatchRequest) returns (stream google.protobuf.Empty) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+  rpc Push (stream Sample) returns (Ack) {}
+}
+message Ack {}
+`,
+			want: &protoFile{
+				pkg: "p",
+				messages: []protoMessage{
+					{name: "Ack", line: 10},
+				},
+				services: []protoService{{
+					name: "RuleEvaluator", line: 2,
+					methods: []protoRPC{
+						{name: "Evaluate", line: 4, request: "EvalRequest", response: "EvalResponse"},
+						{name: "Watch", line: 5, request: "WatchRequest", response: "google.protobuf.Empty", clientStreaming: true, serverStreaming: true},
+						{name: "Push", line: 8, request: "Sample", response: "Ack", clientStreaming: true},
+					},
+				}},
+			},
+		},
+		{
+			name: "unknown top-level blocks are skipped",
+			src: `package p;
+extend google.protobuf.FieldOptions { string unit = 50000; }
+enum Top { A = 0; }
+message M { string s = 1; }
+`,
+			want: &protoFile{
+				pkg:      "p",
+				messages: []protoMessage{{name: "M", line: 4, fields: []string{"s"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseProto(tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProto:\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCRegisteredTypes(t *testing.T) {
+	prog, _ := buildTestProgram(t, testPkg{"example.com/svc", `package svc
+
+type FooServer interface{ Ping() }
+
+type a struct{}
+
+func (*a) Ping() {}
+
+type b struct{}
+
+func (b) Ping() {}
+
+func RegisterFooServer(s any, srv FooServer) {}
+
+func one()         { RegisterFooServer(nil, &a{}) }
+func two(c bool) {
+	var srv FooServer = &a{}
+	if c {
+		srv = b{}
+	}
+	RegisterFooServer(nil, srv)
+}
+func param(srv FooServer) { RegisterFooServer(nil, srv) }
+`})
+
+	pkg := prog.ImportedPackage("example.com/svc")
+	tests := []struct {
+		fn   string
+		want []string
+	}{
+		{"one", []string{"a"}},
+		{"two", []string{"a", "b"}},
+		{"param", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			var got []string
+			for _, b := range pkg.Func(tt.fn).Blocks {
+				for _, instr := range b.Instrs {
+					call, ok := instr.(*ssa.Call)
+					if !ok || call.Call.StaticCallee() != pkg.Func("RegisterFooServer") {
+						continue
+					}
+					for _, obj := range grpcRegisteredTypes(call.Call.Args[1], 0) {
+						got = append(got, obj.Name())
+					}
+				}
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("grpcRegisteredTypes in %s = %v, want %v", tt.fn, got, tt.want)
+			}
+		})
+	}
+}
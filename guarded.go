@@ -0,0 +1,373 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Guarded-by inference thresholds: a field is guarded by a mutex when at
+// least guardMinLocked of its accesses hold it and they make up at least
+// guardMinRatio of all its accesses.
+const (
+	guardMinLocked = 2
+	guardMinRatio  = 0.75
+)
+
+// fieldAccess is one access of a struct field through a FieldAddr.
+type fieldAccess struct {
+	instr     *ssa.FieldAddr
+	fn        *ssa.Function
+	write     bool
+	locked    map[int]bool // mutex field indices held
+	readLocks map[int]bool // held only for reading although the access writes
+}
+
+// guardStats collects the accesses of one field.
+type guardStats struct {
+	accesses []fieldAccess
+}
+
+// InferGuardedBy infers, for each struct with a sync.Mutex / sync.RWMutex
+// field (named, embedded or a pointer), which of its other fields are
+// consistently accessed while that mutex is held on the same value, like
+// Java's @GuardedBy without annotations. Held locks come from the lock
+// dataflow of AnalyzeLockPairing (keyed by access path, so s.mu guards s.f
+// but not t.f). Accesses in a method whose every call site holds the mutex
+// (see lockedOnEntry), or whose name ends in "Locked" / "locked", count as
+// locked; accesses to a freshly allocated value (constructors) are ignored.
+//
+// A field is guarded when at least guardMinLocked accesses and guardMinRatio
+// of all accesses hold the mutex. It gets a guarded_by edge to the mutex
+// field and guarded_by / guard_ratio properties; its unlocked accesses, and
+// writes made under the read lock only, are recorded in unguarded_accesses
+// on the accessing function.
+func InferGuardedBy(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Inferring guarded-by relations...")
+
+	var funcs []*ssa.Function
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) > 0 {
+			funcs = append(funcs, fn)
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Pos() < funcs[j].Pos() })
+
+	mutexCache := make(map[*types.Named][]int)
+	mutexFields := func(t types.Type) (*types.Named, []int) {
+		n, ok := t.(*types.Named)
+		if !ok || n.Obj().Pkg() == nil || !modSet.IsKnownPkg(n.Obj().Pkg().Path()) {
+			return nil, nil
+		}
+		if mus, ok := mutexCache[n]; ok {
+			return n, mus
+		}
+		var mus []int
+		if st, ok := n.Underlying().(*types.Struct); ok {
+			for i := 0; i < st.NumFields(); i++ {
+				if isMutexType(st.Field(i).Type()) {
+					mus = append(mus, i)
+				}
+			}
+		}
+		mutexCache[n] = mus
+		return n, mus
+	}
+
+	flows := make(map[*ssa.Function][]*lockState, len(funcs))
+	for _, fn := range funcs {
+		flows[fn] = lockFlow(fn)
+	}
+
+	heldOnEntry := lockedOnEntry(ssaResult.Prog, funcs, flows, mutexFields)
+
+	type fieldRef struct {
+		typ   *types.Named
+		field int
+	}
+	stats := make(map[fieldRef]*guardStats)
+	var refs []fieldRef
+	for _, fn := range funcs {
+		assumeLocked := strings.HasSuffix(fn.Name(), "Locked") || strings.HasSuffix(fn.Name(), "locked")
+		forEachLockState(fn, flows[fn], func(instr ssa.Instruction, st *lockState) {
+			fa, ok := instr.(*ssa.FieldAddr)
+			if !ok {
+				return
+			}
+			typ, mus := mutexFields(deref(fa.X.Type()))
+			if len(mus) == 0 || isMutexField(mus, fa.Field) || isSyncType(fa.Type()) {
+				return
+			}
+			if _, fresh := fa.X.(*ssa.Alloc); fresh {
+				return
+			}
+			acc := fieldAccess{
+				instr: fa, fn: fn, write: isFieldWrite(fa),
+				locked: make(map[int]bool), readLocks: make(map[int]bool),
+			}
+			for _, mu := range mus {
+				onEntry := heldOnEntry[fn][mu] && len(fn.Params) > 0 && fa.X == ssa.Value(fn.Params[0])
+				w, r := guardHeld(st, fa.X, mu)
+				if assumeLocked || onEntry || w || r {
+					acc.locked[mu] = true
+				}
+				if acc.write && r && !w && !assumeLocked && !onEntry {
+					acc.readLocks[mu] = true
+				}
+			}
+			ref := fieldRef{typ, fa.Field}
+			if stats[ref] == nil {
+				stats[ref] = &guardStats{}
+				refs = append(refs, ref)
+			}
+			stats[ref].accesses = append(stats[ref].accesses, acc)
+		})
+	}
+
+	// Field nodes are registered at the position of each declared name.
+	fieldNodes := make(map[string]bool)
+	for _, n := range cpg.Nodes {
+		if n.Kind == "field" {
+			fieldNodes[n.ID] = true
+		}
+	}
+	fieldNodeID := func(v *types.Var) string {
+		pos := fset.Position(v.Pos())
+		if id := posLookup.Get(modSet.RelFile(pos.Filename), pos.Line, pos.Column); fieldNodes[id] {
+			return id
+		}
+		return ""
+	}
+
+	type guardInfo struct {
+		mutex string
+		ratio float64
+	}
+	guarded := make(map[string]guardInfo) // field node ID → guard
+	unguarded := make(map[string][]map[string]any)
+	var guardedFields, unguardedCount int
+	for _, ref := range refs {
+		st := ref.typ.Underlying().(*types.Struct)
+		s := stats[ref]
+		best, bestLocked := -1, 0
+		_, mus := mutexFields(ref.typ)
+		for _, mu := range mus {
+			locked := 0
+			for _, a := range s.accesses {
+				if a.locked[mu] {
+					locked++
+				}
+			}
+			if locked > bestLocked {
+				best, bestLocked = mu, locked
+			}
+		}
+		ratio := float64(bestLocked) / float64(len(s.accesses))
+		if best < 0 || bestLocked < guardMinLocked || ratio < guardMinRatio {
+			continue
+		}
+		fieldID := fieldNodeID(st.Field(ref.field))
+		muID := fieldNodeID(st.Field(best))
+		typeName := ref.typ.Obj().Name()
+		fieldName := typeName + "." + st.Field(ref.field).Name()
+		muName := typeName + "." + st.Field(best).Name()
+		if fieldID != "" && muID != "" {
+			cpg.AddEdge(Edge{
+				Source: fieldID, Target: muID, Kind: "guarded_by",
+				Properties: map[string]any{
+					"field":    fieldName,
+					"locked":   bestLocked,
+					"accesses": len(s.accesses),
+				},
+			})
+			guarded[fieldID] = guardInfo{muName, ratio}
+		}
+		guardedFields++
+
+		for _, a := range s.accesses {
+			if a.locked[best] && !a.readLocks[best] {
+				continue
+			}
+			fnID := ssaFuncNodeID(a.fn, fset, funcLookup)
+			file, line, col := instrPos(a.instr, fset)
+			if fnID == "" || file == "" {
+				continue
+			}
+			unguarded[fnID] = append(unguarded[fnID], map[string]any{
+				"field": fieldName,
+				"mutex": muName,
+				"op":    accessOp(a.write),
+				"rlock": a.readLocks[best],
+				"site":  posLookup.Get(file, line, col),
+				"file":  file,
+				"line":  line,
+			})
+			unguardedCount++
+		}
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if g, ok := guarded[n.ID]; ok {
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["guarded_by"] = g.mutex
+			n.Properties["guard_ratio"] = float64(int(g.ratio*100)) / 100
+		}
+		if accs, ok := unguarded[n.ID]; ok && n.Kind == "function" {
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["unguarded_accesses"] = accs
+		}
+	}
+
+	prog.Log("Guarded-by: %d guarded fields, %d unguarded accesses", guardedFields, unguardedCount)
+}
+
+// lockedOnEntry returns, for each method, the mutexes of its receiver held
+// at every call site. Only methods whose callers are all static calls
+// qualify: a method used as a value or callable through an interface (its
+// type converted to one, or its name invoked on one) may be entered
+// without the lock.
+func lockedOnEntry(
+	prog *ssa.Program,
+	funcs []*ssa.Function,
+	flows map[*ssa.Function][]*lockState,
+	mutexFields func(types.Type) (*types.Named, []int),
+) map[*ssa.Function]map[int]bool {
+	heldOnEntry := make(map[*ssa.Function]map[int]bool)
+	callSeen := make(map[*ssa.Function]bool)
+	for _, fn := range funcs {
+		forEachLockState(fn, flows[fn], func(instr ssa.Instruction, st *lockState) {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				return
+			}
+			callee := call.Call.StaticCallee()
+			if callee == nil || callee.Signature.Recv() == nil || len(call.Call.Args) == 0 {
+				return
+			}
+			_, mus := mutexFields(deref(callee.Signature.Recv().Type()))
+			if len(mus) == 0 {
+				return
+			}
+			held := make(map[int]bool)
+			for _, mu := range mus {
+				if w, r := guardHeld(st, call.Call.Args[0], mu); w || r {
+					held[mu] = true
+				}
+			}
+			if !callSeen[callee] {
+				callSeen[callee] = true
+				heldOnEntry[callee] = held
+				return
+			}
+			for mu := range heldOnEntry[callee] {
+				if !held[mu] {
+					delete(heldOnEntry[callee], mu)
+				}
+			}
+		})
+	}
+
+	escaped := escapedFuncs(funcs)
+	converted := make(map[string]bool) // types converted to an interface
+	for _, rt := range prog.RuntimeTypes() {
+		converted[rt.String()] = true
+	}
+	for fn := range heldOnEntry {
+		recv := fn.Signature.Recv().Type()
+		ptr := types.NewPointer(deref(recv)).String()
+		_, ptrRecv := recv.(*types.Pointer)
+		if escaped[fn] || converted[ptr] || (!ptrRecv && converted[recv.String()]) {
+			delete(heldOnEntry, fn)
+		}
+	}
+	return heldOnEntry
+}
+
+// forEachLockState calls visit with the lock state before each instruction
+// of fn's reachable blocks, given the block entry states from lockFlow.
+func forEachLockState(fn *ssa.Function, in []*lockState, visit func(ssa.Instruction, *lockState)) {
+	for _, b := range fn.Blocks {
+		if in[b.Index] == nil {
+			continue
+		}
+		st := in[b.Index].clone()
+		for _, instr := range b.Instrs {
+			visit(instr, st)
+			applyLockInstr(st, instr)
+		}
+	}
+}
+
+// guardHeld reports whether mutex field mu of the struct base points to is
+// write-locked and read-locked in st.
+func guardHeld(st *lockState, base ssa.Value, mu int) (write, read bool) {
+	p := lockPath(base) + "." + structFieldName(deref(base.Type()), mu)
+	return st.must["w:"+p], st.must["r:"+p]
+}
+
+// isMutexType reports whether t is sync.Mutex, sync.RWMutex or a pointer
+// to one.
+func isMutexType(t types.Type) bool {
+	n, ok := deref(t).(*types.Named)
+	if !ok || n.Obj().Pkg() == nil || n.Obj().Pkg().Path() != "sync" {
+		return false
+	}
+	return n.Obj().Name() == "Mutex" || n.Obj().Name() == "RWMutex"
+}
+
+func isMutexField(mus []int, field int) bool {
+	for _, mu := range mus {
+		if mu == field {
+			return true
+		}
+	}
+	return false
+}
+
+// isFieldWrite reports whether the field address is stored to, or a map
+// held in the field is updated.
+func isFieldWrite(fa *ssa.FieldAddr) bool {
+	refs := fa.Referrers()
+	if refs == nil {
+		return false
+	}
+	for _, ref := range *refs {
+		switch r := ref.(type) {
+		case *ssa.Store:
+			if r.Addr == fa {
+				return true
+			}
+		case *ssa.UnOp:
+			if r.Op != token.MUL || r.Referrers() == nil {
+				continue
+			}
+			for _, use := range *r.Referrers() {
+				if mu, ok := use.(*ssa.MapUpdate); ok && mu.Map == r {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+func TestLockedOnEntry(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/lib", Dir: "/src/example.com/lib"}, nil)
+
+	prog, _ := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+import "sync"
+
+type counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *counter) Bump() { c.mu.Lock(); c.inc(); c.mu.Unlock() }
+func (c *counter) inc()  { c.n++ }
+
+type gauge struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (g *gauge) Set() { g.mu.Lock(); g.store(); g.mu.Unlock(); use(g.store) }
+func (g *gauge) store() { g.n = 1 }
+
+func use(f func()) { f() }
+
+type meter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (m *meter) Mark() { m.mu.Lock(); m.tick(); m.mu.Unlock() }
+func (m *meter) tick() { m.n++ }
+
+type ticker interface{ tick() }
+
+func Expose(m *meter) ticker { return m }
+
+type timer struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (t *timer) Fire() { t.mu.Lock(); t.stop(); t.mu.Unlock() }
+func (t *timer) stop() { t.n = 0 }
+
+type stopper interface{ stop() }
+
+func Halt(s stopper) { s.stop() }
+
+func Run(c *counter, g *gauge, m *meter, t *timer) {
+	c.Bump()
+	g.Set()
+	m.Mark()
+	t.Fire()
+}
+`})
+
+	pkg := prog.ImportedPackage("example.com/lib")
+	var funcs []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg == pkg && fn.Synthetic == "" && len(fn.Blocks) > 0 {
+			funcs = append(funcs, fn)
+		}
+	}
+	flows := make(map[*ssa.Function][]*lockState, len(funcs))
+	for _, fn := range funcs {
+		flows[fn] = lockFlow(fn)
+	}
+	mutexFields := func(t types.Type) (*types.Named, []int) {
+		n, ok := t.(*types.Named)
+		if !ok || n.Obj().Pkg() != pkg.Pkg {
+			return nil, nil
+		}
+		return n, []int{0}
+	}
+	held := lockedOnEntry(prog, funcs, flows, mutexFields)
+
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{"inc", true},    // only called with c.mu held
+		{"store", false}, // also used as a method value
+		{"tick", false},  // *meter converted to an interface
+		{"stop", false},  // invoked through an interface
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var fn *ssa.Function
+			for _, f := range funcs {
+				if f.Name() == tt.method && f.Signature.Recv() != nil {
+					fn = f
+				}
+			}
+			if fn == nil {
+				t.Fatalf("no method %s", tt.method)
+			}
+			if got := held[fn][0]; got != tt.want {
+				t.Errorf("held on entry = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
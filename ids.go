@@ -34,6 +34,45 @@ func BlockID(funcID string, blockIndex int) string {
 	return fmt.Sprintf("%s::bb%d", funcID, blockIndex)
 }
 
+// InstrID generates a node ID for the index-th SSA instruction of a basic block.
+func InstrID(blockID string, index int) string {
+	return fmt.Sprintf("%s::i%d", blockID, index)
+}
+
+// LoopID generates a node ID for the natural loop headed by an SSA basic block.
+func LoopID(funcID string, headerIndex int) string {
+	return fmt.Sprintf("%s::loop%d", funcID, headerIndex)
+}
+
+// ChanID generates a node ID for the channel created by a make(chan) call.
+func ChanID(pkg, file string, line, col int) string {
+	return fmt.Sprintf("%s::@%s:%d:%d:chan", pkg, file, line, col)
+}
+
+// LockID generates a node ID for a mutex identified by its struct field or
+// package variable (see lockClass).
+func LockID(class string) string {
+	return fmt.Sprintf("lock::%s", class)
+}
+
+// MetricID generates a node ID for a Prometheus metric, by its full name
+// or, when the name is not constant, by its constructor call site.
+func MetricID(name string) string {
+	return fmt.Sprintf("metric::%s", name)
+}
+
+// RouteID generates a node ID for an HTTP route served by the registration
+// call at site. path is "?" when the pattern is not constant.
+func RouteID(method, path, site string) string {
+	return fmt.Sprintf("route::%s@%s", strings.TrimSpace(method+" "+path), site)
+}
+
+// ProtoID generates a node ID for a protobuf definition by its fully
+// qualified name: "pkg.Message", "pkg.Service" or "pkg.Service/Method".
+func ProtoID(fullName string) string {
+	return fmt.Sprintf("proto::%s", fullName)
+}
+
 // BaseName extracts the filename without directory from a path.
 func BaseName(path string) string {
 	idx := strings.LastIndex(path, "/")
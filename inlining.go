@@ -0,0 +1,133 @@
+package main
+
+import "slices"
+
+// AttachOptimizations attaches the compiler's inlining and bounds-check
+// decisions (see RunEscapeAnalysis) to the nodes they concern.
+//
+// Function nodes get inline_cost and, when the compiler refuses to inline
+// them, inline_blocked with the reason (inline_budget for functions over
+// the budget). Call nodes get inlined, inline_callee and, for calls left in
+// place, inline_blocked: the site's own reason (a recursive cycle) or, for
+// static calls of a function that cannot be inlined, the callee's. Index
+// and slice expressions whose bounds check survived prove get
+// bounds_check, the checks the compiler kept.
+func AttachOptimizations(results []EscapeResult, posLookup *PosLookup, funcLookup *FuncLookup, cpg *CPG, prog *Progress) {
+	nodeByID := make(map[string]*Node, len(cpg.Nodes))
+	for i := range cpg.Nodes {
+		nodeByID[cpg.Nodes[i].ID] = &cpg.Nodes[i]
+	}
+	props := func(n *Node) map[string]any {
+		if n.Properties == nil {
+			n.Properties = map[string]any{}
+		}
+		return n.Properties
+	}
+
+	var funcs, inlined, blocked, checks int
+	for _, r := range results {
+		switch r.Kind {
+		case "inlineable", "not_inlineable":
+			fn := nodeByID[funcLookup.Get(r.RelFile, r.Line, r.Col)]
+			if fn == nil || fn.Kind != "function" {
+				continue
+			}
+			p := props(fn)
+			if _, done := p["inline_cost"]; done || p["inline_blocked"] != nil {
+				continue
+			}
+			funcs++
+			if r.Cost > 0 {
+				p["inline_cost"] = r.Cost
+			}
+			if r.Kind == "not_inlineable" {
+				p["inline_blocked"] = r.Reason
+				if r.Budget > 0 {
+					p["inline_budget"] = r.Budget
+				}
+			}
+		case "inlined_call":
+			call := nodeByID[posLookup.Get(r.RelFile, r.Line, r.Col)]
+			if call == nil || call.Kind != "call" {
+				continue
+			}
+			p := props(call)
+			if p["inlined"] == true {
+				continue
+			}
+			inlined++
+			p["inlined"] = true
+			p["inline_callee"] = r.Detail
+		case "bounds_check":
+			n := nodeByID[posLookup.Get(r.RelFile, r.Line, r.Col)]
+			if n == nil {
+				continue
+			}
+			p := props(n)
+			kept, _ := p["bounds_check"].([]string)
+			if slices.Contains(kept, r.Detail) {
+				continue
+			}
+			checks++
+			p["bounds_check"] = append(kept, r.Detail)
+		}
+	}
+
+	// A recursive call is inlined once and then refused at the same site;
+	// the refusal only stands for calls never inlined.
+	for _, r := range results {
+		if r.Kind != "call_not_inlined" {
+			continue
+		}
+		call := nodeByID[posLookup.Get(r.RelFile, r.Line, r.Col)]
+		if call == nil || call.Kind != "call" {
+			continue
+		}
+		p := props(call)
+		if _, done := p["inlined"]; done {
+			continue
+		}
+		blocked++
+		p["inlined"] = false
+		p["inline_callee"] = r.Detail
+		p["inline_blocked"] = r.Reason
+	}
+
+	// The compiler says nothing at the call sites of a function it cannot
+	// inline; take the callee's reason for static calls.
+	callees := make(map[string][]string)
+	dynamic := make(map[string]bool)
+	for _, e := range cpg.Edges {
+		if e.Kind != "call_site" {
+			continue
+		}
+		callees[e.Source] = append(callees[e.Source], e.Target)
+		if e.Properties["dynamic"] == true {
+			dynamic[e.Source] = true
+		}
+	}
+	for site, targets := range callees {
+		if len(targets) != 1 || dynamic[site] {
+			continue
+		}
+		call, fn := nodeByID[site], nodeByID[targets[0]]
+		if call == nil || fn == nil || fn.Properties == nil {
+			continue
+		}
+		reason, ok := fn.Properties["inline_blocked"].(string)
+		if !ok {
+			continue
+		}
+		p := props(call)
+		if _, done := p["inlined"]; done {
+			continue
+		}
+		blocked++
+		p["inlined"] = false
+		p["inline_callee"] = fn.Name
+		p["inline_blocked"] = reason
+	}
+
+	prog.Log("Optimizations: %d functions with inlining decisions, %d call sites inlined, %d not inlined, %d bounds checks kept",
+		funcs, inlined, blocked, checks)
+}
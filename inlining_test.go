@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttachOptimizations(t *testing.T) {
+	cpg := &CPG{
+		Nodes: []Node{
+			{ID: "f:Big", Kind: "function", Name: "Big"},
+			{ID: "f:Pick", Kind: "function", Name: "Pick"},
+			{ID: "f:NoInl", Kind: "function", Name: "NoInl"},
+			{ID: "c:big", Kind: "call"},
+			{ID: "c:rec", Kind: "call"},
+			{ID: "c:recOnce", Kind: "call"},
+			{ID: "c:noinl", Kind: "call"},
+			{ID: "c:iface", Kind: "call"},
+			{ID: "i:idx", Kind: "index"},
+		},
+		Edges: []Edge{
+			{Source: "c:noinl", Target: "f:NoInl", Kind: "call_site"},
+			{Source: "c:iface", Target: "f:Pick", Kind: "call_site", Properties: map[string]any{"dynamic": true}},
+			{Source: "c:big", Target: "f:Big", Kind: "call_site"},
+		},
+	}
+	funcLookup := NewFuncLookup()
+	funcLookup.Set("hot.go", 22, 6, "f:Big")
+	funcLookup.Set("hot.go", 11, 6, "f:Pick")
+	funcLookup.Set("call.go", 13, 6, "f:NoInl")
+	posLookup := NewPosLookup()
+	posLookup.Set("hot.go", 15, 11, "c:big")
+	posLookup.Set("call.go", 15, 41, "c:rec")
+	posLookup.Set("call.go", 20, 3, "c:recOnce")
+	posLookup.Set("hot.go", 14, 10, "i:idx")
+
+	results := []EscapeResult{
+		{RelFile: "hot.go", Line: 22, Col: 6, Kind: "inlineable", Detail: "Big", Cost: 64},
+		{RelFile: "hot.go", Line: 11, Col: 6, Kind: "not_inlineable", Detail: "Pick", Reason: "function too complex", Cost: 95, Budget: 80},
+		{RelFile: "call.go", Line: 13, Col: 6, Kind: "not_inlineable", Detail: "NoInl", Reason: "marked go:noinline"},
+		{RelFile: "hot.go", Line: 15, Col: 11, Kind: "inlined_call", Detail: "Big"},
+		// Inlined once, then refused at the same site
+		{RelFile: "call.go", Line: 20, Col: 3, Kind: "inlined_call", Detail: "Rec"},
+		{RelFile: "call.go", Line: 20, Col: 3, Kind: "call_not_inlined", Detail: "Rec", Reason: "repeated recursive cycle"},
+		{RelFile: "call.go", Line: 15, Col: 41, Kind: "call_not_inlined", Detail: "Rec", Reason: "repeated recursive cycle"},
+		{RelFile: "hot.go", Line: 14, Col: 10, Kind: "bounds_check", Detail: "IsInBounds"},
+		{RelFile: "hot.go", Line: 14, Col: 10, Kind: "bounds_check", Detail: "IsInBounds"},
+		{RelFile: "hot.go", Line: 14, Col: 10, Kind: "bounds_check", Detail: "IsSliceInBounds"},
+	}
+	AttachOptimizations(results, posLookup, funcLookup, cpg, NewProgress(false))
+
+	want := map[string]map[string]any{
+		"f:Big":     {"inline_cost": 64},
+		"f:Pick":    {"inline_cost": 95, "inline_blocked": "function too complex", "inline_budget": 80},
+		"f:NoInl":   {"inline_blocked": "marked go:noinline"},
+		"c:big":     {"inlined": true, "inline_callee": "Big"},
+		"c:rec":     {"inlined": false, "inline_callee": "Rec", "inline_blocked": "repeated recursive cycle"},
+		"c:recOnce": {"inlined": true, "inline_callee": "Rec"},
+		"c:noinl":   {"inlined": false, "inline_callee": "NoInl", "inline_blocked": "marked go:noinline"},
+		"c:iface":   nil,
+		"i:idx":     {"bounds_check": []string{"IsInBounds", "IsSliceInBounds"}},
+	}
+	for _, n := range cpg.Nodes {
+		if !reflect.DeepEqual(n.Properties, want[n.ID]) {
+			t.Errorf("%s: got %v, want %v", n.ID, n.Properties, want[n.ID])
+		}
+	}
+}
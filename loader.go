@@ -97,10 +97,11 @@ func LoadPackages(goworkPath string, prog *Progress) (*LoadResult, error) {
 			packages.NeedSyntax |
 			packages.NeedTypesInfo |
 			packages.NeedTypesSizes,
-		Dir:   modSet.PrimaryDir(),
-		Fset:  fset,
-		Tests: false,
-		Env:   replaceEnv(os.Environ(), "GOWORK", goworkPath),
+		Dir:        modSet.PrimaryDir(),
+		Fset:       fset,
+		Tests:      !flagSkipTests,
+		BuildFlags: buildFlags(),
+		Env:        replaceEnv(os.Environ(), "GOWORK", goworkPath),
 	}
 
 	initial, err := packages.Load(cfg, modSet.LoadPatterns()...)
@@ -111,7 +112,7 @@ func LoadPackages(goworkPath string, prog *Progress) (*LoadResult, error) {
 	// Filter to known module packages only
 	filtered := make([]*packages.Package, 0, len(initial))
 	var errCount int
-	for _, pkg := range initial {
+	for _, pkg := range dedupTestVariants(initial) {
 		if !modSet.IsKnownPkg(pkg.PkgPath) {
 			continue
 		}
@@ -148,12 +149,56 @@ func LoadPackages(goworkPath string, prog *Progress) (*LoadResult, error) {
 	}, nil
 }
 
-// Skip flags, set by main before any pipeline phase runs.
+// dedupTestVariants drops what loading with Tests adds besides the test
+// files: the generated testmain packages ("p.test", named main) and all but
+// one copy of each package. go list returns a package with tests both plain
+// and as the variant "p [p.test]" that adds its _test.go files, and
+// recompiles packages its tests import as "q [p.test]". The own test
+// variant is kept when there is one, else the plain package; external test
+// packages ("p_test [p.test]") have no other copy. Uses of the dropped
+// copies' objects resolve by position (see DefLookup).
+func dedupTestVariants(pkgs []*packages.Package) []*packages.Package {
+	ids := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		ids[pkg.ID] = true
+	}
+	kept := make(map[string]bool)
+	out := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		path := pkg.PkgPath
+		if strings.HasSuffix(path, ".test") || kept[path] {
+			continue
+		}
+		own := path + " [" + path + ".test]"
+		switch {
+		case pkg.ID == own:
+		case pkg.ID == path && !ids[own]:
+		case !ids[own] && !ids[path]:
+		default:
+			continue
+		}
+		kept[path] = true
+		out = append(out, pkg)
+	}
+	return out
+}
+
+// Skip flags and build tags, set by main before any pipeline phase runs.
 var (
 	flagSkipTests     = true
 	flagSkipGenerated = true
+	flagBuildTags     string
 )
 
+// buildFlags returns the go command flags shared by package loading and the
+// compiler runs of escape analysis.
+func buildFlags() []string {
+	if flagBuildTags == "" {
+		return nil
+	}
+	return []string{"-tags=" + flagBuildTags}
+}
+
 // replaceEnv returns a copy of environ with key set to val, replacing any
 // existing entry for key. This avoids duplicate env vars which have
 // platform-dependent behavior (last-wins on Linux, first-wins on some BSDs).
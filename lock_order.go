@@ -0,0 +1,499 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// lockAcq is one way a function acquires a lock: the mode ("w" or "r") and
+// the chain of call site IDs leading to the Lock call, ending with it.
+type lockAcq struct {
+	mode  string
+	chain []string
+}
+
+// lockCallSite is a call made by a function together with the lock
+// classes held on every path to it.
+type lockCallSite struct {
+	site    string
+	held    map[string]string // class → mode
+	heldAt  map[string]string // class → site of the call that acquired it
+	targets []string
+}
+
+// lockFuncInfo summarizes one function for the lock-order graph.
+type lockFuncInfo struct {
+	local    map[string]lockAcq // class → first Lock call in the function
+	ordered  []lockOrderWitness // acquisitions of B with A held, within the function
+	calls    []lockCallSite
+	acquires map[string]lockAcq // local plus transitive through callees
+}
+
+// lockOrderWitness is one observation of B acquired while A is held.
+type lockOrderWitness struct {
+	from, to       string // lock classes
+	function       string
+	heldSite       string
+	heldMode, mode string
+	chain          []string
+}
+
+// lockInstance is a lock node being built.
+type lockInstance struct {
+	typ   string // sync.Mutex or sync.RWMutex
+	obj   types.Object
+	sites int
+}
+
+// BuildLockOrder identifies lock instances by struct field or package
+// variable (lockClass) and builds the whole-program lock acquisition order
+// graph over the VTA call graph. Each function is summarized by the locks
+// it acquires, directly or through callees (following call_site edges, so
+// interface and closure calls are included; go statements are not, the
+// spawned goroutine holds nothing). An edge A→B is added when B is acquired,
+// locally or in a callee, while A is held on every path to that point.
+//
+// It emits lock nodes, acquires edges (function→lock it locks directly) and
+// lock_order edges (lock→lock) carrying the function holding A, the site A
+// was acquired at, and the call chain down to B's acquisition. Each
+// strongly connected component of the lock_order graph yields one cycle,
+// stored as order_cycle on its first lock node, which createLockOrder
+// turns into a lock_order_inversion finding. Re-acquiring the same class
+// (two instances of one type) is not an edge.
+//
+// Mutex calls that already carry sync_kind get a lock property pointing at
+// their lock node. Must run after BuildCallGraph.
+func BuildLockOrder(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Building lock order graph...")
+
+	callTargets := make(map[string][]string) // call site ID → callee IDs
+	for _, e := range cpg.Edges {
+		if e.Kind == "call_site" {
+			callTargets[e.Source] = append(callTargets[e.Source], e.Target)
+		}
+	}
+
+	instances := make(map[string]*lockInstance)
+	siteLocks := make(map[string]string) // Lock/Unlock call site ID → lock class
+	infos := make(map[string]*lockFuncInfo)
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+		fnID := ssaFuncNodeID(fn, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		info := lockFuncSummary(fn, fset, posLookup, callTargets, instances, siteLocks)
+		if info != nil {
+			info.acquires = make(map[string]lockAcq, len(info.local))
+			for c, a := range info.local {
+				info.acquires[c] = a
+			}
+			for i := range info.ordered {
+				info.ordered[i].function = fnID
+			}
+			infos[fnID] = info
+		}
+	}
+
+	fnIDs := make([]string, 0, len(infos))
+	for id := range infos {
+		fnIDs = append(fnIDs, id)
+	}
+	sort.Strings(fnIDs)
+
+	// Transitive acquisitions. Each round extends chains by one call, so
+	// the first witness found for a class is a shortest one.
+	for changed := true; changed; {
+		changed = false
+		for _, id := range fnIDs {
+			info := infos[id]
+			for _, call := range info.calls {
+				for _, t := range call.targets {
+					callee, ok := infos[t]
+					if !ok || t == id {
+						continue
+					}
+					for _, class := range slices.Sorted(maps.Keys(callee.acquires)) {
+						if _, ok := info.acquires[class]; ok {
+							continue
+						}
+						a := callee.acquires[class]
+						info.acquires[class] = lockAcq{
+							mode:  a.mode,
+							chain: append([]string{call.site}, a.chain...),
+						}
+						changed = true
+					}
+				}
+			}
+		}
+	}
+
+	// Order edges: local acquisitions under a held lock, and everything a
+	// callee acquires while the caller holds a lock.
+	type orderKey struct{ from, to string }
+	orders := make(map[orderKey][]lockOrderWitness)
+	var keys []orderKey
+	add := func(w lockOrderWitness) {
+		if w.from == w.to {
+			return
+		}
+		k := orderKey{w.from, w.to}
+		if _, ok := orders[k]; !ok {
+			keys = append(keys, k)
+		}
+		orders[k] = append(orders[k], w)
+	}
+	for _, id := range fnIDs {
+		info := infos[id]
+		for _, w := range info.ordered {
+			add(w)
+		}
+		for _, call := range info.calls {
+			for _, t := range call.targets {
+				callee, ok := infos[t]
+				if !ok {
+					continue
+				}
+				for _, held := range slices.Sorted(maps.Keys(call.held)) {
+					for _, class := range slices.Sorted(maps.Keys(callee.acquires)) {
+						a := callee.acquires[class]
+						add(lockOrderWitness{
+							from: held, to: class, function: id,
+							heldSite: call.heldAt[held], heldMode: call.held[held], mode: a.mode,
+							chain: append([]string{call.site}, a.chain...),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].from != keys[j].from {
+			return keys[i].from < keys[j].from
+		}
+		return keys[i].to < keys[j].to
+	})
+	orderProps := make(map[orderKey]map[string]any, len(keys))
+	succs := make(map[string][]string)
+	for _, k := range keys {
+		ws := orders[k]
+		w := ws[0]
+		for _, o := range ws[1:] {
+			if len(o.chain) < len(w.chain) {
+				w = o
+			}
+		}
+		orderProps[k] = map[string]any{
+			"function":     w.function,
+			"held_site":    w.heldSite,
+			"held_mode":    w.heldMode,
+			"mode":         w.mode,
+			"acquire_site": w.chain[len(w.chain)-1],
+			"chain":        w.chain,
+			"witnesses":    len(ws),
+		}
+		succs[k.from] = append(succs[k.from], k.to)
+	}
+
+	// One cycle per strongly connected component, recorded on the lock it
+	// starts from for createLockOrder.
+	cycles := make(map[string]map[string]any)
+	for _, cycle := range lockOrderCycles(succs) {
+		locks := make([]string, len(cycle))
+		props := make([]map[string]any, len(cycle))
+		for i, c := range cycle {
+			locks[i] = LockID(c)
+			props[i] = orderProps[orderKey{c, cycle[(i+1)%len(cycle)]}]
+		}
+		cycles[cycle[0]] = map[string]any{"locks": locks, "orders": props}
+	}
+
+	var lockNodes, acquireEdges, orderEdges int
+	classes := make([]string, 0, len(instances))
+	for c := range instances {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	for _, c := range classes {
+		inst := instances[c]
+		node := Node{
+			ID:       LockID(c),
+			Kind:     "lock",
+			Name:     c,
+			TypeInfo: inst.typ,
+			Properties: map[string]any{
+				"lock_sites": inst.sites,
+			},
+		}
+		if cycle, ok := cycles[c]; ok {
+			node.Properties["order_cycle"] = cycle
+		}
+		if inst.obj.Pkg() != nil {
+			node.Package = modSet.RelPkg(inst.obj.Pkg().Path())
+		}
+		if inst.obj.Pos().IsValid() {
+			pos := fset.Position(inst.obj.Pos())
+			node.File = modSet.RelFile(pos.Filename)
+			node.Line, node.Col = pos.Line, pos.Column
+		}
+		cpg.AddNode(node)
+		lockNodes++
+	}
+	for _, id := range fnIDs {
+		for _, class := range slices.Sorted(maps.Keys(infos[id].local)) {
+			a := infos[id].local[class]
+			cpg.AddEdge(Edge{
+				Source: id, Target: LockID(class), Kind: "acquires",
+				Properties: map[string]any{"site": a.chain[0], "mode": a.mode},
+			})
+			acquireEdges++
+		}
+	}
+	for _, k := range keys {
+		cpg.AddEdge(Edge{
+			Source: LockID(k.from), Target: LockID(k.to), Kind: "lock_order",
+			Properties: orderProps[k],
+		})
+		orderEdges++
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if class, ok := siteLocks[n.ID]; ok && n.Properties["sync_kind"] != nil {
+			n.Properties["lock"] = LockID(class)
+		}
+	}
+
+	prog.Log("Created %d lock nodes, %d acquires, %d lock_order edges, %d order cycles", lockNodes, acquireEdges, orderEdges, len(cycles))
+}
+
+// lockOrderCycles finds the strongly connected components of the
+// lock_order graph (Tarjan) and returns one cycle for each component with
+// more than one lock: a shortest cycle through its smallest class, starting
+// there. succs lists must be sorted for a deterministic result.
+func lockOrderCycles(succs map[string][]string) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+	var connect func(v string)
+	connect = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range succs[v] {
+			if _, seen := index[w]; !seen {
+				connect(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			sccs = append(sccs, scc)
+		}
+	}
+	for _, v := range slices.Sorted(maps.Keys(succs)) {
+		if _, seen := index[v]; !seen {
+			connect(v)
+		}
+	}
+
+	var cycles [][]string
+	for _, scc := range sccs {
+		in := make(map[string]bool, len(scc))
+		for _, v := range scc {
+			in[v] = true
+		}
+		root := slices.Min(scc)
+		// BFS from root within the component back to root.
+		parent := map[string]string{root: ""}
+		queue := []string{root}
+		last := ""
+		for len(queue) > 0 && last == "" {
+			v := queue[0]
+			queue = queue[1:]
+			for _, w := range succs[v] {
+				if w == root {
+					last = v
+					break
+				}
+				if _, seen := parent[w]; !seen && in[w] {
+					parent[w] = v
+					queue = append(queue, w)
+				}
+			}
+		}
+		var cycle []string
+		for v := last; v != ""; v = parent[v] {
+			cycle = append(cycle, v)
+		}
+		slices.Reverse(cycle)
+		cycles = append(cycles, cycle)
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// lockFuncSummary collects fn's lock acquisitions, the order between locks
+// acquired within fn, and its calls made with locks held. Locks without a
+// program-wide identity (locals) are ignored. Returns nil for functions
+// with neither locks nor calls into analyzed code.
+func lockFuncSummary(
+	fn *ssa.Function,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	callTargets map[string][]string,
+	instances map[string]*lockInstance,
+	siteLocks map[string]string,
+) *lockFuncInfo {
+	info := &lockFuncInfo{local: make(map[string]lockAcq)}
+	classOf := make(map[string]string) // lock path → class, within fn
+
+	siteOf := func(instr ssa.Instruction) string {
+		file, line, col := instrPos(instr, fset)
+		if file == "" {
+			return ""
+		}
+		return posLookup.Get(file, line, col)
+	}
+
+	// Resolve classes up front: the state keys locks by path.
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			op, recv := syncLockOp(&call.Call)
+			if op == "" {
+				continue
+			}
+			class, obj := lockClass(recv)
+			if class == "" {
+				continue
+			}
+			classOf[lockPath(recv)] = class
+			inst, ok := instances[class]
+			if !ok {
+				inst = &lockInstance{typ: deref(recv.Type()).String(), obj: obj}
+				instances[class] = inst
+			}
+			site := siteOf(call)
+			if site != "" {
+				siteLocks[site] = class
+			}
+			if op == lockOpLock || op == lockOpRLock {
+				inst.sites++
+				if _, ok := info.local[class]; !ok && site != "" {
+					info.local[class] = lockAcq{mode: lockMode(op), chain: []string{site}}
+				}
+			}
+		}
+	}
+
+	// held returns the classes held on every path with their mode and the
+	// site that acquired them.
+	held := func(st *lockState) (modes, sites map[string]string) {
+		modes, sites = make(map[string]string), make(map[string]string)
+		for k := range st.must {
+			if class := classOf[k[2:]]; class != "" {
+				// A write hold dominates a read hold of the same lock.
+				if modes[class] != "w" {
+					modes[class] = k[:1]
+					sites[class] = ""
+					if call := st.at[k]; call != nil {
+						sites[class] = siteOf(call)
+					}
+				}
+			}
+		}
+		return modes, sites
+	}
+
+	in := lockFlow(fn)
+	for _, b := range fn.Blocks {
+		if in[b.Index] == nil {
+			continue
+		}
+		st := in[b.Index].clone()
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				op, recv := syncLockOp(&call.Call)
+				switch {
+				case op == lockOpLock || op == lockOpRLock:
+					class := classOf[lockPath(recv)]
+					site := siteOf(call)
+					if class != "" && site != "" {
+						h, at := held(st)
+						for _, from := range slices.Sorted(maps.Keys(h)) {
+							info.ordered = append(info.ordered, lockOrderWitness{
+								from: from, to: class,
+								heldSite: at[from], heldMode: h[from], mode: lockMode(op),
+								chain: []string{site},
+							})
+						}
+					}
+				case op == "":
+					if site := siteOf(call); site != "" && len(callTargets[site]) > 0 {
+						h, at := held(st)
+						info.calls = append(info.calls, lockCallSite{
+							site: site, held: h, heldAt: at, targets: callTargets[site],
+						})
+					}
+				}
+			}
+			applyLockInstr(st, instr)
+		}
+	}
+
+	if len(info.local) == 0 && len(info.calls) == 0 {
+		return nil
+	}
+	return info
+}
+
+// lockMode maps a Lock/RLock operation to its mode.
+func lockMode(op string) string {
+	if op == lockOpRLock {
+		return "r"
+	}
+	return "w"
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestLockOrderCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		succs map[string][]string
+		want  [][]string
+	}{
+		{"acyclic", map[string][]string{"a": {"b"}, "b": {"c"}}, nil},
+		{"two", map[string][]string{"a": {"b"}, "b": {"a"}}, [][]string{{"a", "b"}}},
+		{"four", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"a"}},
+			[][]string{{"a", "b", "c", "d"}}},
+		// one cycle per component, the shortest through its smallest lock
+		{"shortest", map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"a"}, "d": {"a"}},
+			[][]string{{"a", "c"}}},
+		{"two components", map[string][]string{
+			"a": {"b"}, "b": {"a", "x"}, "x": {"y"}, "y": {"z"}, "z": {"x"},
+		}, [][]string{{"a", "b"}, {"x", "y", "z"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lockOrderCycles(tt.succs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lockOrderCycles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockAcquisitionSite(t *testing.T) {
+	prog, fset := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+import "sync"
+
+type S struct{ a, b sync.Mutex }
+
+func Relock(s *S) {
+	s.a.Lock()
+	s.a.Unlock()
+	s.a.Lock()
+	s.b.Lock()
+	s.b.Unlock()
+	s.a.Unlock()
+}
+
+func Branches(s *S, c bool) {
+	if c {
+		s.a.Lock()
+	} else {
+		s.a.Lock()
+	}
+	s.b.Lock()
+	s.b.Unlock()
+	s.a.Unlock()
+}
+`})
+	pkg := prog.ImportedPackage("example.com/lib")
+
+	tests := []struct {
+		fn   string
+		want int // line of the s.a.Lock() held at s.b.Lock()
+	}{
+		{"Relock", 10},   // the second acquisition, not the first
+		{"Branches", 18}, // the earliest of the branches
+	}
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			fn := pkg.Func(tt.fn)
+			got := -1
+			forEachLockState(fn, lockFlow(fn), func(instr ssa.Instruction, st *lockState) {
+				call, ok := instr.(*ssa.Call)
+				if !ok {
+					return
+				}
+				op, recv := syncLockOp(&call.Call)
+				if op != lockOpLock || lockPath(recv) == "" || !strings.HasSuffix(lockPath(recv), ".b") {
+					return
+				}
+				for k, at := range st.at {
+					if strings.HasSuffix(k, ".a") {
+						got = fset.Position(at.Pos()).Line
+					}
+				}
+			})
+			if got != tt.want {
+				t.Errorf("s.a acquired at line %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
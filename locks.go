@@ -0,0 +1,628 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Lock operations on sync.Mutex and sync.RWMutex.
+const (
+	lockOpLock    = "lock"
+	lockOpUnlock  = "unlock"
+	lockOpRLock   = "rlock"
+	lockOpRUnlock = "runlock"
+)
+
+// syncLockOp classifies a call to a sync.Mutex / sync.RWMutex method and
+// returns the operation and the mutex pointer it acts on. Promoted methods
+// of embedded mutexes resolve to the same static callee with the embedded
+// field's address as receiver.
+func syncLockOp(common *ssa.CallCommon) (string, ssa.Value) {
+	callee := common.StaticCallee()
+	if callee == nil || len(common.Args) == 0 {
+		return "", nil
+	}
+	recv := callee.Signature.Recv()
+	if recv == nil {
+		return "", nil
+	}
+	n, ok := deref(recv.Type()).(*types.Named)
+	if !ok || n.Obj().Pkg() == nil || n.Obj().Pkg().Path() != "sync" {
+		return "", nil
+	}
+	typeName := n.Obj().Name()
+	if typeName != "Mutex" && typeName != "RWMutex" {
+		return "", nil
+	}
+	switch callee.Name() {
+	case "Lock":
+		return lockOpLock, common.Args[0]
+	case "Unlock":
+		return lockOpUnlock, common.Args[0]
+	case "RLock":
+		if typeName == "RWMutex" {
+			return lockOpRLock, common.Args[0]
+		}
+	case "RUnlock":
+		if typeName == "RWMutex" {
+			return lockOpRUnlock, common.Args[0]
+		}
+	}
+	return "", nil
+}
+
+// lockPath renders the access path of a mutex pointer within one function,
+// e.g. "s.mu" or "m.tg.mtx", so separate evaluations of the same
+// expression compare equal. Loads are transparent.
+func lockPath(v ssa.Value) string {
+	switch x := v.(type) {
+	case *ssa.FieldAddr:
+		return lockPath(x.X) + "." + structFieldName(deref(x.X.Type()), x.Field)
+	case *ssa.Field:
+		return lockPath(x.X) + "." + structFieldName(x.X.Type(), x.Field)
+	case *ssa.UnOp:
+		if x.Op == token.MUL {
+			return lockPath(x.X)
+		}
+	case *ssa.Parameter, *ssa.FreeVar:
+		return x.Name()
+	case *ssa.Global:
+		return x.Pkg.Pkg.Name() + "." + x.Name()
+	case *ssa.Alloc:
+		if x.Comment != "" {
+			return x.Comment
+		}
+	case *ssa.ChangeType:
+		return lockPath(x.X)
+	}
+	return v.Name()
+}
+
+// lockClass identifies a mutex across the program: "pkg.Type.field" for a
+// struct field (all instances share it), "pkg.var" for a package variable,
+// "" for locals, whose identity does not outlive the function. The object
+// is the field or variable declaration.
+func lockClass(v ssa.Value) (string, types.Object) {
+	switch x := v.(type) {
+	case *ssa.FieldAddr:
+		return fieldClass(deref(x.X.Type()), x.Field)
+	case *ssa.Field:
+		return fieldClass(x.X.Type(), x.Field)
+	case *ssa.Global:
+		return modSet.RelPkg(x.Pkg.Pkg.Path()) + "." + x.Name(), x.Object()
+	case *ssa.UnOp:
+		if x.Op == token.MUL {
+			// A pointer to a mutex held in a variable (mu *sync.Mutex):
+			// identify it by where the pointer lives.
+			return lockClass(x.X)
+		}
+	case *ssa.ChangeType:
+		return lockClass(x.X)
+	}
+	return "", nil
+}
+
+// fieldClass renders field i of struct type t as "pkg.Type.field".
+func fieldClass(t types.Type, i int) (string, types.Object) {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || i >= st.NumFields() {
+		return "", nil
+	}
+	return typeShortName(t) + "." + st.Field(i).Name(), st.Field(i)
+}
+
+// structFieldName returns the name of field i of struct type t.
+func structFieldName(t types.Type, i int) string {
+	if st, ok := t.Underlying().(*types.Struct); ok && i < st.NumFields() {
+		return st.Field(i).Name()
+	}
+	return fmt.Sprintf("field%d", i)
+}
+
+// typeShortName renders a named type as pkg.Type relative to the module.
+func typeShortName(t types.Type) string {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil {
+		return modSet.RelPkg(n.Obj().Pkg().Path()) + "." + n.Obj().Name()
+	}
+	return t.String()
+}
+
+// blockingCallKind classifies calls that may block indefinitely:
+// WaitGroup.Wait, time.Sleep and network I/O. Returns "".
+func blockingCallKind(common *ssa.CallCommon) string {
+	if _, ok := waitGroupCall(common, "Wait"); ok {
+		return "wg_wait"
+	}
+	var pkgPath, name string
+	if common.IsInvoke() {
+		if n, ok := common.Value.Type().(*types.Named); ok && n.Obj().Pkg() != nil {
+			pkgPath = n.Obj().Pkg().Path()
+		}
+		name = common.Method.Name()
+	} else if callee := common.StaticCallee(); callee != nil {
+		if obj := callee.Object(); obj != nil && obj.Pkg() != nil {
+			pkgPath = obj.Pkg().Path()
+		}
+		name = callee.Name()
+	}
+	switch {
+	case pkgPath == "time" && name == "Sleep":
+		return "sleep"
+	case networkPkgs[pkgPath] && networkMethods[name]:
+		return "network_io"
+	}
+	return ""
+}
+
+// networkPkgs and networkMethods select the network calls treated as
+// blocking by blockingCallKind.
+var networkPkgs = map[string]bool{
+	"net":                          true,
+	"net/http":                     true,
+	"net/rpc":                      true,
+	"google.golang.org/grpc":       true,
+	"github.com/miekg/dns":         true,
+	"golang.org/x/net/http2":       true,
+	"github.com/gorilla/websocket": true,
+}
+
+var networkMethods = map[string]bool{
+	"Dial": true, "DialContext": true, "DialTimeout": true,
+	"Do": true, "Get": true, "Head": true, "Post": true, "PostForm": true,
+	"Read": true, "Write": true, "Accept": true, "ReadFrom": true, "WriteTo": true,
+	"ListenAndServe": true, "Serve": true, "Invoke": true, "NewStream": true,
+	"RecvMsg": true, "SendMsg": true, "Exchange": true, "ExchangeContext": true,
+	"ReadMessage": true, "WriteMessage": true,
+}
+
+// lockState is the dataflow fact at a program point: locks held on some
+// path (may), on every path (must), and deferred unlocks on every path.
+// Keys are "w:" or "r:" plus the lock path. at records the Lock / RLock call
+// that acquired each must-held lock; when paths acquire it at different
+// calls, the earliest in source order is kept.
+type lockState struct {
+	may, must, deferred map[string]bool
+	at                  map[string]*ssa.Call
+}
+
+func newLockState() *lockState {
+	return &lockState{
+		may: map[string]bool{}, must: map[string]bool{}, deferred: map[string]bool{},
+		at: map[string]*ssa.Call{},
+	}
+}
+
+func (s *lockState) clone() *lockState {
+	c := newLockState()
+	for k := range s.may {
+		c.may[k] = true
+	}
+	for k := range s.must {
+		c.must[k] = true
+	}
+	for k := range s.deferred {
+		c.deferred[k] = true
+	}
+	for k, call := range s.at {
+		c.at[k] = call
+	}
+	return c
+}
+
+// join merges a predecessor's exit state: union for may, intersection for
+// must and deferred.
+func (s *lockState) join(o *lockState) {
+	for k := range o.may {
+		s.may[k] = true
+	}
+	for k := range s.must {
+		if !o.must[k] {
+			delete(s.must, k)
+			delete(s.at, k)
+		} else if oc := o.at[k]; oc != nil && (s.at[k] == nil || oc.Pos() < s.at[k].Pos()) {
+			s.at[k] = oc
+		}
+	}
+	for k := range s.deferred {
+		if !o.deferred[k] {
+			delete(s.deferred, k)
+		}
+	}
+}
+
+func (s *lockState) equal(o *lockState) bool {
+	eq := func(a, b map[string]bool) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for k := range a {
+			if !b[k] {
+				return false
+			}
+		}
+		return true
+	}
+	if !eq(s.may, o.may) || !eq(s.must, o.must) || !eq(s.deferred, o.deferred) || len(s.at) != len(o.at) {
+		return false
+	}
+	for k, call := range s.at {
+		if o.at[k] != call {
+			return false
+		}
+	}
+	return true
+}
+
+// heldNames lists the lock paths in a set, sorted, without the mode prefix.
+func heldNames(set map[string]bool) []string {
+	var names []string
+	for k := range set {
+		names = append(names, k[2:])
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lockIssue is one pairing problem found in a function.
+type lockIssue struct {
+	kind   string // double_lock, unlock_without_lock, lock_not_released, blocking_under_lock
+	lock   string
+	instr  ssa.Instruction
+	blocks []int // offending path of block indices
+	detail string
+}
+
+// AnalyzeLockPairing runs a forward dataflow analysis over each function's
+// basic blocks tracking the sync.Mutex / sync.RWMutex locks held, by access
+// path. It reports:
+//
+//   - double_lock: Lock (or RLock under a write lock) of a lock held on
+//     every path to it;
+//   - unlock_without_lock: Unlock reachable on a path that holds no lock,
+//     in a function that also locks it (functions that only unlock are
+//     assumed to release a lock taken by their caller);
+//   - lock_not_released: a return reached with the lock possibly held and
+//     no deferred unlock, in a function that unlocks it elsewhere (lock
+//     helpers that never unlock are intentional);
+//   - blocking_under_lock: channel operations on unbuffered or untraced
+//     channels, blocking selects, WaitGroup.Wait, time.Sleep and network
+//     I/O while a lock is held on every path.
+//
+// Each issue carries the offending path of blocks and is stored in
+// lock_issues on the function node.
+func AnalyzeLockPairing(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Analyzing lock pairing...")
+
+	_, chanIndex := traceChannels(ssaResult)
+
+	issuesByFunc := make(map[string][]map[string]any)
+	counts := make(map[string]int)
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+		issues := lockIssues(fn, chanIndex)
+		if len(issues) == 0 {
+			continue
+		}
+		fnID := ssaFuncNodeID(fn, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		for _, is := range issues {
+			file, line, col := instrPos(is.instr, fset)
+			site := ""
+			if file != "" {
+				site = posLookup.Get(file, line, col)
+			}
+			path := make([]string, len(is.blocks))
+			for i, b := range is.blocks {
+				path[i] = BlockID(fnID, b)
+			}
+			issuesByFunc[fnID] = append(issuesByFunc[fnID], map[string]any{
+				"kind":   is.kind,
+				"lock":   is.lock,
+				"site":   site,
+				"file":   file,
+				"line":   line,
+				"path":   path,
+				"detail": is.detail,
+			})
+			counts[is.kind]++
+		}
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if issues, ok := issuesByFunc[n.ID]; ok {
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["lock_issues"] = issues
+		}
+	}
+
+	prog.Log("Locks: %d double locks, %d unlocks without lock, %d not released, %d blocking under lock",
+		counts["double_lock"], counts["unlock_without_lock"], counts["lock_not_released"], counts["blocking_under_lock"])
+}
+
+// lockIssues analyzes one function.
+func lockIssues(fn *ssa.Function, chanIndex map[chanOpKey][]*chanIdent) []lockIssue {
+	// Which lock paths the function locks and unlocks anywhere, and where.
+	locks := make(map[string][]int)   // path → blocks with Lock/RLock
+	unlocks := make(map[string][]int) // path → blocks with Unlock/RUnlock (incl. deferred)
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			var common *ssa.CallCommon
+			switch inst := instr.(type) {
+			case *ssa.Call:
+				common = &inst.Call
+			case *ssa.Defer:
+				common = &inst.Call
+			default:
+				continue
+			}
+			op, recv := syncLockOp(common)
+			switch op {
+			case lockOpLock, lockOpRLock:
+				p := lockPath(recv)
+				locks[p] = append(locks[p], b.Index)
+			case lockOpUnlock, lockOpRUnlock:
+				p := lockPath(recv)
+				unlocks[p] = append(unlocks[p], b.Index)
+			}
+		}
+	}
+	if len(locks) == 0 && len(unlocks) == 0 {
+		return nil
+	}
+
+	in := lockFlow(fn)
+
+	var issues []lockIssue
+	reported := make(map[string]bool)
+	report := func(is lockIssue) {
+		key := fmt.Sprintf("%s|%s|%p", is.kind, is.lock, is.instr)
+		if reported[key] {
+			return
+		}
+		reported[key] = true
+		issues = append(issues, is)
+	}
+
+	for _, b := range fn.Blocks {
+		if in[b.Index] == nil {
+			continue
+		}
+		st := in[b.Index].clone()
+		for _, instr := range b.Instrs {
+			switch inst := instr.(type) {
+			case *ssa.Call:
+				op, recv := syncLockOp(&inst.Call)
+				switch op {
+				case lockOpLock, lockOpRLock:
+					p := lockPath(recv)
+					if st.must["w:"+p] || (op == lockOpLock && st.must["r:"+p]) {
+						report(lockIssue{
+							kind: "double_lock", lock: p, instr: inst,
+							blocks: blockPath(fn, locks[p], b.Index, nil),
+							detail: op + " of " + p + " already held",
+						})
+					}
+				case lockOpUnlock, lockOpRUnlock:
+					p := lockPath(recv)
+					mode := "w:"
+					if op == lockOpRUnlock {
+						mode = "r:"
+					}
+					if len(locks[p]) > 0 && !st.must[mode+p] {
+						report(lockIssue{
+							kind: "unlock_without_lock", lock: p, instr: inst,
+							blocks: blockPath(fn, []int{0}, b.Index, blockSet(locks[p])),
+							detail: op + " of " + p + " on a path that does not hold it",
+						})
+					}
+				}
+				if kind := blockingCallKind(&inst.Call); kind != "" && len(st.must) > 0 {
+					report(blockingIssue(fn, st, inst, kind, locks))
+				}
+			case *ssa.Send:
+				if len(st.must) > 0 && mayBlockOnChan(chanIndex[chanOpKey{inst, -1}], "recv") {
+					report(blockingIssue(fn, st, inst, "chan_send", locks))
+				}
+			case *ssa.UnOp:
+				if inst.Op == token.ARROW && len(st.must) > 0 &&
+					mayBlockOnChan(chanIndex[chanOpKey{inst, -1}], "send") {
+					report(blockingIssue(fn, st, inst, "chan_recv", locks))
+				}
+			case *ssa.Select:
+				if inst.Blocking && len(st.must) > 0 {
+					report(blockingIssue(fn, st, inst, "select", locks))
+				}
+			case *ssa.Return:
+				for _, k := range slices.Sorted(maps.Keys(st.may)) {
+					p := k[2:]
+					if st.deferred[k] || len(unlocks[p]) == 0 {
+						continue
+					}
+					report(lockIssue{
+						kind: "lock_not_released", lock: p, instr: inst,
+						blocks: blockPath(fn, locks[p], b.Index, blockSet(unlocks[p])),
+						detail: p + " may still be held at return",
+					})
+				}
+			}
+			applyLockInstr(st, instr)
+		}
+	}
+	return issues
+}
+
+// lockFlow computes the lock state at entry to each block of fn by
+// iterating to a fixpoint in block order (the SSA builder emits blocks
+// roughly in reverse postorder). A nil state is an unreachable block; it
+// does not constrain the join.
+func lockFlow(fn *ssa.Function) []*lockState {
+	in := make([]*lockState, len(fn.Blocks))
+	out := make([]*lockState, len(fn.Blocks))
+	in[0] = newLockState()
+	for changed := true; changed; {
+		changed = false
+		for _, b := range fn.Blocks {
+			if in[b.Index] == nil {
+				continue
+			}
+			st := in[b.Index].clone()
+			for _, instr := range b.Instrs {
+				applyLockInstr(st, instr)
+			}
+			out[b.Index] = st
+			for _, s := range b.Succs {
+				var next *lockState
+				for _, p := range s.Preds {
+					if out[p.Index] == nil {
+						continue
+					}
+					if next == nil {
+						next = out[p.Index].clone()
+					} else {
+						next.join(out[p.Index])
+					}
+				}
+				if next != nil && (in[s.Index] == nil || !in[s.Index].equal(next)) {
+					in[s.Index] = next
+					changed = true
+				}
+			}
+		}
+	}
+	return in
+}
+
+// applyLockInstr updates the lock state across one instruction.
+func applyLockInstr(st *lockState, instr ssa.Instruction) {
+	switch inst := instr.(type) {
+	case *ssa.Call:
+		op, recv := syncLockOp(&inst.Call)
+		if op == "" {
+			return
+		}
+		p := lockPath(recv)
+		switch op {
+		case lockOpLock:
+			st.may["w:"+p], st.must["w:"+p] = true, true
+			if st.at["w:"+p] == nil {
+				st.at["w:"+p] = inst
+			}
+		case lockOpRLock:
+			st.may["r:"+p], st.must["r:"+p] = true, true
+			if st.at["r:"+p] == nil {
+				st.at["r:"+p] = inst
+			}
+		case lockOpUnlock:
+			delete(st.may, "w:"+p)
+			delete(st.must, "w:"+p)
+			delete(st.at, "w:"+p)
+		case lockOpRUnlock:
+			delete(st.may, "r:"+p)
+			delete(st.must, "r:"+p)
+			delete(st.at, "r:"+p)
+		}
+	case *ssa.Defer:
+		op, recv := syncLockOp(&inst.Call)
+		switch op {
+		case lockOpUnlock:
+			st.deferred["w:"+lockPath(recv)] = true
+		case lockOpRUnlock:
+			st.deferred["r:"+lockPath(recv)] = true
+		}
+	}
+}
+
+// blockingIssue builds a blocking_under_lock issue for the locks held on
+// every path, with the path from the first lock site.
+func blockingIssue(fn *ssa.Function, st *lockState, instr ssa.Instruction, kind string, locks map[string][]int) lockIssue {
+	held := heldNames(st.must)
+	return lockIssue{
+		kind: "blocking_under_lock", lock: strings.Join(held, ","), instr: instr,
+		blocks: blockPath(fn, locks[held[0]], instr.Block().Index, nil),
+		detail: kind + " while holding " + strings.Join(held, ", "),
+	}
+}
+
+// mayBlockOnChan reports whether a channel operation may block: the channel
+// is untraced, or some traced channel is unbuffered or lacks a counterpart.
+func mayBlockOnChan(chs []*chanIdent, counterpart string) bool {
+	if len(chs) == 0 {
+		return true
+	}
+	for _, c := range chs {
+		if !c.buffered() || !c.has(counterpart) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockPath returns the shortest CFG path (block indices) from any of the
+// from blocks to block to, not passing through avoid. Falls back to [to].
+func blockPath(fn *ssa.Function, from []int, to int, avoid map[int]bool) []int {
+	parent := make(map[int]int)
+	var queue []int
+	for _, f := range from {
+		if _, ok := parent[f]; !ok {
+			parent[f] = -1
+			queue = append(queue, f)
+		}
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == to {
+			var path []int
+			for b := cur; b != -1; b = parent[b] {
+				path = append(path, b)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		for _, s := range fn.Blocks[cur].Succs {
+			if _, seen := parent[s.Index]; seen || (avoid[s.Index] && s.Index != to) {
+				continue
+			}
+			parent[s.Index] = cur
+			queue = append(queue, s.Index)
+		}
+	}
+	return []int{to}
+}
+
+func blockSet(blocks []int) map[int]bool {
+	set := make(map[int]bool, len(blocks))
+	for _, b := range blocks {
+		set[b] = true
+	}
+	return set
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// naturalLoop is the set of blocks of one natural loop of a function's CFG.
+// Loops that share a header are merged, as usual.
+type naturalLoop struct {
+	header  int
+	latches []int
+	body    map[int]bool
+	exits   []int
+	parent  int // index into the function's loop slice, -1 for outermost
+	depth   int
+}
+
+// ExtractLoops identifies natural loops from back edges of the SSA CFG
+// (an edge u→h where h dominates u) and emits one loop node per header,
+// with its latches, exit blocks and nesting depth. in_loop edges link each
+// basic_block and AST node to its innermost loop, and each nested loop to
+// the loop that encloses it; loop_stmt links a loop to the for/range
+// statement it came from.
+//
+// Must run after ExtractCFGAndDFG, which creates the basic_block nodes.
+func ExtractLoops(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Extracting natural loops...")
+
+	var loopNodes, blockEdges, stmtEdges, nestEdges int
+
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) < 2 {
+			continue
+		}
+		loops := naturalLoops(fn.Blocks)
+		if len(loops) == 0 {
+			continue
+		}
+		funcNodeID := ssaFuncNodeID(fn, fset, funcLookup)
+		if funcNodeID == "" {
+			continue
+		}
+		relPkg := modSet.RelPkg(fn.Pkg.Pkg.Path())
+		stmts := loopStmts(fn)
+
+		loopIDs := make([]string, len(loops))
+		for i, l := range loops {
+			loopIDs[i] = LoopID(funcNodeID, l.header)
+		}
+
+		for i, l := range loops {
+			header := fn.Blocks[l.header]
+			line, col, file := blockPos(header, fset)
+			kind := "goto"
+			var stmtID string
+			if s := enclosingLoopStmt(fn, l, stmts); s != nil {
+				// The AST visitor keys range statements at the range keyword.
+				kind = "for"
+				pos := s.Pos()
+				if rs, ok := s.(*ast.RangeStmt); ok {
+					kind = "range"
+					pos = rs.Range
+				}
+				p := fset.Position(pos)
+				if rel := modSet.RelFile(p.Filename); rel != "" {
+					file, line, col = rel, p.Line, p.Column
+					stmtID = posLookup.Get(rel, p.Line, p.Column)
+				}
+			}
+
+			latches := make([]string, len(l.latches))
+			for k, b := range l.latches {
+				latches[k] = BlockID(funcNodeID, b)
+			}
+			exits := make([]string, len(l.exits))
+			for k, b := range l.exits {
+				exits[k] = BlockID(funcNodeID, b)
+			}
+			props := map[string]any{
+				"header":     BlockID(funcNodeID, l.header),
+				"latches":    latches,
+				"exits":      exits,
+				"depth":      l.depth,
+				"num_blocks": len(l.body),
+				"loop_kind":  kind,
+			}
+			if l.parent >= 0 {
+				props["parent_loop"] = loopIDs[l.parent]
+			}
+			name := header.Comment
+			if name == "" {
+				name = "loop"
+			}
+			cpg.AddNode(Node{
+				ID:             loopIDs[i],
+				Kind:           "loop",
+				Name:           name,
+				File:           file,
+				Line:           line,
+				Col:            col,
+				Package:        relPkg,
+				ParentFunction: funcNodeID,
+				Properties:     props,
+			})
+			loopNodes++
+
+			if l.parent >= 0 {
+				cpg.AddEdge(Edge{Source: loopIDs[i], Target: loopIDs[l.parent], Kind: "in_loop"})
+				nestEdges++
+			}
+			if stmtID != "" {
+				cpg.AddEdge(Edge{Source: loopIDs[i], Target: stmtID, Kind: "loop_stmt"})
+			}
+		}
+
+		// Innermost loop per block: the deepest loop whose body holds it.
+		innermost := make([]int, len(fn.Blocks))
+		for b := range innermost {
+			innermost[b] = -1
+			for i, l := range loops {
+				if l.body[b] && (innermost[b] < 0 || l.depth > loops[innermost[b]].depth) {
+					innermost[b] = i
+				}
+			}
+		}
+
+		// An AST node can span several blocks (a for statement's condition
+		// sits in the header, its post statement in the latch); it belongs
+		// to the deepest loop among them.
+		stmtLoop := make(map[string]int)
+		var stmtOrder []string
+		for b, block := range fn.Blocks {
+			li := innermost[b]
+			if li < 0 {
+				continue
+			}
+			cpg.AddEdge(Edge{Source: BlockID(funcNodeID, b), Target: loopIDs[li], Kind: "in_loop"})
+			blockEdges++
+			for _, instr := range block.Instrs {
+				if _, ok := instr.(*ssa.Phi); ok {
+					continue // positioned at the variable's declaration
+				}
+				file, line, col := instrPos(instr, fset)
+				if file == "" {
+					continue
+				}
+				id := posLookup.Get(file, line, col)
+				if id == "" {
+					continue
+				}
+				prev, seen := stmtLoop[id]
+				if !seen {
+					stmtOrder = append(stmtOrder, id)
+				}
+				if !seen || loops[li].depth > loops[prev].depth {
+					stmtLoop[id] = li
+				}
+			}
+		}
+		for _, id := range stmtOrder {
+			cpg.AddEdge(Edge{Source: id, Target: loopIDs[stmtLoop[id]], Kind: "in_loop"})
+			stmtEdges++
+		}
+	}
+
+	prog.Log("Created %d loop nodes, %d block, %d statement, %d nesting in_loop edges",
+		loopNodes, blockEdges, stmtEdges, nestEdges)
+}
+
+// naturalLoops returns the natural loops of a CFG ordered by header index,
+// with parent and depth filled in. The body of the loop headed by h is h
+// plus every block that reaches one of its latches without passing h.
+func naturalLoops(blocks []*ssa.BasicBlock) []*naturalLoop {
+	byHeader := make(map[int]*naturalLoop)
+	for _, b := range blocks {
+		for _, s := range b.Succs {
+			if !s.Dominates(b) {
+				continue
+			}
+			l := byHeader[s.Index]
+			if l == nil {
+				l = &naturalLoop{header: s.Index, body: map[int]bool{s.Index: true}, parent: -1}
+				byHeader[s.Index] = l
+			}
+			l.latches = append(l.latches, b.Index)
+			stack := []int{b.Index}
+			for len(stack) > 0 {
+				x := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if l.body[x] {
+					continue
+				}
+				l.body[x] = true
+				for _, p := range blocks[x].Preds {
+					stack = append(stack, p.Index)
+				}
+			}
+		}
+	}
+	if len(byHeader) == 0 {
+		return nil
+	}
+
+	loops := make([]*naturalLoop, 0, len(byHeader))
+	for _, l := range byHeader {
+		loops = append(loops, l)
+	}
+	sort.Slice(loops, func(i, j int) bool { return loops[i].header < loops[j].header })
+
+	for _, l := range loops {
+		exitSet := make(map[int]bool)
+		for b := range l.body {
+			for _, s := range blocks[b].Succs {
+				if !l.body[s.Index] {
+					exitSet[s.Index] = true
+				}
+			}
+		}
+		for b := range exitSet {
+			l.exits = append(l.exits, b)
+		}
+		sort.Ints(l.exits)
+		sort.Ints(l.latches)
+	}
+
+	// Parent: the smallest other loop whose body holds this header.
+	for i, l := range loops {
+		for j, m := range loops {
+			if i == j || !m.body[l.header] {
+				continue
+			}
+			if l.parent < 0 || len(m.body) < len(loops[l.parent].body) {
+				l.parent = j
+			}
+		}
+	}
+	for _, l := range loops {
+		for p := l.parent; p >= 0; p = loops[p].parent {
+			l.depth++
+		}
+		l.depth++
+	}
+	return loops
+}
+
+// loopStmts returns the for and range statements in a function's syntax,
+// including those of nested function literals.
+func loopStmts(fn *ssa.Function) []ast.Stmt {
+	syntax := fn.Syntax()
+	if syntax == nil {
+		return nil
+	}
+	var stmts []ast.Stmt
+	ast.Inspect(syntax, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.ForStmt:
+			stmts = append(stmts, s)
+		case *ast.RangeStmt:
+			stmts = append(stmts, s)
+		}
+		return true
+	})
+	return stmts
+}
+
+// enclosingLoopStmt returns the smallest for/range statement that spans
+// every positioned instruction of the loop, or nil for loops built from
+// goto or when no instruction carries a position.
+func enclosingLoopStmt(fn *ssa.Function, l *naturalLoop, stmts []ast.Stmt) ast.Stmt {
+	lo, hi := token.NoPos, token.NoPos
+	for b := range l.body {
+		for _, instr := range fn.Blocks[b].Instrs {
+			if _, ok := instr.(*ssa.Phi); ok {
+				continue
+			}
+			p := instr.Pos()
+			if !p.IsValid() {
+				continue
+			}
+			if lo == token.NoPos || p < lo {
+				lo = p
+			}
+			if p > hi {
+				hi = p
+			}
+		}
+	}
+	if lo == token.NoPos {
+		return nil
+	}
+	var best ast.Stmt
+	for _, s := range stmts {
+		if s.Pos() > lo || s.End() <= hi {
+			continue
+		}
+		if best == nil || s.End()-s.Pos() < best.End()-best.Pos() {
+			best = s
+		}
+	}
+	return best
+}
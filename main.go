@@ -24,6 +24,11 @@ func run() error {
 	skipTests := flag.Bool("skip-tests", true, "Skip _test.go files")
 	verbose := flag.Bool("verbose", false, "Print detailed progress")
 	validate := flag.Bool("validate", false, "Run validation queries after write")
+	ssaInstrs := flag.Bool("ssa-instrs", false, "Emit ssa_instr nodes for every SSA instruction (large output)")
+	grpc := flag.Bool("grpc", false, "Model gRPC services: parse .proto files, keep .pb.go declarations as generated nodes and link servers and clients")
+	reachTests := flag.Bool("reach-tests", false, "Treat Test/Benchmark/Fuzz/Example functions as reachability entry points (implies -skip-tests=false)")
+	errorAllowlist := flag.String("error-allowlist", "", "Comma-separated extra callees whose error results may go unchecked (e.g. (*os.File).Close,io.Copy)")
+	tags := flag.String("tags", "", "Comma-separated build tags for package loading and escape analysis")
 	modules := flag.String("modules", "", "Comma-separated dir:modpath:name triples for additional modules (e.g. ./adapter:sigs.k8s.io/prometheus-adapter:adapter)")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: cpg-gen [flags] <primary-dir> <output.db>\n\n")
@@ -50,6 +55,20 @@ func run() error {
 	// Wire skip flags into the package-level config used by shouldSkipFile
 	flagSkipGenerated = *skipGenerated
 	flagSkipTests = *skipTests
+	flagBuildTags = *tags
+	flagReachTests = *reachTests
+	flagGRPC = *grpc
+	flagSSAInstrs = *ssaInstrs
+	if *errorAllowlist != "" {
+		for _, name := range strings.Split(*errorAllowlist, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				flagErrorAllowlist = append(flagErrorAllowlist, name)
+			}
+		}
+	}
+	if flagReachTests {
+		flagSkipTests = false
+	}
 
 	prog := NewProgress(*verbose)
 
@@ -103,6 +122,11 @@ func run() error {
 	// Phase 2: Walk AST → nodes + AST edges + position lookup
 	posLookup, funcLookup := WalkAST(loadResult.Packages, loadResult.Fset, cpg, prog)
 
+	// Phase 2b: Optional protobuf definitions and generated declarations
+	if flagGRPC {
+		ModelProtobuf(loadResult.Packages, loadResult.Fset, posLookup, cpg, prog)
+	}
+
 	// Phase 3: Build SSA
 	ssaResult := BuildSSA(loadResult.Packages, prog)
 
@@ -112,15 +136,76 @@ func run() error {
 	// Phase 4b: Extract CDG from post-dominator tree
 	ExtractCDG(ssaResult, loadResult.Fset, funcLookup, cpg, prog)
 
+	// Phase 4b2: Natural loops from back edges of the dominator tree
+	ExtractLoops(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
 	// Phase 4c: Extract channel send→receive flow edges
-	ExtractChannelFlow(ssaResult, loadResult.Fset, posLookup, cpg, prog)
+	ExtractChannelFlow(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
 
 	// Phase 4d: Extract panic/recover flow edges
 	ExtractPanicRecover(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
 
+	// Phase 4e: Optional instruction-level SSA nodes
+	if flagSSAInstrs {
+		ExtractSSAInstructions(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+	}
+
+	// Phase 4f: Constant string values of call arguments
+	PropagateConstStrings(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 4g: Prometheus metric definitions, registrations and uses
+	BuildMetricsCatalog(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 4h: HTTP route registrations → http_route nodes
+	DiscoverHTTPRoutes(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 4i: HTTP client calls matched to routes → remote_call edges
+	LinkRemoteCalls(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 4j: gRPC implementations, registrations and client stub calls
+	if flagGRPC {
+		LinkGRPCServices(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+	}
+
 	// Phase 5: Build VTA call graph → call edges
 	BuildCallGraph(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
 
+	// Phase 5b: Entry-point reachability over call + reference edges
+	ComputeReachability(ssaResult, loadResult.Fset, funcLookup, cpg, prog)
+
+	// Phase 5c: Goroutine spawn tree and leak candidates
+	AnalyzeGoroutines(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5d: Inter-procedural panic propagation and recover coverage
+	ComputePanicPropagation(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5e: Lock/unlock pairing, double locks and blocking under lock
+	AnalyzeLockPairing(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5f: Whole-program lock acquisition order graph
+	BuildLockOrder(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5g: Data race candidates around go statements
+	AnalyzeRaces(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5h: Guarded-by inference for struct fields next to a mutex
+	InferGuardedBy(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5i: Context propagation: dropped contexts, uncancellable goroutines
+	AnalyzeContextPropagation(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5j: Resource lifetimes: files, bodies, connections left open
+	AnalyzeResources(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5k: Errors assigned to _, dropped or overwritten unchecked
+	AnalyzeUncheckedErrors(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5l: Possibly nil values dereferenced without a nil check
+	AnalyzeNilDerefs(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
+	// Phase 5m: Reflection, unsafe, cgo and linkname: where the call graph is unsound
+	AnalyzeUnsoundness(ssaResult, loadResult.Fset, posLookup, funcLookup, cpg, prog)
+
 	// Phase 6: Extract type relationships (implements, embeds)
 	ExtractTypeRelationships(loadResult.Packages, loadResult.Fset, posLookup, cpg, prog)
 
@@ -144,14 +229,16 @@ func run() error {
 		},
 	})
 
-	// Phase 7c: Escape analysis from Go compiler (all modules)
-	escapeResults := RunEscapeAnalysis(prog)
+	// Phase 7c: Escape analysis, inlining and bounds checks from Go compiler (all modules)
+	escapeRun := RunEscapeAnalysis(goworkPath, posLookup, funcLookup, prog)
+	AttachEscapeFlows(escapeRun.Results, posLookup, cpg, prog)
+	AttachOptimizations(escapeRun.Results, posLookup, funcLookup, cpg, prog)
 
 	// Phase 7d: Git history for diff-aware analysis (all modules)
 	gitHistory := RunGitHistory(prog)
 
 	// Phase 8: Write SQLite
-	if err := WriteDB(outputPath, cpg, escapeResults, gitHistory, *validate, prog); err != nil {
+	if err := WriteDB(outputPath, cpg, escapeRun, gitHistory, *validate, prog); err != nil {
 		return err
 	}
 
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// client_golang packages holding the metric constructors.
+const (
+	promPkg     = "github.com/prometheus/client_golang/prometheus"
+	promautoPkg = "github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricConstructors maps client_golang constructors (package functions
+// and promauto.Factory methods alike) to the metric type they create.
+var metricConstructors = map[string]string{
+	"NewCounter":      "counter",
+	"NewCounterVec":   "counter",
+	"NewCounterFunc":  "counter",
+	"NewGauge":        "gauge",
+	"NewGaugeVec":     "gauge",
+	"NewGaugeFunc":    "gauge",
+	"NewHistogram":    "histogram",
+	"NewHistogramVec": "histogram",
+	"NewSummary":      "summary",
+	"NewSummaryVec":   "summary",
+	"NewUntypedFunc":  "untyped",
+}
+
+// metricOps are the metric methods whose call sites are recorded as uses.
+var metricOps = map[string]bool{
+	"Inc":              true,
+	"Dec":              true,
+	"Add":              true,
+	"Sub":              true,
+	"Set":              true,
+	"SetToCurrentTime": true,
+	"Observe":          true,
+	"WithLabelValues":  true,
+	"With":             true,
+}
+
+// metricChildOps select a child (or curried vector) of a metric vector.
+var metricChildOps = map[string]bool{
+	"WithLabelValues":          true,
+	"With":                     true,
+	"GetMetricWithLabelValues": true,
+	"GetMetricWith":            true,
+	"CurryWith":                true,
+	"MustCurryWith":            true,
+}
+
+// metricDef is one metric constructor call.
+type metricDef struct {
+	call        *ssa.Call
+	id          string
+	typ         string
+	vec         bool
+	auto        bool // created through promauto, registered on creation
+	constructor string
+	fqName      string // namespace_subsystem_name, "" when not constant
+	namespace   string
+	subsystem   string
+	name        string
+	help        string
+	labels      []string
+	labelsKnown bool
+	variable    string
+	site        string
+	file        string
+	line, col   int
+	pkg         string
+	registered  []string
+	sites       []string // constructor call IDs of every definition merged into this one
+}
+
+// BuildMetricsCatalog finds client_golang metric constructors
+// (prometheus.NewCounter / NewGaugeVec / ..., promauto.New* and
+// promauto.With(reg).New*) and resolves their *Opts composite literal
+// fields (Namespace, Subsystem, Name, Help) and label names through
+// constant propagation into a metric node named namespace_subsystem_name.
+//
+// The metric is tied to the package variable or struct field the
+// constructor result is stored in, so that registrations (Register /
+// MustRegister, including variadic calls) and uses (Inc, Add, Set,
+// Observe, WithLabelValues, ... also through WithLabelValues(...).Inc()
+// chains) of that variable or field anywhere in the program are linked to
+// it. Constructors building the same full name are one metric. Emits
+// metric nodes, defines_metric edges from the constructor call,
+// registers_metric edges from registration calls and metric_use edges
+// from use sites with the method and constant label values.
+func BuildMetricsCatalog(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Building metrics catalog...")
+
+	funcs := knownFuncs(ssaResult)
+	r := newConstResolver(funcs)
+
+	siteOf := func(instr ssa.Instruction) (string, string, int, int) {
+		file, line, col := instrPos(instr, fset)
+		if file == "" {
+			return "", "", 0, 0
+		}
+		return posLookup.Get(file, line, col), file, line, col
+	}
+
+	byCall := make(map[*ssa.Call]*metricDef)
+	var defs []*metricDef
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				def := metricConstructor(call, r)
+				if def == nil {
+					continue
+				}
+				def.site, def.file, def.line, def.col = siteOf(call)
+				if def.file == "" {
+					continue
+				}
+				def.pkg = modSet.RelPkg(fn.Pkg.Pkg.Path())
+				if def.fqName != "" {
+					def.id = MetricID(def.fqName)
+				} else {
+					def.id = MetricID(fmt.Sprintf("@%s:%d:%d", def.file, def.line, def.col))
+				}
+				if def.auto {
+					def.registered = append(def.registered, def.site)
+				}
+				byCall[call] = def
+				defs = append(defs, def)
+			}
+		}
+	}
+
+	// Variables and fields the metrics are stored in.
+	byObj := make(map[types.Object]*metricDef)
+	for _, def := range defs {
+		for _, v := range metricAliases(def.call) {
+			for _, ref := range *v.Referrers() {
+				st, ok := ref.(*ssa.Store)
+				if !ok || st.Val != v {
+					continue
+				}
+				switch addr := st.Addr.(type) {
+				case *ssa.Global:
+					def.variable = modSet.RelPkg(addr.Pkg.Pkg.Path()) + "." + addr.Name()
+					byObj[addr.Object()] = def
+				case *ssa.FieldAddr:
+					if fv := fieldVar(addr.X.Type(), addr.Field); fv != nil {
+						def.variable = typeShortName(deref(addr.X.Type())) + "." + fv.Name()
+						byObj[fv] = def
+					}
+				}
+			}
+		}
+	}
+
+	// metricOf maps a value to the metric it holds.
+	metricOf := func(v ssa.Value) *metricDef {
+		for {
+			switch x := v.(type) {
+			case *ssa.MakeInterface:
+				v = x.X
+				continue
+			case *ssa.ChangeInterface:
+				v = x.X
+				continue
+			case *ssa.ChangeType:
+				v = x.X
+				continue
+			case *ssa.TypeAssert:
+				v = x.X
+				continue
+			case *ssa.Call:
+				return byCall[x]
+			case *ssa.UnOp:
+				if x.Op != token.MUL {
+					return nil
+				}
+				switch addr := x.X.(type) {
+				case *ssa.Global:
+					return byObj[addr.Object()]
+				case *ssa.FieldAddr:
+					if fv := fieldVar(addr.X.Type(), addr.Field); fv != nil {
+						return byObj[fv]
+					}
+				}
+			}
+			return nil
+		}
+	}
+
+	var regEdges, useEdges int
+	for _, fn := range funcs {
+		fnID := ""
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				common := call.Common()
+				method := metricMethodName(common)
+				switch {
+				case method == "Register" || method == "MustRegister":
+					site, _, _, _ := siteOf(call)
+					if site == "" {
+						continue
+					}
+					for _, arg := range callArgsByIndex(common) {
+						def := metricOf(arg)
+						if def == nil {
+							continue
+						}
+						def.registered = append(def.registered, site)
+						cpg.AddEdge(Edge{Source: site, Target: def.id, Kind: "registers_metric"})
+						regEdges++
+					}
+				case metricOps[method]:
+					recv := metricReceiver(common)
+					if recv == nil {
+						continue
+					}
+					var labelValues []string
+					for {
+						parent, ok := recv.(*ssa.Call)
+						if !ok || byCall[parent] != nil || !metricChildOps[metricMethodName(&parent.Call)] {
+							break
+						}
+						labelValues = append(metricLabelValues(&parent.Call, r), labelValues...)
+						recv = metricReceiver(&parent.Call)
+						if recv == nil {
+							break
+						}
+					}
+					if recv == nil {
+						continue
+					}
+					def := metricOf(recv)
+					if def == nil {
+						continue
+					}
+					if metricChildOps[method] {
+						labelValues = append(labelValues, metricLabelValues(common, r)...)
+					}
+					site, _, _, _ := siteOf(call)
+					if site == "" {
+						continue
+					}
+					if fnID == "" {
+						fnID = ssaFuncNodeID(fn, fset, funcLookup)
+					}
+					if labelValues == nil {
+						labelValues = []string{}
+					}
+					cpg.AddEdge(Edge{
+						Source: site, Target: def.id, Kind: "metric_use",
+						Properties: map[string]any{
+							"method":       method,
+							"function":     fnID,
+							"label_values": labelValues,
+						},
+					})
+					useEdges++
+				}
+			}
+		}
+	}
+
+	var resolved int
+	defs = mergeMetricDefs(defs)
+	for _, def := range defs {
+		labels := def.labels
+		if labels == nil {
+			labels = []string{}
+		}
+		regs := def.registered
+		if regs == nil {
+			regs = []string{}
+		}
+		name := def.fqName
+		if name == "" {
+			name = def.variable
+		}
+		cpg.AddNode(Node{
+			ID:       def.id,
+			Kind:     "metric",
+			Name:     name,
+			Package:  def.pkg,
+			File:     def.file,
+			Line:     def.line,
+			Col:      def.col,
+			TypeInfo: def.typ,
+			Properties: map[string]any{
+				"metric_name":     def.fqName,
+				"metric_type":     def.typ,
+				"vec":             def.vec,
+				"namespace":       def.namespace,
+				"subsystem":       def.subsystem,
+				"short_name":      def.name,
+				"help":            def.help,
+				"labels":          labels,
+				"labels_resolved": def.labelsKnown,
+				"variable":        def.variable,
+				"constructor":     def.constructor,
+				"auto_registered": def.auto,
+				"registrations":   regs,
+			},
+		})
+		for _, site := range def.sites {
+			cpg.AddEdge(Edge{Source: site, Target: def.id, Kind: "defines_metric"})
+		}
+		if def.fqName != "" {
+			resolved++
+		}
+	}
+
+	prog.Log("Metrics catalog: %d metrics (%d named), %d registrations, %d uses", len(defs), resolved, regEdges, useEdges)
+}
+
+// mergeMetricDefs merges constructor calls building the same metric (one
+// ID) into the first of them: the variables holding it, its registrations
+// and all definition sites are combined, and it is auto-registered when
+// any definition is.
+func mergeMetricDefs(defs []*metricDef) []*metricDef {
+	byID := make(map[string]*metricDef)
+	variables := make(map[string][]string)
+	var out []*metricDef
+	for _, def := range defs {
+		m, ok := byID[def.id]
+		if !ok {
+			c := *def
+			c.registered = nil
+			m = &c
+			byID[def.id] = m
+			out = append(out, m)
+		}
+		m.sites = append(m.sites, def.site)
+		m.registered = append(m.registered, def.registered...)
+		m.auto = m.auto || def.auto
+		if def.variable != "" {
+			variables[def.id] = append(variables[def.id], def.variable)
+		}
+	}
+	for _, m := range out {
+		vars := slices.Compact(slices.Sorted(slices.Values(variables[m.id])))
+		m.variable = strings.Join(vars, ", ")
+		m.registered = slices.Compact(slices.Sorted(slices.Values(m.registered)))
+	}
+	return out
+}
+
+// metricConstructor recognizes a client_golang metric constructor call and
+// resolves its options and label names.
+func metricConstructor(call *ssa.Call, r *constResolver) *metricDef {
+	callee := call.Call.StaticCallee()
+	if callee == nil || callee.Pkg == nil {
+		return nil
+	}
+	path := callee.Pkg.Pkg.Path()
+	if path != promPkg && path != promautoPkg {
+		return nil
+	}
+	typ, ok := metricConstructors[callee.Name()]
+	if !ok {
+		return nil
+	}
+	def := &metricDef{
+		call:        call,
+		typ:         typ,
+		vec:         strings.HasSuffix(callee.Name(), "Vec"),
+		auto:        path == promautoPkg,
+		constructor: calleeName(&call.Call),
+	}
+	args := call.Call.Args
+	if callee.Signature.Recv() != nil && len(args) > 0 {
+		args = args[1:] // promauto.Factory receiver
+	}
+	if len(args) == 0 {
+		return def
+	}
+
+	single := func(v ssa.Value) string {
+		if v == nil {
+			return ""
+		}
+		if vals := r.strings(v); len(vals) == 1 {
+			return vals[0]
+		}
+		return ""
+	}
+	fields := optsFields(args[0])
+	def.namespace = single(fields["Namespace"])
+	def.subsystem = single(fields["Subsystem"])
+	def.name = single(fields["Name"])
+	def.help = single(fields["Help"])
+	if def.name != "" {
+		var parts []string
+		for _, p := range []string{def.namespace, def.subsystem, def.name} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		def.fqName = strings.Join(parts, "_")
+	}
+
+	if def.vec && len(args) > 1 {
+		if elems, ok := sliceElems(args[1]); ok {
+			def.labelsKnown = true
+			for k := 0; k < len(elems); k++ {
+				l := single(elems[k])
+				if l == "" {
+					def.labelsKnown = false
+					break
+				}
+				def.labels = append(def.labels, l)
+			}
+		}
+	}
+	return def
+}
+
+// optsFields returns the values stored into the fields of an *Opts
+// composite literal, by field name. Fields not set resolve to nil.
+func optsFields(v ssa.Value) map[string]ssa.Value {
+	fields := make(map[string]ssa.Value)
+	for {
+		ct, ok := v.(*ssa.ChangeType)
+		if !ok {
+			break
+		}
+		v = ct.X
+	}
+	load, ok := v.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL {
+		return fields
+	}
+	alloc, ok := load.X.(*ssa.Alloc)
+	if !ok || alloc.Referrers() == nil {
+		return fields
+	}
+	for _, ref := range *alloc.Referrers() {
+		fa, ok := ref.(*ssa.FieldAddr)
+		if !ok || fa.Referrers() == nil {
+			continue
+		}
+		for _, use := range *fa.Referrers() {
+			if st, ok := use.(*ssa.Store); ok && st.Addr == fa {
+				fields[structFieldName(deref(alloc.Type()), fa.Field)] = st.Val
+			}
+		}
+	}
+	return fields
+}
+
+// metricAliases returns v and the conversions of it to interfaces or
+// named types.
+func metricAliases(v ssa.Value) []ssa.Value {
+	out := []ssa.Value{v}
+	for i := 0; i < len(out); i++ {
+		if out[i].Referrers() == nil {
+			continue
+		}
+		for _, ref := range *out[i].Referrers() {
+			switch c := ref.(type) {
+			case *ssa.MakeInterface:
+				out = append(out, c)
+			case *ssa.ChangeInterface:
+				out = append(out, c)
+			case *ssa.ChangeType:
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// metricMethodName returns the method a call invokes, or "" for plain
+// function calls.
+func metricMethodName(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		return common.Method.Name()
+	}
+	callee := common.StaticCallee()
+	if callee == nil {
+		return ""
+	}
+	if callee.Signature.Recv() != nil || (callee.Pkg != nil && callee.Pkg.Pkg.Path() == promPkg) {
+		return callee.Name() // methods, and prometheus.MustRegister / Register
+	}
+	return ""
+}
+
+// metricReceiver returns the receiver of a method call.
+func metricReceiver(common *ssa.CallCommon) ssa.Value {
+	if common.IsInvoke() {
+		return common.Value
+	}
+	if callee := common.StaticCallee(); callee != nil && callee.Signature.Recv() != nil && len(common.Args) > 0 {
+		return common.Args[0]
+	}
+	return nil
+}
+
+// metricLabelValues resolves the constant label values passed to
+// WithLabelValues (one entry per argument, "" when not constant).
+func metricLabelValues(common *ssa.CallCommon, r *constResolver) []string {
+	if metricMethodName(common) != "WithLabelValues" && metricMethodName(common) != "GetMetricWithLabelValues" {
+		return nil
+	}
+	args := callArgsByIndex(common)
+	keys := make([]int, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	values := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := ""
+		if vals := r.strings(args[k]); len(vals) == 1 {
+			v = vals[0]
+		}
+		values = append(values, v)
+	}
+	return values
+}
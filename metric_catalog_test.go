@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeMetricDefs(t *testing.T) {
+	defs := []*metricDef{
+		{id: "metric::up", fqName: "up", typ: "gauge", site: "c1", variable: "pkg.up", registered: []string{"r1"}},
+		{id: "metric::other", fqName: "other", typ: "counter", site: "c2", variable: "pkg.other"},
+		{id: "metric::up", fqName: "up", typ: "gauge", site: "c3", variable: "T.up", registered: []string{"r2", "c3"}, auto: true},
+		{id: "metric::up", fqName: "up", typ: "gauge", site: "c4", variable: "pkg.up", registered: []string{"r1"}},
+	}
+	got := mergeMetricDefs(defs)
+	if len(got) != 2 {
+		t.Fatalf("got %d metrics, want 2", len(got))
+	}
+	up := got[0]
+	if up.id != "metric::up" {
+		t.Fatalf("first metric = %s, want metric::up", up.id)
+	}
+	if want := []string{"c1", "c3", "c4"}; !reflect.DeepEqual(up.sites, want) {
+		t.Errorf("sites = %v, want %v", up.sites, want)
+	}
+	if want := []string{"c3", "r1", "r2"}; !reflect.DeepEqual(up.registered, want) {
+		t.Errorf("registered = %v, want %v", up.registered, want)
+	}
+	if want := "T.up, pkg.up"; up.variable != want {
+		t.Errorf("variable = %q, want %q", up.variable, want)
+	}
+	if !up.auto {
+		t.Errorf("auto = false, want true")
+	}
+	if other := got[1]; other.variable != "pkg.other" || !reflect.DeepEqual(other.sites, []string{"c2"}) {
+		t.Errorf("other = %+v", other)
+	}
+	if len(defs[0].registered) != 1 {
+		t.Errorf("merge modified its input: %v", defs[0].registered)
+	}
+}
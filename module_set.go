@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -45,6 +46,23 @@ func (ms *ModuleSet) IsKnownPkg(pkgPath string) bool {
 	return false
 }
 
+// ModuleFor returns the module that owns pkgPath, preferring the longest
+// matching ModPath when module paths are nested.
+func (ms *ModuleSet) ModuleFor(pkgPath string) (ModuleInfo, bool) {
+	var best ModuleInfo
+	found := false
+	for _, m := range ms.modules {
+		if pkgPath != m.ModPath && !strings.HasPrefix(pkgPath, m.ModPath+"/") {
+			continue
+		}
+		if !found || len(m.ModPath) > len(best.ModPath) {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
 // RelPkg strips the module prefix from a full import path and prepends the
 // module's Prefix. Prometheus (Prefix:"") yields "scrape"; adapter (Prefix:"adapter")
 // yields "adapter/pkg/client".
@@ -118,6 +136,40 @@ func (ms *ModuleSet) RelFile(absPath string) string {
 	return bestPrefix + "/" + bestRel
 }
 
+// Component names the module owning pkgPath as a communicating component:
+// its Prefix, or the last element of its module path for the primary
+// module ("prometheus"), skipping a major version suffix (example.com/foo/v2
+// is "foo"). Returns "" for packages outside the set.
+func (ms *ModuleSet) Component(pkgPath string) string {
+	m, ok := ms.ModuleFor(pkgPath)
+	if !ok {
+		return ""
+	}
+	if m.Prefix != "" {
+		return m.Prefix
+	}
+	modPath := m.ModPath
+	if dir, last := path.Split(modPath); dir != "" && isMajorVersion(last) {
+		modPath = strings.TrimSuffix(dir, "/")
+	}
+	return path.Base(modPath)
+}
+
+// isMajorVersion reports whether a module path element is a major version
+// suffix: "v" followed by a number of at least 2 without leading zeros.
+func isMajorVersion(elem string) bool {
+	n, ok := strings.CutPrefix(elem, "v")
+	if !ok || len(n) == 0 || n[0] == '0' || n == "1" {
+		return false
+	}
+	for _, c := range n {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // PrimaryDir returns the first (primary) module's directory.
 func (ms *ModuleSet) PrimaryDir() string {
 	return ms.modules[0].Dir
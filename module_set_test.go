@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestModuleSetComponent(t *testing.T) {
+	tests := []struct {
+		modPath string
+		want    string
+	}{
+		{"github.com/prometheus/prometheus", "prometheus"},
+		{"example.com/foo/v2", "foo"},
+		{"example.com/foo/v10", "foo"},
+		{"example.com/foo/v1", "v1"},   // not a major version suffix
+		{"example.com/foo/v02", "v02"}, // leading zero
+		{"example.com/foo/vx", "vx"},
+		{"v2", "v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.modPath, func(t *testing.T) {
+			ms := NewModuleSet(ModuleInfo{ModPath: tt.modPath, Dir: "/src/" + tt.modPath}, nil)
+			if got := ms.Component(tt.modPath + "/pkg"); got != tt.want {
+				t.Errorf("Component = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	ms := NewModuleSet(
+		ModuleInfo{ModPath: "example.com/app/v3", Dir: "/src/app"},
+		[]ModuleInfo{{ModPath: "example.com/adapter/v2", Dir: "/src/adapter", Prefix: "adapter"}},
+	)
+	if got := ms.Component("example.com/adapter/v2/x"); got != "adapter" {
+		t.Errorf("Component(prefixed) = %q, want %q", got, "adapter")
+	}
+	if got := ms.Component("example.org/other"); got != "" {
+		t.Errorf("Component(unknown) = %q, want \"\"", got)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// apiUse accumulates the uses of one provider symbol from one consumer
+// function (or file, for package-level initializers).
+type apiUse struct {
+	refs      int
+	calls     int
+	callSites []string
+}
+
+// emitCrossModuleUses emits api_use edges from consumer functions to the
+// exported declarations of other analyzed modules they reference: types,
+// functions, methods, fields, variables and constants. Each edge carries
+// the reference and call counts and the call sites. Runs after all
+// packages are walked so defLookup resolves declarations in any module.
+func emitCrossModuleUses(pkgs []*packages.Package, fset *token.FileSet, defLookup *DefLookup, funcLookup *FuncLookup, cpg *CPG) int {
+	type useKey struct{ source, target string }
+	uses := make(map[useKey]*apiUse)
+	modules := make(map[useKey][2]string) // consumer, provider component
+
+	for _, pkg := range pkgs {
+		consumer := modSet.Component(pkg.PkgPath)
+		if consumer == "" || pkg.TypesInfo == nil {
+			continue
+		}
+		for i, file := range pkg.Syntax {
+			if i >= len(pkg.CompiledGoFiles) {
+				continue
+			}
+			relFile := modSet.RelFile(pkg.CompiledGoFiles[i])
+			if relFile == "" || shouldSkipFile(relFile) {
+				continue
+			}
+			for _, decl := range file.Decls {
+				source := FileID(relFile)
+				if fd, ok := decl.(*ast.FuncDecl); ok {
+					p := fset.Position(fd.Pos())
+					if id := funcLookup.Get(relFile, p.Line, p.Column); id != "" {
+						source = id
+					}
+				}
+				callees := make(map[*ast.Ident]bool)
+				ast.Inspect(decl, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.CallExpr:
+						switch fun := ast.Unparen(n.Fun).(type) {
+						case *ast.Ident:
+							callees[fun] = true
+						case *ast.SelectorExpr:
+							callees[fun.Sel] = true
+						case *ast.IndexExpr: // generic instantiation
+							if sel, ok := fun.X.(*ast.SelectorExpr); ok {
+								callees[sel.Sel] = true
+							}
+						}
+					case *ast.Ident:
+						obj := apiObject(pkg.TypesInfo.Uses[n])
+						if obj == nil {
+							return true
+						}
+						provider := modSet.Component(obj.Pkg().Path())
+						if provider == "" || provider == consumer {
+							return true
+						}
+						target := defLookup.Get(obj)
+						if target == "" {
+							return true
+						}
+						k := useKey{source, target}
+						u := uses[k]
+						if u == nil {
+							u = &apiUse{}
+							uses[k] = u
+							modules[k] = [2]string{consumer, provider}
+						}
+						u.refs++
+						if callees[n] {
+							p := fset.Position(n.Pos())
+							u.calls++
+							u.callSites = append(u.callSites, fmt.Sprintf("%s:%d", relFile, p.Line))
+						}
+					}
+					return true
+				})
+			}
+		}
+	}
+
+	keys := make([]useKey, 0, len(uses))
+	for k := range uses {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].source != keys[j].source {
+			return keys[i].source < keys[j].source
+		}
+		return keys[i].target < keys[j].target
+	})
+	for _, k := range keys {
+		u := uses[k]
+		cpg.AddEdge(Edge{
+			Source: k.source,
+			Target: k.target,
+			Kind:   "api_use",
+			Properties: map[string]any{
+				"consumer_module": modules[k][0],
+				"provider_module": modules[k][1],
+				"refs":            u.refs,
+				"calls":           u.calls,
+				"call_sites":      append([]string{}, u.callSites...),
+			},
+		})
+	}
+	return len(keys)
+}
+
+// apiObject returns the declaration an identifier use refers to when it is
+// an exported package-level object, method or field, or nil. Uses of
+// generic instantiations map to their origin declaration.
+func apiObject(obj types.Object) types.Object {
+	if obj == nil || obj.Pkg() == nil || !obj.Exported() {
+		return nil
+	}
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin()
+	case *types.Var:
+		if !o.IsField() && o.Parent() != o.Pkg().Scope() {
+			return nil
+		}
+		return o.Origin()
+	case *types.TypeName, *types.Const:
+		if o.Parent() != o.Pkg().Scope() {
+			return nil
+		}
+		return o
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Sources of nilness recorded with possible_nil_deref findings.
+const (
+	nilSrcMapLookup  = "map_lookup"     // v := m[k] with a nilable element type
+	nilSrcTypeAssert = "type_assert"    // v, _ := x.(T) with ok ignored
+	nilSrcReturn     = "nil_return"     // callee observed to return nil with a nil error (or no error)
+	nilSrcWithError  = "nil_with_error" // callee observed to return nil, err; safe once err == nil
+	nilSrcDocumented = "documented_nil" // callee's doc comment says it returns nil
+	nilSrcUninit     = "uninit_field"   // interface field never assigned anywhere
+)
+
+// nilDocRe matches doc comments promising a nil result ("returns nil if",
+// "or nil", "nil when ...").
+var nilDocRe = regexp.MustCompile(`(?i)\b(returns?|or)\s+nil\b|\bnil\s+(if|when)\b`)
+
+// nilSource is a value that may be nil, together with the conditions
+// under which it is known not to be.
+type nilSource struct {
+	value  ssa.Value
+	instr  ssa.Instruction // where the value comes from
+	kind   string
+	detail string
+	errVal ssa.Value // for nil_with_error: err == nil rules nil out
+}
+
+// nilReturnInfo summarizes what a function returns in each result.
+type nilReturnInfo struct {
+	nilWithNilErr map[int]bool // result can be nil while the error is nil
+	nilWithErr    map[int]bool // result is nil only alongside a non-nil error
+}
+
+// AnalyzeNilDerefs finds dereferences of values that may be nil with no
+// dominating nil check. The values tracked are map lookups (without
+// comma-ok) of nilable elements, comma-ok type assertions whose ok is
+// ignored, results of analyzed functions observed to return a nil
+// constant (unless a nil error rules it out, and the caller checked
+// err == nil first) or documented to return nil, and loads of
+// interface-typed fields that nothing in the analyzed modules ever
+// assigns.
+//
+// A dereference is a pointer load or store, a field or array element
+// address, an interface method call, a call through a func value or a map
+// write using the value directly. It is safe when a block where the value
+// (or, for nil_with_error, its error) compared against nil shows it
+// non-nil dominates the dereference. Method calls on a possibly nil
+// pointer receiver are not counted: the method may handle nil.
+//
+// The first unguarded dereference of each source is recorded in
+// nil_derefs on the function with the source of nilness and the CFG block
+// path from the source to the dereference.
+func AnalyzeNilDerefs(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Analyzing nil dereference risks...")
+
+	var funcs []*ssa.Function
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) > 0 {
+			funcs = append(funcs, fn)
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Pos() < funcs[j].Pos() })
+
+	returns := make(map[*ssa.Function]*nilReturnInfo, len(funcs))
+	assigned := make(map[*types.Var]bool)
+	for _, fn := range funcs {
+		returns[fn] = nilReturns(fn)
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if st, ok := instr.(*ssa.Store); ok {
+					if fa, ok := st.Addr.(*ssa.FieldAddr); ok {
+						if v := fieldVar(fa.X.Type(), fa.Field); v != nil {
+							assigned[v] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	byFunc := make(map[string][]map[string]any)
+	counts := make(map[string]int)
+	for _, fn := range funcs {
+		fnID := ssaFuncNodeID(fn, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		for _, src := range nilSources(fn, returns, assigned) {
+			deref, op := unguardedDeref(src)
+			if deref == nil {
+				continue
+			}
+			file, line, col := instrPos(deref, fset)
+			if file == "" {
+				continue
+			}
+			srcFile, srcLine, srcCol := instrPos(src.instr, fset)
+			srcSite := ""
+			if srcFile != "" {
+				srcSite = posLookup.Get(srcFile, srcLine, srcCol)
+			}
+			blocks := blockPath(fn, []int{src.instr.Block().Index}, deref.Block().Index, nil)
+			path := make([]string, len(blocks))
+			for i, b := range blocks {
+				path[i] = BlockID(fnID, b)
+			}
+			byFunc[fnID] = append(byFunc[fnID], map[string]any{
+				"source_kind": src.kind,
+				"source":      src.detail,
+				"source_site": srcSite,
+				"source_line": srcLine,
+				"op":          op,
+				"site":        posLookup.Get(file, line, col),
+				"file":        file,
+				"line":        line,
+				"path":        path,
+			})
+			counts[src.kind]++
+		}
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if derefs, ok := byFunc[n.ID]; ok && n.Kind == "function" {
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["nil_derefs"] = derefs
+		}
+	}
+
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	prog.Log("Nil derefs: %d possible (%d map lookups, %d type assertions, %d nil returns, %d documented, %d uninitialized fields)",
+		total, counts[nilSrcMapLookup], counts[nilSrcTypeAssert], counts[nilSrcReturn]+counts[nilSrcWithError],
+		counts[nilSrcDocumented], counts[nilSrcUninit])
+}
+
+// nilReturns records which results of fn are returned as a nil constant,
+// and whether the error result is nil at the same time.
+func nilReturns(fn *ssa.Function) *nilReturnInfo {
+	info := &nilReturnInfo{
+		nilWithNilErr: make(map[int]bool),
+		nilWithErr:    make(map[int]bool),
+	}
+	res := fn.Signature.Results()
+	errIdx := -1
+	if res.Len() > 0 && isErrorType(res.At(res.Len()-1).Type()) {
+		errIdx = res.Len() - 1
+	}
+	for _, b := range fn.Blocks {
+		ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return)
+		if !ok {
+			continue
+		}
+		errNil := errIdx < 0 || mayBeNilConst(ret.Results[errIdx])
+		for i, r := range ret.Results {
+			if i == errIdx || !derefNilable(r.Type()) || !mayBeNilConst(r) {
+				continue
+			}
+			if errNil {
+				info.nilWithNilErr[i] = true
+			} else {
+				info.nilWithErr[i] = true
+			}
+		}
+	}
+	return info
+}
+
+// mayBeNilConst reports whether v is the nil constant, or a phi with a nil
+// constant edge.
+func mayBeNilConst(v ssa.Value) bool {
+	if c, ok := v.(*ssa.Const); ok {
+		return c.IsNil()
+	}
+	if phi, ok := v.(*ssa.Phi); ok {
+		for _, e := range phi.Edges {
+			if c, ok := e.(*ssa.Const); ok && c.IsNil() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// derefNilable reports whether a nil value of type t can be dereferenced
+// into a panic: pointers, interfaces, maps (writes) and funcs. Nil slices
+// and channels do not panic on use.
+func derefNilable(t types.Type) bool {
+	if !isNilableType(t) {
+		return false
+	}
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Chan:
+		return false
+	}
+	return true
+}
+
+// fieldVar returns the field of the struct that a value of type t (a
+// pointer to it) addresses, or nil.
+func fieldVar(t types.Type, field int) *types.Var {
+	st, ok := deref(t).Underlying().(*types.Struct)
+	if !ok || field >= st.NumFields() {
+		return nil
+	}
+	return st.Field(field).Origin()
+}
+
+// nilSources lists the possibly nil values fn produces.
+func nilSources(fn *ssa.Function, returns map[*ssa.Function]*nilReturnInfo, assigned map[*types.Var]bool) []nilSource {
+	var srcs []nilSource
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch v := instr.(type) {
+			case *ssa.Lookup:
+				if _, isMap := v.X.Type().Underlying().(*types.Map); isMap && !v.CommaOk && derefNilable(v.Type()) {
+					srcs = append(srcs, nilSource{
+						value: v, instr: v, kind: nilSrcMapLookup,
+						detail: "lookup in " + v.X.Type().String(),
+					})
+				}
+			case *ssa.TypeAssert:
+				if !v.CommaOk || !derefNilable(v.AssertedType) {
+					continue
+				}
+				var val ssa.Value
+				okUsed := false
+				for _, ref := range *v.Referrers() {
+					if ex, ok := ref.(*ssa.Extract); ok {
+						if ex.Index == 0 {
+							val = ex
+						} else if valueUsed(ex) {
+							okUsed = true
+						}
+					}
+				}
+				if val != nil && !okUsed {
+					srcs = append(srcs, nilSource{
+						value: val, instr: v, kind: nilSrcTypeAssert,
+						detail: "assertion to " + v.AssertedType.String() + " with ok ignored",
+					})
+				}
+			case *ssa.Call:
+				srcs = append(srcs, nilCallSources(v, returns)...)
+			case *ssa.UnOp:
+				if v.Op != token.MUL {
+					continue
+				}
+				if fa, ok := v.X.(*ssa.FieldAddr); ok {
+					if src, ok := uninitFieldSource(v, fa.X.Type(), fa.Field, assigned); ok {
+						srcs = append(srcs, src)
+					}
+				}
+			case *ssa.Field:
+				if src, ok := uninitFieldSource(v, v.X.Type(), v.Field, assigned); ok {
+					srcs = append(srcs, src)
+				}
+			}
+		}
+	}
+	return srcs
+}
+
+// nilCallSources returns the results of call that may be nil, from what
+// the callee was observed or documented to return.
+func nilCallSources(call *ssa.Call, returns map[*ssa.Function]*nilReturnInfo) []nilSource {
+	callee := call.Call.StaticCallee()
+	info := returns[callee]
+	if callee == nil || info == nil {
+		return nil
+	}
+	name := calleeName(&call.Call)
+
+	results := make(map[int]ssa.Value)
+	var errVal ssa.Value
+	if tup, ok := call.Type().(*types.Tuple); ok {
+		for _, ref := range *call.Referrers() {
+			if ex, ok := ref.(*ssa.Extract); ok {
+				results[ex.Index] = ex
+				if ex.Index == tup.Len()-1 && isErrorType(ex.Type()) {
+					errVal = ex
+				}
+			}
+		}
+	} else {
+		results[0] = call
+	}
+
+	var srcs []nilSource
+	indices := make([]int, 0, len(results))
+	for i := range results {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	documented := false
+	for _, i := range indices {
+		v := results[i]
+		switch {
+		case info.nilWithNilErr[i]:
+			srcs = append(srcs, nilSource{
+				value: v, instr: call, kind: nilSrcReturn,
+				detail: name + " returns nil",
+			})
+		case info.nilWithErr[i]:
+			srcs = append(srcs, nilSource{
+				value: v, instr: call, kind: nilSrcWithError, errVal: errVal,
+				detail: name + " returns nil with an error",
+			})
+		case !documented && errVal == nil && derefNilable(v.Type()) && nilDocumented(callee):
+			documented = true
+			srcs = append(srcs, nilSource{
+				value: v, instr: call, kind: nilSrcDocumented,
+				detail: name + " is documented to return nil",
+			})
+		}
+	}
+	return srcs
+}
+
+// nilDocumented reports whether fn's doc comment says it may return nil.
+func nilDocumented(fn *ssa.Function) bool {
+	decl, ok := fn.Syntax().(*ast.FuncDecl)
+	return ok && decl.Doc != nil && nilDocRe.MatchString(decl.Doc.Text())
+}
+
+// uninitFieldSource makes a source of a load of an interface field that is
+// never assigned.
+func uninitFieldSource(v ssa.Value, t types.Type, field int, assigned map[*types.Var]bool) (nilSource, bool) {
+	fv := fieldVar(t, field)
+	if fv == nil || assigned[fv] || fv.Pkg() == nil || !modSet.IsKnownPkg(fv.Pkg().Path()) {
+		return nilSource{}, false
+	}
+	if _, ok := fv.Type().Underlying().(*types.Interface); !ok {
+		return nilSource{}, false
+	}
+	return nilSource{
+		value: v, instr: v.(ssa.Instruction), kind: nilSrcUninit,
+		detail: "field " + typeShortName(deref(t)) + "." + fv.Name() + " is never assigned",
+	}, true
+}
+
+// unguardedDeref returns the first dereference of src's value, in block
+// order, not dominated by a nil check, and the kind of dereference.
+func unguardedDeref(src nilSource) (ssa.Instruction, string) {
+	guards := nonNilBlocks(src.value, false)
+	if src.errVal != nil {
+		guards = append(guards, nonNilBlocks(src.errVal, true)...)
+	}
+	refs := src.value.Referrers()
+	if refs == nil {
+		return nil, ""
+	}
+	var first ssa.Instruction
+	firstOp := ""
+	for _, ref := range *refs {
+		op := derefOp(ref, src.value)
+		if op == "" {
+			continue
+		}
+		guarded := false
+		for _, g := range guards {
+			if g.Dominates(ref.Block()) {
+				guarded = true
+				break
+			}
+		}
+		if guarded {
+			continue
+		}
+		if first == nil || ref.Block().Index < first.Block().Index ||
+			(ref.Block() == first.Block() && instrIndex(ref.Block(), ref) < instrIndex(first.Block(), first)) {
+			first, firstOp = ref, op
+		}
+	}
+	return first, firstOp
+}
+
+// derefOp names the way instr dereferences v, or "" if it does not.
+func derefOp(instr ssa.Instruction, v ssa.Value) string {
+	switch r := instr.(type) {
+	case *ssa.UnOp:
+		if r.Op == token.MUL && r.X == v {
+			return "load"
+		}
+	case *ssa.FieldAddr:
+		if r.X == v {
+			return "field access"
+		}
+	case *ssa.IndexAddr:
+		if _, isPtr := v.Type().Underlying().(*types.Pointer); isPtr && r.X == v {
+			return "index"
+		}
+	case *ssa.Store:
+		if r.Addr == v {
+			return "store"
+		}
+	case *ssa.MapUpdate:
+		if r.Map == v {
+			return "map write"
+		}
+	case ssa.CallInstruction:
+		common := r.Common()
+		if common.Value != v {
+			return ""
+		}
+		if common.IsInvoke() {
+			return "method call " + common.Method.Name()
+		}
+		if _, isFunc := v.(*ssa.Function); !isFunc {
+			return "call"
+		}
+	}
+	return ""
+}
+
+// nonNilBlocks returns the successors of the ifs comparing v against nil
+// that are taken when v is not nil (or, with wantNil, when it is nil: an
+// error that is nil means the result beside it is set).
+func nonNilBlocks(v ssa.Value, wantNil bool) []*ssa.BasicBlock {
+	var blocks []*ssa.BasicBlock
+	if v.Referrers() == nil {
+		return nil
+	}
+	for _, ref := range *v.Referrers() {
+		cmp, ok := ref.(*ssa.BinOp)
+		if !ok || (cmp.Op != token.EQL && cmp.Op != token.NEQ) || cmp.Referrers() == nil {
+			continue
+		}
+		other := cmp.Y
+		if other == v {
+			other = cmp.X
+		}
+		if c, ok := other.(*ssa.Const); !ok || !c.IsNil() {
+			continue
+		}
+		// Succs[0] is taken when the comparison holds.
+		succ := 0
+		if (cmp.Op == token.EQL) != wantNil {
+			succ = 1
+		}
+		for _, use := range *cmp.Referrers() {
+			// A successor joined from elsewhere proves nothing.
+			if ifInstr, ok := use.(*ssa.If); ok && len(ifInstr.Block().Succs[succ].Preds) == 1 {
+				blocks = append(blocks, ifInstr.Block().Succs[succ])
+			}
+		}
+	}
+	return blocks
+}
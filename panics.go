@@ -0,0 +1,303 @@
+package main
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Panic origin kinds. panicKindExit terminates the process and cannot be
+// recovered; the others unwind and stop at a deferred recover.
+const (
+	panicKindPanic    = "panic"
+	panicKindLogPanic = "log_panic"
+	panicKindMust     = "must"
+	panicKindNilMap   = "nil_map_write"
+	panicKindExit     = "exit"
+)
+
+// exitPkgs are logging packages whose Fatal*/Exit* functions and methods
+// call os.Exit.
+var exitPkgs = map[string]bool{
+	"log":                        true,
+	"k8s.io/klog":                true,
+	"k8s.io/klog/v2":             true,
+	"github.com/golang/glog":     true,
+	"github.com/sirupsen/logrus": true,
+}
+
+// panicPoint is an instruction that may start or propagate a panic: a local
+// origin (kind set) or a call whose targets may panic (targets set).
+type panicPoint struct {
+	siteID  string
+	kind    string
+	targets []string
+	caught  bool // dominated by a deferred recover in the same function
+}
+
+// panicState is one function's result: where the panic comes from and,
+// when it propagates from a callee, which one.
+type panicState struct {
+	origin string // AST node ID of the original panic site
+	kind   string
+	via    string // callee function ID, "" for a local origin
+}
+
+// ComputePanicPropagation determines which functions may panic, directly or
+// through their callees, and which of those panics escape a deferred
+// recover. A panic point is caught when a defer of a function that calls
+// recover() dominates it, so a recover installed on only some paths leaves
+// the others uncaught. Deferred calls are panic points like calls, caught
+// by recovers deferred before them. Origins are explicit panic, log.Panic*,
+// external Must* helpers (regexp.MustCompile, template.Must, ...) and stores
+// into a map that is constant nil; other standard library panics and runtime
+// errors (index out of range, nil dereference, failed type assertion) are
+// not modeled. Process exits (os.Exit, log.Fatal* and friends) propagate the
+// same way but are never caught, and are reported as may_exit.
+//
+// Functions get may_panic / may_exit with panic_kind, panic_origin and
+// panic_via (the callee the panic arrives through; empty when it starts
+// locally), plus recovers=true when they defer a recover. Spawned
+// goroutines that may panic get panic_unrecovered for the
+// goroutine_without_recover finding.
+//
+// Must run after BuildCallGraph and AnalyzeGoroutines.
+func ComputePanicPropagation(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Computing panic propagation...")
+
+	callTargets := make(map[string][]string) // call site ID → callee IDs
+	for _, e := range cpg.Edges {
+		if e.Kind == "call_site" {
+			callTargets[e.Source] = append(callTargets[e.Source], e.Target)
+		}
+	}
+
+	points := make(map[string][]panicPoint)
+	recovers := make(map[string]bool)
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		fnID := ssaFuncNodeID(fn, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		pts, recovered := funcPanicPoints(fn, fnID, fset, posLookup, callTargets)
+		points[fnID] = append(points[fnID], pts...)
+		if recovered {
+			recovers[fnID] = true
+		}
+	}
+
+	panics := propagatePanics(points, func(kind string) bool { return kind != panicKindExit }, true)
+	exits := propagatePanics(points, func(kind string) bool { return kind == panicKindExit }, false)
+
+	var mayPanic, mayExit, unrecovered int
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if n.Kind != "function" {
+			continue
+		}
+		p, isPanic := panics[n.ID]
+		x, isExit := exits[n.ID]
+		if !isPanic && !isExit && !recovers[n.ID] {
+			continue
+		}
+		if n.Properties == nil {
+			n.Properties = map[string]any{}
+		}
+		if recovers[n.ID] {
+			n.Properties["recovers"] = true
+		}
+		if isPanic {
+			mayPanic++
+			n.Properties["may_panic"] = true
+			n.Properties["panic_kind"] = p.kind
+			n.Properties["panic_origin"] = p.origin
+			n.Properties["panic_via"] = p.via
+			if n.Properties["goroutine"] == true {
+				n.Properties["panic_unrecovered"] = true
+				unrecovered++
+			}
+		}
+		if isExit {
+			mayExit++
+			n.Properties["may_exit"] = true
+			n.Properties["exit_origin"] = x.origin
+			n.Properties["exit_via"] = x.via
+		}
+	}
+
+	prog.Log("Panics: %d may panic, %d may exit, %d recover, %d goroutines without recover",
+		mayPanic, mayExit, len(recovers), unrecovered)
+}
+
+// propagatePanics marks functions with an uncaught origin of a matching
+// kind, then walks call points backwards: a caller is marked when one of
+// its call points targets a marked function and (if catchable) is not
+// caught. Callers are visited in ID order so the witness is deterministic.
+func propagatePanics(points map[string][]panicPoint, match func(kind string) bool, catchable bool) map[string]panicState {
+	state := make(map[string]panicState)
+	callers := make(map[string][]string) // callee ID → caller IDs
+
+	fnIDs := make([]string, 0, len(points))
+	for id := range points {
+		fnIDs = append(fnIDs, id)
+	}
+	sort.Strings(fnIDs)
+
+	var queue []string
+	for _, fnID := range fnIDs {
+		for _, pt := range points[fnID] {
+			if catchable && pt.caught {
+				continue
+			}
+			if pt.kind != "" {
+				if _, ok := state[fnID]; !ok && match(pt.kind) {
+					state[fnID] = panicState{origin: pt.siteID, kind: pt.kind}
+					queue = append(queue, fnID)
+				}
+				continue
+			}
+			for _, t := range pt.targets {
+				if t != fnID {
+					callers[t] = append(callers[t], fnID)
+				}
+			}
+		}
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, caller := range callers[cur] {
+			if _, ok := state[caller]; ok {
+				continue
+			}
+			state[caller] = panicState{origin: state[cur].origin, kind: state[cur].kind, via: cur}
+			queue = append(queue, caller)
+		}
+	}
+	return state
+}
+
+// panicCallKind classifies a call that panics or exits by itself, or
+// returns "".
+func panicCallKind(common *ssa.CallCommon) string {
+	callee := common.StaticCallee()
+	if callee == nil {
+		return ""
+	}
+	name := callee.Name()
+	pkgPath := ""
+	if obj := callee.Object(); obj != nil && obj.Pkg() != nil {
+		pkgPath = obj.Pkg().Path()
+	} else if callee.Pkg != nil {
+		pkgPath = callee.Pkg.Pkg.Path()
+	}
+	switch {
+	case pkgPath == "os" && name == "Exit":
+		return panicKindExit
+	case exitPkgs[pkgPath] && (strings.HasPrefix(name, "Fatal") || strings.HasPrefix(name, "Exit")):
+		return panicKindExit
+	case exitPkgs[pkgPath] && strings.HasPrefix(name, "Panic"):
+		return panicKindLogPanic
+	case strings.HasPrefix(name, "Must") && pkgPath != "" && !modSet.IsKnownPkg(pkgPath):
+		// Analyzed Must* helpers are covered by their own panic calls.
+		return panicKindMust
+	}
+	return ""
+}
+
+// funcPanicPoints collects fn's panic points and reports whether fn defers
+// a recover. Calls without a local origin are kept only when the call
+// graph gives them targets.
+func funcPanicPoints(
+	fn *ssa.Function,
+	fnID string,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	callTargets map[string][]string,
+) (points []panicPoint, recovers bool) {
+	var recoverDefers []*ssa.Defer
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if d, ok := instr.(*ssa.Defer); ok {
+				if target := deferTarget(d); target != nil && callsRecover(target) {
+					recoverDefers = append(recoverDefers, d)
+				}
+			}
+		}
+	}
+	for _, block := range fn.Blocks {
+		for i, instr := range block.Instrs {
+			var pt panicPoint
+			switch inst := instr.(type) {
+			case *ssa.Panic:
+				pt.kind = panicKindPanic
+			case *ssa.MapUpdate:
+				if c, ok := inst.Map.(*ssa.Const); ok && c.IsNil() {
+					pt.kind = panicKindNilMap
+				}
+			case *ssa.Call:
+				pt.kind = panicCallKind(&inst.Call)
+			case *ssa.Defer:
+				// The deferred call runs at RunDefers or on return;
+				// recovers deferred before it run after it and catch
+				// its panic, the same dominance test as a call.
+				pt.kind = panicCallKind(&inst.Call)
+			default:
+				continue
+			}
+			if file, line, col := instrPos(instr, fset); file != "" {
+				pt.siteID = posLookup.Get(file, line, col)
+			}
+			if pt.kind == "" {
+				if _, ok := instr.(*ssa.MapUpdate); ok || pt.siteID == "" {
+					continue
+				}
+				pt.targets = callTargets[pt.siteID]
+				if len(pt.targets) == 0 {
+					continue
+				}
+			}
+			if pt.siteID == "" {
+				pt.siteID = fnID
+			}
+			for _, d := range recoverDefers {
+				db := d.Block()
+				if db == block && instrIndex(db, d) < i || db != block && db.Dominates(block) {
+					pt.caught = true
+					break
+				}
+			}
+			points = append(points, pt)
+		}
+	}
+	return points, len(recoverDefers) > 0
+}
+
+// callsRecover reports whether fn calls the recover builtin.
+func callsRecover(fn *ssa.Function) bool {
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				if b, ok := call.Call.Value.(*ssa.Builtin); ok && b.Name() == "recover" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
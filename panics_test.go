@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestDeferredPanicPoints(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/lib", Dir: "/src/example.com/lib"}, nil)
+
+	prog, fset := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+import "log"
+
+func Caught() {
+	defer func() { recover() }()
+	defer log.Panic("boom")
+}
+
+func Uncaught() {
+	defer log.Panic("boom")
+	defer func() { recover() }()
+}
+`})
+	pkg := prog.ImportedPackage("example.com/lib")
+
+	tests := []struct {
+		fn         string
+		wantCaught bool
+	}{
+		// the recover deferred first runs after the panicking call
+		{"Caught", true},
+		// the recover deferred last has already run when the call panics
+		{"Uncaught", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			points, recovers := funcPanicPoints(pkg.Func(tt.fn), tt.fn, fset, NewPosLookup(), nil)
+			if !recovers {
+				t.Errorf("recovers = false, want true")
+			}
+			var origins []panicPoint
+			for _, pt := range points {
+				if pt.kind != "" {
+					origins = append(origins, pt)
+				}
+			}
+			if len(origins) != 1 {
+				t.Fatalf("panic origins = %+v, want one deferred log.Panic", origins)
+			}
+			if origins[0].caught != tt.wantCaught {
+				t.Errorf("caught = %v, want %v", origins[0].caught, tt.wantCaught)
+			}
+		})
+	}
+}
@@ -0,0 +1,732 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// raceCallDepth bounds how far accesses are collected through calls from a
+// goroutine body or from the code following a go statement. Deeper accesses
+// rarely share a variable the analysis can identify and mostly add noise.
+const raceCallDepth = 2
+
+// memAccess is one read or write of a shared location.
+type memAccess struct {
+	loc    string // location key, see raceCtx.loc
+	name   string
+	kind   string // captured, global, field, map
+	write  bool
+	instr  ssa.Instruction
+	anchor ssa.Instruction // instruction in the region's root function that leads to instr
+	held   map[string]bool // lock keys held on every path
+}
+
+// raceRegion is the code running on one side of a race: a goroutine body or
+// the spawner's continuation after a go statement, with its callees.
+type raceRegion struct {
+	accesses []memAccess
+	waits    []ssa.Instruction // receives, blocking selects, WaitGroup.Wait in the root function
+	signals  bool              // sends, closes or WaitGroup.Done anywhere in the region
+}
+
+// raceCtx holds the program-wide indexes used by AnalyzeRaces.
+type raceCtx struct {
+	fset        *token.FileSet
+	posLookup   *PosLookup
+	callTargets map[string][]*ssa.Function
+	bound       map[ssa.Value]bool // values bound into closures
+	flows       map[*ssa.Function][]*lockState
+}
+
+// AnalyzeRaces reports static data-race candidates around go statements.
+// For each go statement it collects the accesses of the goroutine body and
+// of the spawner's code after the go statement (both through calls up to
+// raceCallDepth) to three kinds of shared locations:
+//
+//   - variables captured by reference into the goroutine's closure,
+//     identified by the SSA value bound into the closure, so per-iteration
+//     loop variables (Go 1.22) do not alias across iterations;
+//   - package variables;
+//   - struct fields, by Type.field (all instances share a location), and
+//     the contents of maps loaded from any of these.
+//
+// A pair of accesses to the same location, at least one a write, is a
+// race_candidate unless both hold a common lock or the spawner's access is
+// ordered after a receive / WaitGroup.Wait while the goroutine signals (send,
+// close, Done), or vice versa. A go statement inside a loop also races with
+// its own other instances on locations shared across iterations. sync/atomic
+// operations are calls, not loads or stores, and never form a pair.
+//
+// Candidates are stored in race_candidates on the spawning function.
+func AnalyzeRaces(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Analyzing data race candidates...")
+
+	r := &raceCtx{
+		fset:        fset,
+		posLookup:   posLookup,
+		callTargets: make(map[string][]*ssa.Function),
+		bound:       make(map[ssa.Value]bool),
+		flows:       make(map[*ssa.Function][]*lockState),
+	}
+	funcsByID := make(map[string]*ssa.Function)
+	var spawners []*ssa.Function
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if id := ssaFuncNodeID(fn, fset, funcLookup); id != "" {
+			funcsByID[id] = fn
+		}
+		spawns := false
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch inst := instr.(type) {
+				case *ssa.MakeClosure:
+					for _, v := range inst.Bindings {
+						r.bound[v] = true
+					}
+				case *ssa.Go:
+					spawns = true
+				}
+			}
+		}
+		if spawns {
+			spawners = append(spawners, fn)
+		}
+	}
+	for _, e := range cpg.Edges {
+		if e.Kind == "call_site" {
+			if fn, ok := funcsByID[e.Target]; ok {
+				r.callTargets[e.Source] = append(r.callTargets[e.Source], fn)
+			}
+		}
+	}
+	sort.Slice(spawners, func(i, j int) bool { return spawners[i].Pos() < spawners[j].Pos() })
+
+	candidatesByFunc := make(map[string][]map[string]any)
+	var total int
+	for _, fn := range spawners {
+		fnID := ssaFuncNodeID(fn, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		cands := r.spawnerRaces(fn)
+		if len(cands) > 0 {
+			candidatesByFunc[fnID] = cands
+			total += len(cands)
+		}
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if cands, ok := candidatesByFunc[n.ID]; ok {
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["race_candidates"] = cands
+		}
+	}
+
+	prog.Log("Races: %d candidates in %d spawning functions", total, len(candidatesByFunc))
+}
+
+// spawnerRaces checks every go statement of fn.
+func (r *raceCtx) spawnerRaces(fn *ssa.Function) []map[string]any {
+	var loops []*naturalLoop
+	if len(fn.Blocks) > 0 {
+		loops = naturalLoops(fn.Blocks)
+	}
+	inLoop := func(b *ssa.BasicBlock) *naturalLoop {
+		var inner *naturalLoop
+		for _, l := range loops {
+			if l.body[b.Index] && (inner == nil || len(l.body) < len(inner.body)) {
+				inner = l
+			}
+		}
+		return inner
+	}
+
+	type goSide struct {
+		stmt   *ssa.Go
+		site   string
+		region *raceRegion
+		loop   *naturalLoop
+	}
+	var gos []goSide
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			g, ok := instr.(*ssa.Go)
+			if !ok {
+				continue
+			}
+			region := r.goroutineRegion(g)
+			if region == nil {
+				continue
+			}
+			gos = append(gos, goSide{g, r.site(g), region, inLoop(b)})
+		}
+	}
+
+	var cands []map[string]any
+	seen := make(map[string]bool)
+	report := func(g goSide, a, b memAccess, other string) {
+		key := g.site + "|" + a.loc
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		fileA, lineA, _ := instrPos(a.instr, r.fset)
+		fileB, lineB, _ := instrPos(b.instr, r.fset)
+		cands = append(cands, map[string]any{
+			"location":       a.name,
+			"location_kind":  a.kind,
+			"go_site":        g.site,
+			"goroutine_site": r.site(a.instr),
+			"goroutine_op":   accessOp(a.write),
+			"goroutine_file": fileA,
+			"goroutine_line": lineA,
+			"other":          other,
+			"other_site":     r.site(b.instr),
+			"other_op":       accessOp(b.write),
+			"other_file":     fileB,
+			"other_line":     lineB,
+		})
+	}
+
+	for _, g := range gos {
+		parent := r.continuationRegion(fn, g.stmt)
+		fresh := r.perIterationCaptures(g.stmt, g.loop)
+		for _, a := range g.region.accesses {
+			for _, b := range parent.accesses {
+				if a.loc != b.loc || !(a.write || b.write) || commonLock(a.held, b.held) {
+					continue
+				}
+				if redefined, ok := fresh[strings.TrimSuffix(a.loc, "[]")]; ok && !sameIteration(g.stmt, b.anchor, redefined) {
+					continue
+				}
+				// Spawner waits for a goroutine that signals, or the
+				// goroutine waits for a spawner that signals.
+				if g.region.signals && dominatedByAny(b.anchor, parent.waits) ||
+					parent.signals && dominatedByAny(a.anchor, g.region.waits) {
+					continue
+				}
+				report(g, a, b, "spawner")
+			}
+		}
+		// Instances of the same go statement in a loop run concurrently.
+		if g.loop == nil {
+			continue
+		}
+		for _, a := range g.region.accesses {
+			if !a.write || !r.sharedAcrossIterations(a, g.stmt, g.loop) {
+				continue
+			}
+			for _, b := range g.region.accesses {
+				if a.loc != b.loc || commonLock(a.held, b.held) {
+					continue
+				}
+				report(g, a, b, "goroutine")
+				break
+			}
+		}
+	}
+	return cands
+}
+
+// sharedAcrossIterations reports whether a goroutine access in a loop-spawned
+// goroutine touches the same location in every instance: package variables
+// and fields always do, captured variables when the captured value is
+// defined outside the loop (not a per-iteration copy).
+func (r *raceCtx) sharedAcrossIterations(a memAccess, g *ssa.Go, loop *naturalLoop) bool {
+	if a.kind != "captured" {
+		return true
+	}
+	mc, ok := g.Call.Value.(*ssa.MakeClosure)
+	if !ok {
+		return true
+	}
+	outer := r.envOf(g.Parent())
+	for _, b := range mc.Bindings {
+		if k := r.bindingKey(b, outer); k != a.loc && k+"[]" != a.loc {
+			continue
+		}
+		if instr, ok := b.(ssa.Instruction); ok && loop.body[instr.Block().Index] {
+			return false
+		}
+	}
+	return true
+}
+
+// perIterationCaptures maps the capture keys of values a loop-spawned
+// closure binds that are defined inside the loop to their defining block:
+// each iteration creates a fresh variable there.
+func (r *raceCtx) perIterationCaptures(g *ssa.Go, loop *naturalLoop) map[string]*ssa.BasicBlock {
+	fresh := make(map[string]*ssa.BasicBlock)
+	mc, ok := g.Call.Value.(*ssa.MakeClosure)
+	if !ok || loop == nil {
+		return fresh
+	}
+	outer := r.envOf(g.Parent())
+	for _, b := range mc.Bindings {
+		if instr, ok := b.(ssa.Instruction); ok && loop.body[instr.Block().Index] {
+			fresh[r.bindingKey(b, outer)] = instr.Block()
+		}
+	}
+	return fresh
+}
+
+// sameIteration reports whether anchor, after go statement g, is reachable
+// without passing through block redefined again, i.e. still accesses the
+// variable instance g's closure captured.
+func sameIteration(g *ssa.Go, anchor ssa.Instruction, redefined *ssa.BasicBlock) bool {
+	if anchor == nil {
+		return false
+	}
+	start := g.Block()
+	if anchor.Block() == start && instrIndex(start, anchor) > instrIndex(start, g) {
+		return true
+	}
+	seen := map[*ssa.BasicBlock]bool{}
+	queue := append([]*ssa.BasicBlock(nil), start.Succs...)
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		if seen[b] || b == redefined {
+			continue
+		}
+		seen[b] = true
+		if b == anchor.Block() {
+			return true
+		}
+		queue = append(queue, b.Succs...)
+	}
+	return false
+}
+
+// goroutineRegion collects the accesses of the function a go statement
+// starts. Only closures and static callees are followed.
+func (r *raceCtx) goroutineRegion(g *ssa.Go) *raceRegion {
+	var fn *ssa.Function
+	env := make(map[*ssa.FreeVar]string)
+	switch v := g.Call.Value.(type) {
+	case *ssa.MakeClosure:
+		fn, _ = v.Fn.(*ssa.Function)
+		if fn != nil {
+			outer := r.envOf(g.Parent())
+			for i, b := range v.Bindings {
+				if i < len(fn.FreeVars) {
+					env[fn.FreeVars[i]] = r.bindingKey(b, outer)
+				}
+			}
+		}
+	case *ssa.Function:
+		fn = v
+	}
+	if fn == nil || fn.Pkg == nil || !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) || len(fn.Blocks) == 0 {
+		return nil
+	}
+	region := &raceRegion{}
+	visited := map[*ssa.Function]bool{fn: true}
+	r.collect(region, fn, env, nil, 0, nil, map[string]bool{}, nil, 0, visited)
+	return region
+}
+
+// continuationRegion collects the accesses in fn reachable after go
+// statement g. Instructions before g in its block are included only when
+// the block is re-entered through a loop.
+func (r *raceCtx) continuationRegion(fn *ssa.Function, g *ssa.Go) *raceRegion {
+	region := &raceRegion{}
+	start := g.Block()
+	startIdx := instrIndex(start, g) + 1
+	reached := map[*ssa.BasicBlock]bool{}
+	queue := append([]*ssa.BasicBlock(nil), start.Succs...)
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		if reached[b] {
+			continue
+		}
+		reached[b] = true
+		queue = append(queue, b.Succs...)
+	}
+	visited := map[*ssa.Function]bool{fn: true}
+	env := r.envOf(fn)
+	r.collect(region, fn, env, []*ssa.BasicBlock{start}, startIdx, reached, map[string]bool{}, nil, 0, visited)
+	return region
+}
+
+// envOf maps the free variables of a closure to the capture keys of the
+// values its (single) MakeClosure binds, so the spawner's own accesses
+// through free variables match the goroutine's.
+func (r *raceCtx) envOf(fn *ssa.Function) map[*ssa.FreeVar]string {
+	env := make(map[*ssa.FreeVar]string)
+	if fn.Parent() == nil || len(fn.FreeVars) == 0 {
+		return env
+	}
+	for _, b := range fn.Parent().Blocks {
+		for _, instr := range b.Instrs {
+			mc, ok := instr.(*ssa.MakeClosure)
+			if !ok || mc.Fn != fn {
+				continue
+			}
+			outer := r.envOf(fn.Parent())
+			for i, v := range mc.Bindings {
+				if i < len(fn.FreeVars) {
+					env[fn.FreeVars[i]] = r.bindingKey(v, outer)
+				}
+			}
+			return env
+		}
+	}
+	return env
+}
+
+// bindingKey returns the capture key of a value bound into a closure,
+// resolving free variables of an enclosing closure through env.
+func (r *raceCtx) bindingKey(v ssa.Value, env map[*ssa.FreeVar]string) string {
+	if fv, ok := v.(*ssa.FreeVar); ok {
+		if k, ok := env[fv]; ok {
+			return k
+		}
+	}
+	return captureKey(v)
+}
+
+func captureKey(v ssa.Value) string {
+	return fmt.Sprintf("cap:%p", v)
+}
+
+// collect adds fn's accesses to region. When blocks is non-nil, only those
+// blocks (the first from index startIdx) and the blocks in reached are
+// scanned; otherwise the whole function. inherited are locks held by the
+// callers; anchor is the root function's call leading here (nil at depth 0).
+func (r *raceCtx) collect(
+	region *raceRegion,
+	fn *ssa.Function,
+	env map[*ssa.FreeVar]string,
+	blocks []*ssa.BasicBlock,
+	startIdx int,
+	reached map[*ssa.BasicBlock]bool,
+	inherited map[string]bool,
+	anchor ssa.Instruction,
+	depth int,
+	visited map[*ssa.Function]bool,
+) {
+	in, ok := r.flows[fn]
+	if !ok {
+		in = lockFlow(fn)
+		r.flows[fn] = in
+	}
+	lockKeys := make(map[string]string) // lock path → lock key
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				if op, recv := syncLockOp(&call.Call); op != "" {
+					if k, _, _ := r.loc(recv, env); k != "" {
+						lockKeys[lockPath(recv)] = k
+					}
+				}
+			}
+		}
+	}
+
+	scan := func(b *ssa.BasicBlock, from int) {
+		if in[b.Index] == nil {
+			return
+		}
+		st := in[b.Index].clone()
+		for i, instr := range b.Instrs {
+			if i < from {
+				applyLockInstr(st, instr)
+				continue
+			}
+			held := func() map[string]bool {
+				h := make(map[string]bool, len(inherited))
+				for k := range inherited {
+					h[k] = true
+				}
+				for k := range st.must {
+					if lk := lockKeys[k[2:]]; lk != "" {
+						h[lk] = true
+					}
+				}
+				return h
+			}
+			at := anchor
+			if at == nil {
+				at = instr
+			}
+			r.syncOps(region, instr, depth == 0)
+			for _, acc := range r.accessesOf(instr, env) {
+				acc.anchor = at
+				acc.held = held()
+				region.accesses = append(region.accesses, acc)
+			}
+			if call, ok := instr.(*ssa.Call); ok && depth < raceCallDepth {
+				for _, callee := range r.calleesOf(call) {
+					if visited[callee] || len(callee.Blocks) == 0 {
+						continue
+					}
+					visited[callee] = true
+					calleeEnv := map[*ssa.FreeVar]string{}
+					if mc, ok := call.Call.Value.(*ssa.MakeClosure); ok && mc.Fn == callee {
+						for i, v := range mc.Bindings {
+							if i < len(callee.FreeVars) {
+								calleeEnv[callee.FreeVars[i]] = r.bindingKey(v, env)
+							}
+						}
+					}
+					r.collect(region, callee, calleeEnv, nil, 0, nil, held(), at, depth+1, visited)
+				}
+			}
+			applyLockInstr(st, instr)
+		}
+	}
+
+	if blocks == nil {
+		for _, b := range fn.Blocks {
+			scan(b, 0)
+		}
+		return
+	}
+	for _, b := range blocks {
+		if !reached[b] {
+			scan(b, startIdx)
+		}
+	}
+	for _, b := range fn.Blocks {
+		if reached[b] {
+			scan(b, 0)
+		}
+	}
+}
+
+// calleesOf resolves the analyzed functions a call may reach.
+func (r *raceCtx) calleesOf(call *ssa.Call) []*ssa.Function {
+	if mc, ok := call.Call.Value.(*ssa.MakeClosure); ok {
+		if fn, ok := mc.Fn.(*ssa.Function); ok {
+			return []*ssa.Function{fn}
+		}
+	}
+	if site := r.site(call); site != "" {
+		return r.callTargets[site]
+	}
+	if callee := call.Call.StaticCallee(); callee != nil && callee.Pkg != nil &&
+		modSet.IsKnownPkg(callee.Pkg.Pkg.Path()) {
+		return []*ssa.Function{callee}
+	}
+	return nil
+}
+
+// syncOps records waits (in the region's root function) and signals.
+func (r *raceCtx) syncOps(region *raceRegion, instr ssa.Instruction, root bool) {
+	switch inst := instr.(type) {
+	case *ssa.UnOp:
+		if inst.Op == token.ARROW && root {
+			region.waits = append(region.waits, inst)
+		}
+	case *ssa.Select:
+		if inst.Blocking && root {
+			region.waits = append(region.waits, inst)
+		}
+		for _, st := range inst.States {
+			if st.Dir == types.SendOnly {
+				region.signals = true
+			}
+		}
+	case *ssa.Send:
+		region.signals = true
+	case *ssa.Call:
+		if _, ok := waitGroupCall(&inst.Call, "Wait"); ok && root {
+			region.waits = append(region.waits, inst)
+		}
+		if _, ok := waitGroupCall(&inst.Call, "Done"); ok {
+			region.signals = true
+		}
+		if b, ok := inst.Call.Value.(*ssa.Builtin); ok && b.Name() == "close" {
+			region.signals = true
+		}
+	case *ssa.Defer:
+		if _, ok := waitGroupCall(&inst.Call, "Done"); ok {
+			region.signals = true
+		}
+		if b, ok := inst.Call.Value.(*ssa.Builtin); ok && b.Name() == "close" {
+			region.signals = true
+		}
+	}
+}
+
+// accessesOf returns the shared-location reads and writes of instr.
+func (r *raceCtx) accessesOf(instr ssa.Instruction, env map[*ssa.FreeVar]string) []memAccess {
+	var accs []memAccess
+	add := func(addr ssa.Value, write bool, contents bool) {
+		key, name, kind := r.loc(addr, env)
+		if key == "" {
+			return
+		}
+		if contents {
+			key, name, kind = key+"[]", name+"[]", "map"
+		}
+		accs = append(accs, memAccess{loc: key, name: name, kind: kind, write: write, instr: instr})
+	}
+	// mapSource returns the address a map value was loaded from.
+	mapSource := func(m ssa.Value) ssa.Value {
+		if u, ok := m.(*ssa.UnOp); ok && u.Op == token.MUL {
+			return u.X
+		}
+		return nil
+	}
+	switch inst := instr.(type) {
+	case *ssa.Store:
+		if !isSyncType(inst.Val.Type()) {
+			add(inst.Addr, true, false)
+		}
+	case *ssa.UnOp:
+		if inst.Op == token.MUL && !isSyncType(inst.Type()) {
+			add(inst.X, false, false)
+		}
+	case *ssa.MapUpdate:
+		if src := mapSource(inst.Map); src != nil {
+			add(src, true, true)
+		}
+	case *ssa.Lookup:
+		if _, ok := inst.X.Type().Underlying().(*types.Map); ok {
+			if src := mapSource(inst.X); src != nil {
+				add(src, false, true)
+			}
+		}
+	case *ssa.Range:
+		if _, ok := inst.X.Type().Underlying().(*types.Map); ok {
+			if src := mapSource(inst.X); src != nil {
+				add(src, false, true)
+			}
+		}
+	case *ssa.Call:
+		if b, ok := inst.Call.Value.(*ssa.Builtin); ok && b.Name() == "delete" && len(inst.Call.Args) > 0 {
+			if src := mapSource(inst.Call.Args[0]); src != nil {
+				add(src, true, true)
+			}
+		}
+	}
+	return accs
+}
+
+// loc resolves an address to a shared location key, display name and kind,
+// or "" for locations the analysis does not track.
+func (r *raceCtx) loc(addr ssa.Value, env map[*ssa.FreeVar]string) (string, string, string) {
+	switch x := addr.(type) {
+	case *ssa.FreeVar:
+		if k, ok := env[x]; ok {
+			return k, x.Name(), "captured"
+		}
+		return "", "", ""
+	case *ssa.Global:
+		return "global:" + x.RelString(nil), modSet.RelPkg(x.Pkg.Pkg.Path()) + "." + x.Name(), "global"
+	case *ssa.FieldAddr:
+		// Fields of a captured struct variable or of a package variable
+		// belong to that variable; other fields are identified by type.
+		if k, name, kind := r.loc(x.X, env); kind == "captured" || kind == "global" {
+			return k, name, kind
+		}
+		class, _ := fieldClass(deref(x.X.Type()), x.Field)
+		if class == "" {
+			return "", "", ""
+		}
+		return "field:" + class, class, "field"
+	case *ssa.IndexAddr:
+		if _, ok := deref(x.X.Type()).Underlying().(*types.Array); ok {
+			return r.loc(x.X, env)
+		}
+		return "", "", ""
+	}
+	if r.bound[addr] {
+		name := addr.Name()
+		if a, ok := addr.(*ssa.Alloc); ok && a.Comment != "" {
+			name = a.Comment
+		}
+		return captureKey(addr), name, "captured"
+	}
+	return "", "", ""
+}
+
+// site returns the AST node ID at an instruction's position, or "".
+func (r *raceCtx) site(instr ssa.Instruction) string {
+	file, line, col := instrPos(instr, r.fset)
+	if file == "" {
+		return ""
+	}
+	return r.posLookup.Get(file, line, col)
+}
+
+// isSyncType reports whether t is a sync or sync/atomic type, whose
+// copies and zeroing are not data accesses of interest.
+func isSyncType(t types.Type) bool {
+	n, ok := deref(t).(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	p := n.Obj().Pkg().Path()
+	return p == "sync" || p == "sync/atomic"
+}
+
+// commonLock reports whether two held-lock sets intersect.
+func commonLock(a, b map[string]bool) bool {
+	for k := range a {
+		if b[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// dominatedByAny reports whether instr executes after one of waits on every
+// path: an earlier instruction of its block or a dominating block.
+func dominatedByAny(instr ssa.Instruction, waits []ssa.Instruction) bool {
+	if instr == nil {
+		return false
+	}
+	b := instr.Block()
+	for _, w := range waits {
+		wb := w.Block()
+		if wb == b {
+			if instrIndex(b, w) < instrIndex(b, instr) {
+				return true
+			}
+		} else if wb.Dominates(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// instrIndex returns the position of instr within block.
+func instrIndex(block *ssa.BasicBlock, instr ssa.Instruction) int {
+	for i, in := range block.Instrs {
+		if in == instr {
+			return i
+		}
+	}
+	return len(block.Instrs)
+}
+
+func accessOp(write bool) string {
+	if write {
+		return "write"
+	}
+	return "read"
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// flagReachTests makes Test/Benchmark/Fuzz/Example functions reachability
+// entry points. Set by main before any pipeline phase runs.
+var flagReachTests = false
+
+// Entry point kinds, in priority order. When a function qualifies as more
+// than one kind, the first one listed wins.
+const (
+	entryMain        = "main"
+	entryInit        = "init"
+	entryHTTPHandler = "http_handler"
+	entryExportedAPI = "exported_api"
+	entryTest        = "test"
+)
+
+var entryPriority = map[string]int{
+	entryMain:        0,
+	entryInit:        1,
+	entryHTTPHandler: 2,
+	entryExportedAPI: 3,
+	entryTest:        4,
+}
+
+// httpRouterPkgs lists packages that provide HTTP routers.
+var httpRouterPkgs = map[string]bool{
+	"net/http":                            true,
+	"github.com/prometheus/common/route":  true,
+	"github.com/gorilla/mux":              true,
+	"github.com/go-chi/chi":               true,
+	"github.com/go-chi/chi/v5":            true,
+	"github.com/julienschmidt/httprouter": true,
+}
+
+// httpRouterTypes lists, per package, the router types whose registration
+// methods take a handler that the server, not the analyzed code, will
+// eventually invoke.
+var httpRouterTypes = map[string]map[string]bool{
+	"net/http":                            {"ServeMux": true},
+	"github.com/prometheus/common/route":  {"Router": true},
+	"github.com/gorilla/mux":              {"Router": true, "Route": true},
+	"github.com/go-chi/chi":               {"Router": true, "Mux": true},
+	"github.com/go-chi/chi/v5":            {"Router": true, "Mux": true},
+	"github.com/julienschmidt/httprouter": {"Router": true},
+}
+
+// httpRegisterMethods are the method names on httpRouterTypes that register
+// a handler.
+var httpRegisterMethods = map[string]bool{
+	"Handle": true, "HandleFunc": true, "Handler": true, "HandlerFunc": true,
+	"Get": true, "Post": true, "Put": true, "Patch": true, "Delete": true,
+	"Del": true, "Head": true, "Options": true, "Method": true, "MethodFunc": true,
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true,
+}
+
+// httpRegisterFuncs are the package-level net/http functions that register
+// a handler on DefaultServeMux.
+var httpRegisterFuncs = map[string]bool{"Handle": true, "HandleFunc": true}
+
+// ComputeReachability marks every known-module function as reachable or not
+// from the program's entry points: main, init (including package-level var
+// initializers), HTTP handler registrations, exported APIs of library modules
+// (modules without a main package), and optionally tests.
+//
+// The traversal follows VTA call edges plus two kinds of implicit edges that
+// the call graph misses when the eventual caller lives outside the analyzed
+// modules: function values referenced by a function (callbacks, method
+// values, closures), and exported methods of concrete types converted to an
+// interface (fmt.Stringer, sort.Interface, http.Handler, ...).
+//
+// Reachable functions get reachable=true with the entry kind, depth and
+// their parent on a shortest path from the entry point; the
+// reachability_witness query follows the parents back to the entry.
+// Unreachable functions are grouped into dead subgraphs by the dead roots
+// (unreachable functions no unreachable function calls) that reach them;
+// the root of each subgraph carries the member list so the writer can emit
+// one unreachable_function finding per subgraph.
+func ComputeReachability(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Computing entry-point reachability...")
+
+	succs := make(map[string]map[string]struct{})
+	addEdge := func(src, dst string) {
+		if src == "" || dst == "" {
+			return
+		}
+		s := succs[src]
+		if s == nil {
+			s = make(map[string]struct{})
+			succs[src] = s
+		}
+		s[dst] = struct{}{}
+	}
+	for _, e := range cpg.Edges {
+		if e.Kind == "call" {
+			addEdge(e.Source, e.Target)
+		}
+	}
+
+	hasMain := modulesWithMain(ssaResult.Prog.AllPackages())
+
+	seeds := make(map[string]string) // function ID → entry kind
+	addSeed := func(id, kind string) {
+		if id == "" {
+			return
+		}
+		if prev, ok := seeds[id]; ok && entryPriority[prev] <= entryPriority[kind] {
+			return
+		}
+		seeds[id] = kind
+	}
+
+	progSSA := ssaResult.Prog
+	nodeID := func(fn *ssa.Function) string {
+		fn = sourceFunc(progSSA, fn)
+		if fn == nil {
+			return ""
+		}
+		return ssaFuncNodeID(fn, fset, funcLookup)
+	}
+
+	var refEdges, ifaceEdges int
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		// The synthetic package initializer runs package-level var
+		// initializers; everything it touches is an init entry point.
+		isPkgInit := fn.Synthetic == "package initializer"
+		if fn.Synthetic != "" && !isPkgInit {
+			continue
+		}
+
+		srcID := ""
+		if !isPkgInit {
+			srcID = ssaFuncNodeID(fn, fset, funcLookup)
+			if srcID == "" {
+				continue
+			}
+			if kind := entryKind(fn, fset, hasMain); kind != "" {
+				addSeed(srcID, kind)
+			}
+		}
+		link := func(target *ssa.Function) bool {
+			tid := nodeID(target)
+			if tid == "" || tid == srcID {
+				return false
+			}
+			if isPkgInit {
+				addSeed(tid, entryInit)
+			} else {
+				addEdge(srcID, tid)
+			}
+			return true
+		}
+
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				// Function values referenced as operands: callbacks,
+				// closures, method values and static callees alike.
+				for _, op := range instr.Operands(nil) {
+					if op == nil {
+						continue
+					}
+					if target, ok := (*op).(*ssa.Function); ok && link(target) {
+						refEdges++
+					}
+				}
+
+				switch inst := instr.(type) {
+				case *ssa.MakeInterface:
+					for _, m := range exportedMethods(progSSA, inst.X.Type()) {
+						if link(m) {
+							ifaceEdges++
+						}
+					}
+				case ssa.CallInstruction:
+					if !isHTTPRegistration(inst.Common()) {
+						continue
+					}
+					for _, arg := range inst.Common().Args {
+						for _, h := range handlerFuncs(progSSA, arg) {
+							addSeed(nodeID(h), entryHTTPHandler)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Multi-source BFS from all seeds yields the shortest witness path to
+	// the nearest entry point. Seeds are ordered by kind then ID so the
+	// recorded witness is deterministic.
+	seedIDs := make([]string, 0, len(seeds))
+	for id := range seeds {
+		seedIDs = append(seedIDs, id)
+	}
+	sort.Slice(seedIDs, func(i, j int) bool {
+		pi, pj := entryPriority[seeds[seedIDs[i]]], entryPriority[seeds[seedIDs[j]]]
+		if pi != pj {
+			return pi < pj
+		}
+		return seedIDs[i] < seedIDs[j]
+	})
+
+	parent := make(map[string]string, len(seeds))
+	origin := make(map[string]string, len(seeds)) // reached ID → entry kind
+	depth := make(map[string]int, len(seeds))
+	queue := make([]string, 0, len(seedIDs))
+	for _, id := range seedIDs {
+		origin[id] = seeds[id]
+		queue = append(queue, id)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		next := make([]string, 0, len(succs[cur]))
+		for dst := range succs[cur] {
+			if _, seen := origin[dst]; !seen {
+				next = append(next, dst)
+			}
+		}
+		sort.Strings(next)
+		for _, dst := range next {
+			origin[dst] = origin[cur]
+			parent[dst] = cur
+			depth[dst] = depth[cur] + 1
+			queue = append(queue, dst)
+		}
+	}
+
+	// Candidate set: analyzed-code function nodes (no external stubs).
+	isCandidate := func(n *Node) bool {
+		return n.Kind == "function" && !strings.HasPrefix(n.ID, "ext::")
+	}
+	unreachable := make(map[string]bool)
+	var reachableCount int
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if !isCandidate(n) {
+			continue
+		}
+		if n.Properties == nil {
+			n.Properties = map[string]any{}
+		}
+		kind, ok := origin[n.ID]
+		if !ok {
+			n.Properties["reachable"] = false
+			unreachable[n.ID] = true
+			continue
+		}
+		reachableCount++
+		n.Properties["reachable"] = true
+		n.Properties["reach_entry"] = kind
+		n.Properties["reach_depth"] = depth[n.ID]
+		if p, ok := parent[n.ID]; ok {
+			n.Properties["reach_parent"] = p
+		}
+	}
+
+	subgraphs := deadSubgraphs(unreachable, succs)
+	byID := make(map[string]*Node, len(unreachable))
+	for i := range cpg.Nodes {
+		if unreachable[cpg.Nodes[i].ID] {
+			byID[cpg.Nodes[i].ID] = &cpg.Nodes[i]
+		}
+	}
+	for _, sg := range subgraphs {
+		for _, id := range sg.members {
+			byID[id].Properties["dead_subgraph"] = sg.root
+		}
+		root := byID[sg.root]
+		root.Properties["dead_root"] = true
+		root.Properties["dead_subgraph_size"] = len(sg.members)
+		root.Properties["dead_subgraph_roots"] = sg.roots
+		members := sg.members
+		if len(members) > maxDeadMembers {
+			members = members[:maxDeadMembers]
+		}
+		root.Properties["dead_subgraph_members"] = members
+	}
+
+	kindCounts := make(map[string]int)
+	for _, k := range seeds {
+		kindCounts[k]++
+	}
+	prog.Log("Reachability: %d entry points (%d main, %d init, %d http_handler, %d exported_api, %d test), %d ref + %d interface edges",
+		len(seeds), kindCounts[entryMain], kindCounts[entryInit], kindCounts[entryHTTPHandler],
+		kindCounts[entryExportedAPI], kindCounts[entryTest], refEdges, ifaceEdges)
+	prog.Log("Reachability: %d reachable, %d unreachable functions in %d dead subgraphs",
+		reachableCount, len(unreachable), len(subgraphs))
+}
+
+// maxDeadMembers caps the member list stored on a dead subgraph root.
+// dead_subgraph_size always carries the full count.
+const maxDeadMembers = 50
+
+// modulesWithMain returns the modules with a main package. The others are
+// libraries, whose exported API is reachable by importers we cannot see.
+// Testmain packages ("p.test") are named main but say nothing about the
+// module.
+func modulesWithMain(pkgs []*ssa.Package) map[string]bool {
+	hasMain := make(map[string]bool)
+	for _, p := range pkgs {
+		if p.Pkg.Name() != "main" || strings.HasSuffix(p.Pkg.Path(), ".test") {
+			continue
+		}
+		if m, ok := modSet.ModuleFor(p.Pkg.Path()); ok {
+			hasMain[m.ModPath] = true
+		}
+	}
+	return hasMain
+}
+
+// entryKind classifies fn as an entry point, or returns "".
+func entryKind(fn *ssa.Function, fset *token.FileSet, hasMain map[string]bool) string {
+	// Closures are reached through the function that creates them.
+	if fn.Parent() != nil {
+		return ""
+	}
+	if fn.Signature.Recv() == nil && fn.Name() == "main" && fn.Pkg.Pkg.Name() == "main" {
+		return entryMain
+	}
+	if fn.Signature.Recv() == nil && strings.HasPrefix(fn.Name(), "init#") {
+		return entryInit
+	}
+	if flagReachTests && fn.Signature.Recv() == nil && isTestFunc(fn, fset) {
+		return entryTest
+	}
+
+	pkgPath := fn.Pkg.Pkg.Path()
+	m, ok := modSet.ModuleFor(pkgPath)
+	if !ok || hasMain[m.ModPath] || isInternalPkg(pkgPath) {
+		return ""
+	}
+	if !token.IsExported(fn.Name()) {
+		return ""
+	}
+	if recv := fn.Signature.Recv(); recv != nil {
+		named, ok := deref(recv.Type()).(*types.Named)
+		if !ok || !named.Obj().Exported() {
+			return ""
+		}
+	}
+	return entryExportedAPI
+}
+
+// isTestFunc reports whether fn is a go test driver entry point declared in
+// a _test.go file.
+func isTestFunc(fn *ssa.Function, fset *token.FileSet) bool {
+	if !strings.HasSuffix(fset.Position(fn.Pos()).Filename, "_test.go") {
+		return false
+	}
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+		if strings.HasPrefix(fn.Name(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInternalPkg reports whether pkgPath is an internal package, whose
+// exported API is only visible to its own module.
+func isInternalPkg(pkgPath string) bool {
+	return strings.HasSuffix(pkgPath, "/internal") || strings.Contains(pkgPath, "/internal/")
+}
+
+// sourceFunc maps synthetic wrappers (bound method values, thunks) and
+// generic instantiations back to the declared function that has an AST node.
+func sourceFunc(prog *ssa.Program, fn *ssa.Function) *ssa.Function {
+	if fn.Synthetic == "" {
+		if origin := fn.Origin(); origin != nil {
+			return origin
+		}
+		return fn
+	}
+	obj, ok := fn.Object().(*types.Func)
+	if !ok {
+		return nil
+	}
+	return prog.FuncValue(obj.Origin())
+}
+
+// exportedMethods returns the declared exported methods in the method set of
+// a concrete type T. Converting T to an interface makes them callable from
+// code we never see (fmt, sort, net/http, encoding/json, ...).
+func exportedMethods(prog *ssa.Program, t types.Type) []*ssa.Function {
+	if types.IsInterface(t) {
+		return nil
+	}
+	mset := prog.MethodSets.MethodSet(t)
+	var out []*ssa.Function
+	for i := range mset.Len() {
+		obj, ok := mset.At(i).Obj().(*types.Func)
+		if !ok || !obj.Exported() {
+			continue
+		}
+		if fn := prog.FuncValue(obj.Origin()); fn != nil {
+			out = append(out, fn)
+		}
+	}
+	return out
+}
+
+// isHTTPRegistration reports whether call registers an HTTP handler with
+// a known router (http.HandleFunc, (*ServeMux).Handle, route.Router.Get, ...).
+// Methods are matched on their receiver type, so http.Header.Get,
+// (*http.Client).Post and the like are not registrations.
+func isHTTPRegistration(call *ssa.CallCommon) bool {
+	var obj *types.Func
+	if call.IsInvoke() {
+		obj = call.Method
+	} else if callee := call.StaticCallee(); callee != nil {
+		if origin := callee.Origin(); origin != nil {
+			callee = origin
+		}
+		obj, _ = callee.Object().(*types.Func)
+	}
+	if obj == nil || obj.Pkg() == nil {
+		return false
+	}
+	recv := obj.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return obj.Pkg().Path() == "net/http" && httpRegisterFuncs[obj.Name()]
+	}
+	named, ok := types.Unalias(deref(recv.Type())).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	pkg, typ := named.Obj().Pkg().Path(), named.Obj().Name()
+	if !httpRouterTypes[pkg][typ] || !httpRegisterMethods[obj.Name()] {
+		return false
+	}
+	// (*ServeMux).Handler(r) looks up the handler for a request.
+	return !(pkg == "net/http" && obj.Name() == "Handler")
+}
+
+// handlerFuncs resolves a handler argument to the functions the router will
+// call: plain function values, closures, http.HandlerFunc conversions, and
+// the ServeHTTP method of concrete http.Handler implementations.
+func handlerFuncs(prog *ssa.Program, v ssa.Value) []*ssa.Function {
+	switch val := v.(type) {
+	case *ssa.Function:
+		return []*ssa.Function{val}
+	case *ssa.MakeClosure:
+		if fn, ok := val.Fn.(*ssa.Function); ok {
+			return []*ssa.Function{fn}
+		}
+	case *ssa.ChangeType:
+		return handlerFuncs(prog, val.X)
+	case *ssa.MakeInterface:
+		if fns := handlerFuncs(prog, val.X); len(fns) > 0 {
+			return fns
+		}
+		var out []*ssa.Function
+		for _, m := range exportedMethods(prog, val.X.Type()) {
+			if m.Name() == "ServeHTTP" {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// deadSubgraph is a group of unreachable functions reached from the same
+// dead roots.
+type deadSubgraph struct {
+	root    string   // representative root (reported in the finding)
+	roots   []string // dead roots whose calls reach the members
+	members []string // all members, sorted
+}
+
+// deadSubgraphs groups unreachable functions by the dead roots that reach
+// them over succs. Dead roots are functions no other unreachable function
+// calls; in a pure cycle with no such root, the smallest ID not yet reached
+// stands in for one. A forward reach from each root gives the roots of
+// every function, and functions sharing the same roots form one subgraph,
+// so helpers shared by two dead roots form their own. A subgraph's anchor
+// is its own root when it has one, else a member no other member calls.
+func deadSubgraphs(unreachable map[string]bool, succs map[string]map[string]struct{}) []deadSubgraph {
+	adj := make(map[string][]string)
+	hasDeadCaller := make(map[string]bool)
+	for src := range unreachable {
+		for dst := range succs[src] {
+			if !unreachable[dst] || dst == src {
+				continue
+			}
+			adj[src] = append(adj[src], dst)
+			hasDeadCaller[dst] = true
+		}
+	}
+
+	ids := make([]string, 0, len(unreachable))
+	for id := range unreachable {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	reach := func(root string) []string {
+		seen := map[string]bool{root: true}
+		out := []string{root}
+		for i := 0; i < len(out); i++ {
+			for _, nb := range adj[out[i]] {
+				if !seen[nb] {
+					seen[nb] = true
+					out = append(out, nb)
+				}
+			}
+		}
+		return out
+	}
+
+	var roots []string
+	reached := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !hasDeadCaller[id] {
+			roots = append(roots, id)
+			for _, m := range reach(id) {
+				reached[m] = true
+			}
+		}
+	}
+	for _, id := range ids {
+		if !reached[id] {
+			roots = append(roots, id)
+			for _, m := range reach(id) {
+				reached[m] = true
+			}
+		}
+	}
+	sort.Strings(roots)
+
+	rootsOf := make(map[string][]string, len(ids))
+	for _, r := range roots {
+		for _, m := range reach(r) {
+			rootsOf[m] = append(rootsOf[m], r)
+		}
+	}
+	groups := make(map[string][]string) // joined roots → members
+	for _, id := range ids {
+		key := strings.Join(rootsOf[id], "\x00")
+		groups[key] = append(groups[key], id)
+	}
+
+	isRoot := make(map[string]bool, len(roots))
+	for _, r := range roots {
+		isRoot[r] = true
+	}
+	var out []deadSubgraph
+	for _, members := range groups {
+		inGroup := make(map[string]bool, len(members))
+		for _, m := range members {
+			inGroup[m] = true
+		}
+		calledInGroup := make(map[string]bool)
+		for _, m := range members {
+			for _, nb := range adj[m] {
+				if inGroup[nb] && nb != m {
+					calledInGroup[nb] = true
+				}
+			}
+		}
+		var anchors []string
+		for _, m := range members {
+			if isRoot[m] {
+				anchors = []string{m}
+				break
+			}
+			if !calledInGroup[m] {
+				anchors = append(anchors, m)
+			}
+		}
+		root := members[0]
+		if len(anchors) > 0 {
+			root = anchors[0]
+			// Prefer a named function over a func literal as the anchor.
+			for _, a := range anchors {
+				if !strings.HasSuffix(a, ":func_lit") {
+					root = a
+					break
+				}
+			}
+		}
+		out = append(out, deadSubgraph{root: root, roots: rootsOf[members[0]], members: members})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].root < out[j].root })
+	return out
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// testPkg is the source of a one-file package for buildTestProgram.
+type testPkg struct {
+	path string
+	src  string
+}
+
+// buildTestProgram type-checks and builds SSA for packages importing only
+// the standard library.
+func buildTestProgram(t *testing.T, pkgs ...testPkg) (*ssa.Program, *token.FileSet) {
+	t.Helper()
+	fset := token.NewFileSet()
+	prog := ssa.NewProgram(fset, 0)
+	conf := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	created := make(map[*types.Package]bool)
+	for _, p := range pkgs {
+		f, err := parser.ParseFile(fset, "/src/"+p.path+"/x.go", p.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Instances:  make(map[*ast.Ident]types.Instance),
+			Scopes:     make(map[ast.Node]*types.Scope),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		pkg, err := conf.Check(p.path, fset, []*ast.File{f}, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range pkg.Imports() {
+			if !created[imp] {
+				prog.CreatePackage(imp, nil, nil, true)
+				created[imp] = true
+			}
+		}
+		prog.CreatePackage(pkg, []*ast.File{f}, info, true)
+	}
+	prog.Build()
+	return prog, fset
+}
+
+func TestDedupTestVariants(t *testing.T) {
+	pkg := func(id, path string) *packages.Package { return &packages.Package{ID: id, PkgPath: path} }
+	tests := []struct {
+		name string
+		in   []*packages.Package
+		want []string // IDs
+	}{
+		{
+			name: "without tests",
+			in:   []*packages.Package{pkg("m/a", "m/a"), pkg("m/b", "m/b")},
+			want: []string{"m/a", "m/b"},
+		},
+		{
+			name: "own test variant replaces the plain package",
+			in: []*packages.Package{
+				pkg("m/a", "m/a"),
+				pkg("m/a [m/a.test]", "m/a"),
+				pkg("m/a_test [m/a.test]", "m/a_test"),
+				pkg("m/a.test", "m/a.test"),
+			},
+			want: []string{"m/a [m/a.test]", "m/a_test [m/a.test]"},
+		},
+		{
+			name: "recompiled for another package's tests",
+			in: []*packages.Package{
+				pkg("m/b [m/a.test]", "m/b"),
+				pkg("m/b", "m/b"),
+				pkg("m/a [m/a.test]", "m/a"),
+				pkg("m/a", "m/a"),
+			},
+			want: []string{"m/b", "m/a [m/a.test]"},
+		},
+		{
+			name: "testmain of a package without other copies",
+			in:   []*packages.Package{pkg("m/cmd.test", "m/cmd.test")},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range dedupTestVariants(tt.in) {
+				got = append(got, p.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEntryKind(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/lib", Dir: "/src/example.com/lib"},
+		[]ModuleInfo{{ModPath: "example.com/app", Dir: "/src/example.com/app", Prefix: "app"}})
+
+	prog, fset := buildTestProgram(t,
+		testPkg{"example.com/lib", `package lib
+
+type T struct{}
+
+func (T) Method()  {}
+func (*T) Ptr()    {}
+func Exported()    {}
+func unexported()  {}
+func init()        {}
+
+type hidden struct{}
+
+func (hidden) Method() {}
+`},
+		testPkg{"example.com/lib/internal/x", `package x
+
+func Exported() {}
+`},
+		// go test's generated main must not turn the library into a program
+		testPkg{"example.com/lib/sub.test", `package main
+
+func main() {}
+`},
+		testPkg{"example.com/app", `package main
+
+func main()     {}
+func Exported() {}
+`},
+	)
+
+	hasMain := modulesWithMain(prog.AllPackages())
+	if hasMain["example.com/lib"] {
+		t.Errorf("testmain package marks example.com/lib as having a main package")
+	}
+	if !hasMain["example.com/app"] {
+		t.Errorf("example.com/app has a main package")
+	}
+
+	member := func(path, name string) *ssa.Function {
+		t.Helper()
+		p := prog.ImportedPackage(path)
+		if p == nil {
+			t.Fatalf("no package %s", path)
+		}
+		fn := p.Func(name)
+		if fn == nil {
+			t.Fatalf("no function %s.%s", path, name)
+		}
+		return fn
+	}
+	method := func(path, typ, name string, ptr bool) *ssa.Function {
+		t.Helper()
+		obj := prog.ImportedPackage(path).Pkg.Scope().Lookup(typ)
+		recv := obj.Type()
+		if ptr {
+			recv = types.NewPointer(recv)
+		}
+		sel := prog.MethodSets.MethodSet(recv).Lookup(obj.Pkg(), name)
+		return prog.MethodValue(sel)
+	}
+
+	tests := []struct {
+		name string
+		fn   *ssa.Function
+		want string
+	}{
+		{"library exported function", member("example.com/lib", "Exported"), entryExportedAPI},
+		{"library unexported function", member("example.com/lib", "unexported"), ""},
+		{"library init", member("example.com/lib", "init#1"), entryInit},
+		{"method of exported type", method("example.com/lib", "T", "Method", false), entryExportedAPI},
+		{"pointer method of exported type", method("example.com/lib", "T", "Ptr", true), entryExportedAPI},
+		{"method of unexported type", method("example.com/lib", "hidden", "Method", false), ""},
+		{"internal package", member("example.com/lib/internal/x", "Exported"), ""},
+		{"program main", member("example.com/app", "main"), entryMain},
+		{"exported function of a program", member("example.com/app", "Exported"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryKind(tt.fn, fset, hasMain); got != tt.want {
+				t.Errorf("entryKind(%s) = %q, want %q", tt.fn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeadSubgraphs(t *testing.T) {
+	succs := map[string]map[string]struct{}{
+		"a":    {"a1": {}, "h": {}},
+		"b":    {"h": {}},
+		"main": {"live": {}},
+		"x":    {"y": {}},
+		"y":    {"x": {}, "z": {}},
+	}
+	unreachable := map[string]bool{"a": true, "a1": true, "b": true, "h": true, "x": true, "y": true, "z": true}
+
+	got := deadSubgraphs(unreachable, succs)
+	want := []deadSubgraph{
+		{root: "a", roots: []string{"a"}, members: []string{"a", "a1"}},
+		{root: "b", roots: []string{"b"}, members: []string{"b"}},
+		// shared by both dead roots: its own subgraph
+		{root: "h", roots: []string{"a", "b"}, members: []string{"h"}},
+		// pure cycle: the smallest ID stands in for a root
+		{root: "x", roots: []string{"x"}, members: []string{"x", "y", "z"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deadSubgraphs = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// urlUnknown marks the non-constant parts of a URL pattern.
+const urlUnknown = "\x00"
+
+// httpClientCalls maps net/http client functions and (*http.Client)
+// methods to the index of their URL argument and the request method they
+// send ("" when the method is an argument, at index urlIdx-1).
+var httpClientCalls = map[string]struct {
+	urlIdx int
+	method string
+}{
+	"Get":                   {0, "GET"},
+	"Head":                  {0, "HEAD"},
+	"Post":                  {0, "POST"},
+	"PostForm":              {0, "POST"},
+	"NewRequest":            {1, ""},
+	"NewRequestWithContext": {2, ""},
+}
+
+// clientRoute is a discovered server route as matched by client calls.
+type clientRoute struct {
+	id          string
+	method      string
+	re          *regexp.Regexp // full path
+	suffix      *regexp.Regexp // path anchored at the end only
+	prefixKnown bool
+	confidence  float64
+	handlers    []string
+}
+
+// urlResolver resolves URL expressions to patterns in which non-constant
+// parts are urlUnknown.
+type urlResolver struct {
+	consts    *constResolver
+	memo      map[ssa.Value][]string
+	active    map[ssa.Value]bool
+	truncated bool // the current resolution hit the depth bound or a cycle
+}
+
+// LinkRemoteCalls resolves the URLs of HTTP client calls (http.Get / Post /
+// Head / PostForm, the same methods of *http.Client, and
+// http.NewRequest[WithContext], whose requests client.Do sends) and
+// matches them against the http_route nodes found by DiscoverHTTPRoutes
+// in any analyzed module. Each match becomes a remote_call edge from the
+// calling function to the route's handlers, so call graph traversals
+// cross process boundaries.
+//
+// URLs resolve to patterns: constants, concatenations, fmt.Sprintf,
+// path.Join / url.JoinPath, url.Parse and (*url.URL).String() over a URL
+// literal's Path, with unknown parts (hosts, configured base URLs) left
+// open. A URL with an unknown base matches a route whose full path is its
+// constant tail; a route under an unresolved prefix matches on its
+// pattern's suffix. Route parameters (:name, {name}, *rest) match any
+// segment. The method must agree when both sides know it.
+func LinkRemoteCalls(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Linking HTTP clients to server routes...")
+
+	var routes []clientRoute
+	for _, n := range cpg.Nodes {
+		if n.Kind != "http_route" {
+			continue
+		}
+		path, _ := n.Properties["path"].(string)
+		known, _ := n.Properties["path_resolved"].(bool)
+		handlers, _ := n.Properties["handlers"].([]string)
+		if !known || path == "" || path == "/" || len(handlers) == 0 {
+			continue
+		}
+		expr := routePathRegexp(path)
+		rt := clientRoute{
+			id:       n.ID,
+			re:       regexp.MustCompile("^" + expr + "$"),
+			suffix:   regexp.MustCompile(expr + "$"),
+			handlers: handlers,
+		}
+		rt.method, _ = n.Properties["method"].(string)
+		rt.prefixKnown, _ = n.Properties["prefix_resolved"].(bool)
+		rt.confidence, _ = n.Properties["confidence"].(float64)
+		routes = append(routes, rt)
+	}
+	if len(routes) == 0 {
+		prog.Log("Remote calls: no server routes to match")
+		return
+	}
+
+	funcs := knownFuncs(ssaResult)
+	u := &urlResolver{
+		consts: newConstResolver(funcs),
+		memo:   make(map[ssa.Value][]string),
+		active: make(map[ssa.Value]bool),
+	}
+
+	// The best match per caller and handler becomes its remote_call edge.
+	best := make(map[string]Edge)
+	var sites, resolvedSites int
+	for _, fn := range funcs {
+		if fn.Synthetic != "" {
+			continue
+		}
+		fnID := ""
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				urlArg, methods := u.clientCall(call.Common())
+				if urlArg == nil {
+					continue
+				}
+				sites++
+				file, line, col := instrPos(call, fset)
+				if file == "" {
+					continue
+				}
+				if fnID == "" {
+					fnID = ssaFuncNodeID(fn, fset, funcLookup)
+				}
+				if fnID == "" {
+					continue
+				}
+				site := posLookup.Get(file, line, col)
+
+				matched := false
+				for _, pat := range u.patterns(urlArg, 0) {
+					path, baseKnown := urlPath(pat)
+					if strings.Trim(path, "/"+urlUnknown) == "" {
+						continue
+					}
+					sample := strings.ReplaceAll(path, urlUnknown, "x")
+					for _, rt := range routes {
+						if !methodsAgree(methods, rt.method) {
+							continue
+						}
+						conf := rt.confidence
+						switch {
+						case rt.re.MatchString(sample):
+						case !rt.prefixKnown && rt.suffix.MatchString(sample):
+							conf *= 0.6
+						default:
+							continue
+						}
+						if !baseKnown {
+							conf *= 0.8
+						}
+						for _, h := range rt.handlers {
+							if h == fnID {
+								continue
+							}
+							key := fnID + "\x00" + h
+							if prev, ok := best[key]; ok && prev.Properties["confidence"].(float64) >= conf {
+								continue
+							}
+							best[key] = Edge{
+								Source: fnID, Target: h, Kind: "remote_call",
+								Properties: map[string]any{
+									"site":       site,
+									"method":     strings.Join(methods, ","),
+									"url":        strings.ReplaceAll(pat, urlUnknown, "*"),
+									"route":      rt.id,
+									"component":  modSet.Component(fn.Pkg.Pkg.Path()),
+									"confidence": math.Round(conf*100) / 100,
+								},
+							}
+						}
+						matched = true
+					}
+				}
+				if matched {
+					resolvedSites++
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(best))
+	for k := range best {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cpg.AddEdge(best[k])
+	}
+
+	prog.Log("Remote calls: %d client call sites, %d matched to routes, %d remote_call edges", sites, resolvedSites, len(keys))
+}
+
+// clientCall recognizes an HTTP client call, returning its URL argument
+// and the possible request methods (nil when unknown).
+func (u *urlResolver) clientCall(common *ssa.CallCommon) (ssa.Value, []string) {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Pkg == nil || callee.Pkg.Pkg.Path() != "net/http" {
+		return nil, nil
+	}
+	spec, ok := httpClientCalls[callee.Name()]
+	if !ok {
+		return nil, nil
+	}
+	if recv := callee.Signature.Recv(); recv != nil {
+		if n, ok := deref(recv.Type()).(*types.Named); !ok || n.Obj().Name() != "Client" {
+			return nil, nil
+		}
+	}
+	args := callArgsByIndex(common)
+	urlArg, ok := args[spec.urlIdx]
+	if !ok {
+		return nil, nil
+	}
+	if spec.method != "" {
+		return urlArg, []string{spec.method}
+	}
+	methods := u.consts.strings(args[spec.urlIdx-1])
+	for i, m := range methods {
+		methods[i] = strings.ToUpper(m)
+	}
+	return urlArg, methods
+}
+
+// methodsAgree reports whether a client sending one of methods may hit a
+// route serving routeMethod; unknown methods agree with anything.
+func methodsAgree(methods []string, routeMethod string) bool {
+	if len(methods) == 0 || routeMethod == "" {
+		return true
+	}
+	for _, m := range methods {
+		if m == routeMethod || (routeMethod == "GET" && m == "HEAD") {
+			return true
+		}
+	}
+	return false
+}
+
+// routeParamRe matches route path parameters: :name and {name[:regexp]}
+// segments, and *rest / {rest...} catch-alls.
+var routeParamRe = regexp.MustCompile(`:[A-Za-z_][A-Za-z0-9_]*|\{[^}/]*\.\.\.\}|\{[^}/]*\}|\*[A-Za-z_]*`)
+
+// routePathRegexp converts a route path to a regular expression matching
+// the concrete paths it serves. ServeMux subtree patterns (trailing "/")
+// match everything below them.
+func routePathRegexp(path string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range routeParamRe.FindAllStringIndex(path, -1) {
+		b.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+		param := path[loc[0]:loc[1]]
+		if strings.HasPrefix(param, "*") || strings.HasSuffix(param, "...}") {
+			b.WriteString(".*")
+		} else {
+			b.WriteString("[^/]+")
+		}
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(path[last:]))
+	expr := b.String()
+	if strings.HasSuffix(path, "/") {
+		expr += ".*"
+	}
+	return expr
+}
+
+// urlPath extracts the path of a URL pattern, dropping scheme, host, query
+// and fragment. baseKnown is false when the path is preceded by a
+// non-constant part (a configured base URL or path prefix).
+func urlPath(pat string) (string, bool) {
+	if i := strings.IndexAny(pat, "?#"); i >= 0 {
+		pat = pat[:i]
+	}
+	if i := strings.Index(pat, "://"); i >= 0 {
+		rest := pat[i+3:]
+		j := strings.Index(rest, "/")
+		if j < 0 {
+			return "/", true
+		}
+		host := rest[:j]
+		// An unknown host may stand for a base URL with a path.
+		return rest[j:], !strings.Contains(host, urlUnknown)
+	}
+	if strings.HasPrefix(pat, "/") {
+		return pat, true
+	}
+	// Base URL unknown: the path starts after it.
+	if rest, ok := strings.CutPrefix(pat, urlUnknown); ok {
+		if !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
+		}
+		return rest, false
+	}
+	if k := strings.Index(pat, "/"); k >= 0 {
+		return pat[k:], false
+	}
+	return "", false
+}
+
+// patterns returns the URL patterns v may hold, at most constMaxValues.
+// Results that did not hit the depth bound or a cycle are memoized.
+func (u *urlResolver) patterns(v ssa.Value, depth int) []string {
+	if out, ok := u.memo[v]; ok {
+		return out
+	}
+	if depth > constMaxDepth || u.active[v] {
+		if vals := u.consts.strings(v); vals != nil {
+			return vals
+		}
+		u.truncated = true
+		return []string{urlUnknown}
+	}
+	outer := u.truncated
+	u.truncated = false
+	u.active[v] = true
+	out := u.resolvePatterns(v, depth)
+	delete(u.active, v)
+	if !u.truncated {
+		u.memo[v] = out
+	}
+	u.truncated = u.truncated || outer
+	return out
+}
+
+func (u *urlResolver) resolvePatterns(v ssa.Value, depth int) []string {
+	if vals := u.consts.strings(v); vals != nil {
+		return vals
+	}
+	var out []string
+	switch x := v.(type) {
+	case *ssa.MakeInterface:
+		return u.patterns(x.X, depth+1)
+	case *ssa.ChangeType:
+		return u.patterns(x.X, depth+1)
+	case *ssa.Convert:
+		if isStringValue(x.X) {
+			return u.patterns(x.X, depth+1)
+		}
+	case *ssa.BinOp:
+		if x.Op == token.ADD && isStringValue(x) {
+			return joinPatterns(u.patterns(x.X, depth+1), u.patterns(x.Y, depth+1), "")
+		}
+	case *ssa.Phi:
+		for _, e := range x.Edges {
+			out = append(out, u.patterns(e, depth+1)...)
+		}
+		return capPatterns(out)
+	case *ssa.Parameter:
+		fn := x.Parent()
+		idx := -1
+		for i, p := range fn.Params {
+			if p == x {
+				idx = i
+			}
+		}
+		// Callers outside the analyzed code may pass anything.
+		sites, ok := u.consts.knownCallers(fn)
+		if idx < 0 || !ok {
+			break
+		}
+		for _, common := range sites {
+			if idx >= len(common.Args) {
+				return []string{urlUnknown}
+			}
+			out = append(out, u.patterns(common.Args[idx], depth+1)...)
+		}
+		return capPatterns(out)
+	case *ssa.Call:
+		return u.callPatterns(&x.Call, depth)
+	}
+	return []string{urlUnknown}
+}
+
+// callPatterns resolves the URL patterns a call returns.
+func (u *urlResolver) callPatterns(common *ssa.CallCommon, depth int) []string {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Pkg == nil {
+		return []string{urlUnknown}
+	}
+	pkg, name := callee.Pkg.Pkg.Path(), callee.Name()
+	args := callArgsByIndex(common)
+	switch {
+	case pkg == "fmt" && name == "Sprintf":
+		return u.sprintfPatterns(args, depth)
+	case pkg == "path" && name == "Join":
+		return u.joinArgs(args, 0, depth)
+	case pkg == "net/url" && name == "JoinPath" && callee.Signature.Recv() == nil:
+		return u.joinArgs(args, 0, depth)
+	case pkg == "net/url" && name == "String" && callee.Signature.Recv() != nil:
+		return u.urlStruct(common.Args[0], depth+1)
+	case modSet.IsKnownPkg(pkg) && callee.Signature.Results().Len() == 1 && len(callee.Blocks) > 0:
+		var out []string
+		for _, b := range callee.Blocks {
+			if ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok {
+				out = append(out, u.patterns(ret.Results[0], depth+1)...)
+			}
+		}
+		if len(out) > 0 {
+			return capPatterns(out)
+		}
+	}
+	return []string{urlUnknown}
+}
+
+// urlStruct resolves the patterns of a *url.URL value: url.Parse results,
+// URL literals (through their Path field), JoinPath and ResolveReference.
+func (u *urlResolver) urlStruct(v ssa.Value, depth int) []string {
+	if depth > constMaxDepth {
+		return []string{urlUnknown}
+	}
+	switch x := v.(type) {
+	case *ssa.Extract:
+		if call, ok := x.Tuple.(*ssa.Call); ok && x.Index == 0 {
+			callee := call.Call.StaticCallee()
+			if callee != nil && callee.Pkg != nil && callee.Pkg.Pkg.Path() == "net/url" &&
+				(callee.Name() == "Parse" || callee.Name() == "ParseRequestURI") {
+				return u.patterns(call.Call.Args[0], depth+1)
+			}
+		}
+	case *ssa.Alloc:
+		var out []string
+		for _, ref := range *x.Referrers() {
+			fa, ok := ref.(*ssa.FieldAddr)
+			if !ok || fa.X != x || fa.Referrers() == nil {
+				continue
+			}
+			if fv := fieldVar(x.Type(), fa.Field); fv == nil || fv.Name() != "Path" {
+				continue
+			}
+			for _, use := range *fa.Referrers() {
+				if st, ok := use.(*ssa.Store); ok && st.Addr == fa {
+					out = append(out, joinPatterns([]string{urlUnknown}, u.patterns(st.Val, depth+1), "")...)
+				}
+			}
+		}
+		if len(out) > 0 {
+			return capPatterns(out)
+		}
+	case *ssa.Call:
+		callee := x.Call.StaticCallee()
+		if callee == nil || callee.Pkg == nil || callee.Pkg.Pkg.Path() != "net/url" || callee.Signature.Recv() == nil {
+			break
+		}
+		switch callee.Name() {
+		case "JoinPath":
+			return joinPatterns(u.urlStruct(x.Call.Args[0], depth+1), u.joinArgs(callArgsByIndex(&x.Call), 0, depth), "/")
+		case "ResolveReference":
+			refs := u.urlStruct(x.Call.Args[1], depth+1)
+			var out []string
+			for _, ref := range refs {
+				if strings.HasPrefix(strings.TrimPrefix(ref, urlUnknown), "/") {
+					out = append(out, urlUnknown+strings.TrimPrefix(ref, urlUnknown))
+				} else {
+					out = append(out, joinPatterns(u.urlStruct(x.Call.Args[0], depth+1), []string{ref}, "/")...)
+				}
+			}
+			return capPatterns(out)
+		}
+	}
+	return []string{urlUnknown}
+}
+
+// joinArgs joins the patterns of args[from:] with "/" the way path.Join
+// does, collapsing duplicate slashes.
+func (u *urlResolver) joinArgs(args map[int]ssa.Value, from, depth int) []string {
+	out := []string{""}
+	for i := from; i < from+len(args); i++ {
+		arg, ok := args[i]
+		if !ok {
+			break
+		}
+		sep := "/"
+		if i == from {
+			sep = ""
+		}
+		out = joinPatterns(out, u.patterns(arg, depth+1), sep)
+	}
+	for i, p := range out {
+		for strings.Contains(p, "//") && !strings.Contains(p, "://") {
+			p = strings.ReplaceAll(p, "//", "/")
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// sprintfPatterns formats each constant format with the operands'
+// patterns; %% prints a percent sign.
+func (u *urlResolver) sprintfPatterns(args map[int]ssa.Value, depth int) []string {
+	formats := u.consts.strings(args[0])
+	if formats == nil {
+		return []string{urlUnknown}
+	}
+	var out []string
+	for _, format := range formats {
+		parts := []string{""}
+		op := 1
+		for i := 0; i < len(format); i++ {
+			if format[i] != '%' {
+				parts = joinPatterns(parts, []string{format[i : i+1]}, "")
+				continue
+			}
+			j := i + 1
+			for j < len(format) && strings.IndexByte("+-# 0123456789.*", format[j]) >= 0 {
+				j++
+			}
+			if j >= len(format) {
+				break
+			}
+			if format[j] == '%' {
+				parts = joinPatterns(parts, []string{"%"}, "")
+			} else {
+				vals := []string{urlUnknown}
+				if arg, ok := args[op]; ok {
+					vals = u.patterns(arg, depth+1)
+				}
+				parts = joinPatterns(parts, vals, "")
+				op++
+			}
+			i = j
+		}
+		out = append(out, parts...)
+	}
+	return capPatterns(out)
+}
+
+// joinPatterns concatenates every pattern of xs with every pattern of ys,
+// separated by sep, merging adjacent unknown parts.
+func joinPatterns(xs, ys []string, sep string) []string {
+	var out []string
+	for _, x := range xs {
+		for _, y := range ys {
+			s := x
+			if sep != "" && !strings.HasSuffix(x, sep) && !strings.HasPrefix(y, sep) {
+				s += sep
+			}
+			s += y
+			for strings.Contains(s, urlUnknown+urlUnknown) {
+				s = strings.ReplaceAll(s, urlUnknown+urlUnknown, urlUnknown)
+			}
+			out = append(out, s)
+		}
+	}
+	return capPatterns(out)
+}
+
+// capPatterns sorts and dedups patterns, keeping at most constMaxValues.
+func capPatterns(ps []string) []string {
+	sort.Strings(ps)
+	out := ps[:0]
+	for i, p := range ps {
+		if i == 0 || p != ps[i-1] {
+			out = append(out, p)
+		}
+	}
+	if len(out) > constMaxValues {
+		out = out[:constMaxValues]
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestURLPatternsParams(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/lib", Dir: "/src/example.com/lib"}, nil)
+
+	prog, _ := buildTestProgram(t, testPkg{"example.com/lib", `package lib
+
+func sink(string) {}
+
+func get(url string)   { sink(url) }
+func Fetch(url string) { sink(url) }
+func taken(url string) { sink(url) }
+
+func run(base string) {
+	get("/api/v1/query")
+	get(base + "/api/v1/series")
+	Fetch("/api/v1/labels")
+	taken("/federate")
+	use(taken)
+}
+
+func use(f func(string)) { f("/x") }
+`})
+
+	pkg := prog.ImportedPackage("example.com/lib")
+	var funcs []*ssa.Function
+	for _, m := range pkg.Members {
+		if fn, ok := m.(*ssa.Function); ok && len(fn.Blocks) > 0 {
+			funcs = append(funcs, fn)
+		}
+	}
+	u := &urlResolver{
+		consts: newConstResolver(funcs),
+		memo:   make(map[ssa.Value][]string),
+		active: make(map[ssa.Value]bool),
+	}
+	tests := []struct {
+		fn   string
+		want []string
+	}{
+		{"get", []string{urlUnknown + "/api/v1/series", "/api/v1/query"}},
+		{"Fetch", []string{urlUnknown}}, // exported: unseen callers
+		{"taken", []string{urlUnknown}}, // used as a value
+	}
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			got := u.patterns(pkg.Func(tt.fn).Params[0], 0)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("patterns = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
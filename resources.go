@@ -0,0 +1,420 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// resourceKind describes how a resource type is released.
+type resourceKind struct {
+	release string // method that releases the value
+	field   string // released through this field (Response.Body), or ""
+}
+
+// resourceTypes are the types whose values must be released once created,
+// keyed by "pkgpath.Name" of the (dereferenced) named type.
+var resourceTypes = map[string]resourceKind{
+	"os.File":                {release: "Close"},
+	"net/http.Response":      {release: "Close", field: "Body"},
+	"database/sql.Rows":      {release: "Close"},
+	"database/sql.Stmt":      {release: "Close"},
+	"database/sql.Conn":      {release: "Close"},
+	"net.Conn":               {release: "Close"},
+	"net.PacketConn":         {release: "Close"},
+	"net.Listener":           {release: "Close"},
+	"net.TCPConn":            {release: "Close"},
+	"net.UDPConn":            {release: "Close"},
+	"net.UnixConn":           {release: "Close"},
+	"net.TCPListener":        {release: "Close"},
+	"net.UnixListener":       {release: "Close"},
+	"crypto/tls.Conn":        {release: "Close"},
+	"compress/gzip.Reader":   {release: "Close"},
+	"compress/gzip.Writer":   {release: "Close"},
+	"time.Ticker":            {release: "Stop"},
+	"archive/zip.ReadCloser": {release: "Close"},
+}
+
+// resourceLeak is one resource that some path drops without releasing.
+type resourceLeak struct {
+	create      *ssa.Call
+	exit        *ssa.Return // return reached while the resource is live
+	resource    string      // type name, e.g. *os.File
+	creator     string
+	release     string // e.g. Close, Body.Close, Stop
+	errorReturn bool   // exit returns a non-nil error
+	never       bool   // never released nor handed off anywhere
+}
+
+// AnalyzeResources tracks resources (files, HTTP response bodies, sql.Rows,
+// network connections and listeners, tickers, ...) created by calls outside
+// the analyzed module from their creation to every return of the function.
+// The value is followed through the data flow graph (phis, conversions,
+// interfaces, the Body field of an *http.Response); a path is closed off by
+//
+//   - the release method (Close, or Stop for tickers), called or deferred;
+//   - a hand-off of ownership: passing it to a function of the analyzed
+//     module or a dynamic call, capturing it in a closure, storing it,
+//     sending it on a channel, or returning it.
+//
+// Passing it to a library function (io.ReadAll, bufio.NewReader, ...) is a
+// use, not a hand-off. Paths on which the creator's error is non-nil (or the
+// value is nil) are not followed. A return reachable without a release is
+// recorded in resource_leaks on the function, noting whether it is an error
+// return, the typical "return err before defer f.Close()" leak.
+func AnalyzeResources(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Analyzing resource lifetimes...")
+
+	leaksByFunc := make(map[string][]map[string]any)
+	var created, leaked, errorReturns int
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+		leaks, n := resourceLeaks(fn)
+		created += n
+		if len(leaks) == 0 {
+			continue
+		}
+		fnID := ssaFuncNodeID(fn, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		for _, l := range leaks {
+			file, line, col := instrPos(l.create, fset)
+			if file == "" {
+				continue
+			}
+			exitLine := 0
+			if l.exit != nil {
+				_, exitLine, _ = instrPos(l.exit, fset)
+			}
+			leaksByFunc[fnID] = append(leaksByFunc[fnID], map[string]any{
+				"resource":     l.resource,
+				"creator":      l.creator,
+				"release":      l.release,
+				"site":         posLookup.Get(file, line, col),
+				"file":         file,
+				"line":         line,
+				"exit_line":    exitLine,
+				"error_return": l.errorReturn,
+				"never":        l.never,
+			})
+			leaked++
+			if l.errorReturn {
+				errorReturns++
+			}
+		}
+	}
+
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if leaks, ok := leaksByFunc[n.ID]; ok && n.Kind == "function" {
+			if n.Properties == nil {
+				n.Properties = map[string]any{}
+			}
+			n.Properties["resource_leaks"] = leaks
+		}
+	}
+
+	prog.Log("Resources: %d created, %d not released on some path (%d on error returns)", created, leaked, errorReturns)
+}
+
+// resourceLeaks finds the resources created in fn that reach a return
+// unreleased. It also returns how many resources fn creates.
+func resourceLeaks(fn *ssa.Function) ([]resourceLeak, int) {
+	var leaks []resourceLeak
+	created := 0
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || !resourceCreator(&call.Call) {
+				continue
+			}
+			idx, rk, name := resourceResult(call.Type())
+			if idx < -1 {
+				continue
+			}
+			created++
+
+			var res, errVal ssa.Value
+			if idx == -1 {
+				res = call
+			} else if call.Referrers() != nil {
+				for _, ref := range *call.Referrers() {
+					ex, ok := ref.(*ssa.Extract)
+					if !ok {
+						continue
+					}
+					if ex.Index == idx {
+						res = ex
+					} else if isErrorType(ex.Type()) {
+						errVal = ex
+					}
+				}
+			}
+
+			kills := make(map[ssa.Instruction]bool)
+			released := false
+			pruned := make(map[[2]int]bool)
+			if res != nil {
+				released = resourceKills(res, rk, kills)
+				pruneNilBranches(res, false, pruned)
+			}
+			if errVal != nil {
+				pruneNilBranches(errVal, true, pruned)
+			}
+
+			exit := resourceExit(call, kills, pruned)
+			if exit == nil {
+				continue
+			}
+			release := rk.release
+			if rk.field != "" {
+				release = rk.field + "." + release
+			}
+			leaks = append(leaks, resourceLeak{
+				create:      call,
+				exit:        exit,
+				resource:    name,
+				creator:     calleeName(&call.Call),
+				release:     release,
+				errorReturn: returnsError(exit),
+				never:       !released && len(kills) == 0,
+			})
+		}
+	}
+	return leaks, created
+}
+
+// resourceCreator reports whether a call may create a resource: it targets
+// a function or method outside the analyzed module.
+func resourceCreator(common *ssa.CallCommon) bool {
+	if common.IsInvoke() {
+		pkg := common.Method.Pkg()
+		return pkg == nil || !modSet.IsKnownPkg(pkg.Path())
+	}
+	callee := common.StaticCallee()
+	return callee != nil && callee.Pkg != nil && !modSet.IsKnownPkg(callee.Pkg.Pkg.Path())
+}
+
+// resourceResult finds the resource among a call's results: -1 for the
+// single result, the tuple index otherwise, or -2 if there is none.
+func resourceResult(t types.Type) (int, resourceKind, string) {
+	if tup, ok := t.(*types.Tuple); ok {
+		for i := 0; i < tup.Len(); i++ {
+			if rk, ok := resourceTypeOf(tup.At(i).Type()); ok {
+				return i, rk, tup.At(i).Type().String()
+			}
+		}
+		return -2, resourceKind{}, ""
+	}
+	if rk, ok := resourceTypeOf(t); ok {
+		return -1, rk, t.String()
+	}
+	return -2, resourceKind{}, ""
+}
+
+func resourceTypeOf(t types.Type) (resourceKind, bool) {
+	n, ok := deref(t).(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return resourceKind{}, false
+	}
+	rk, ok := resourceTypes[n.Obj().Pkg().Path()+"."+n.Obj().Name()]
+	return rk, ok
+}
+
+// resourceKills follows res through the data flow graph and marks the
+// instructions that release it or hand it off. Reports whether any of them
+// is a release.
+func resourceKills(res ssa.Value, rk resourceKind, kills map[ssa.Instruction]bool) bool {
+	released := false
+	seen := make(map[ssa.Value]bool)
+	work := []ssa.Value{res}
+	for len(work) > 0 {
+		v := work[len(work)-1]
+		work = work[:len(work)-1]
+		if seen[v] || v.Referrers() == nil {
+			continue
+		}
+		seen[v] = true
+		for _, ref := range *v.Referrers() {
+			switch r := ref.(type) {
+			case *ssa.Phi:
+				work = append(work, r)
+			case *ssa.ChangeType:
+				work = append(work, r)
+			case *ssa.ChangeInterface:
+				work = append(work, r)
+			case *ssa.MakeInterface:
+				work = append(work, r)
+			case *ssa.TypeAssert:
+				work = append(work, r)
+			case *ssa.FieldAddr:
+				// resp.Body: the body is what gets closed.
+				if rk.field == "" || structFieldName(deref(r.X.Type()), r.Field) != rk.field || r.Referrers() == nil {
+					continue
+				}
+				for _, use := range *r.Referrers() {
+					if load, ok := use.(*ssa.UnOp); ok && load.Op == token.MUL {
+						work = append(work, load)
+					}
+				}
+			case ssa.CallInstruction:
+				common := r.Common()
+				if releaseCall(common, v, rk.release) {
+					kills[r] = true
+					released = true
+					continue
+				}
+				if _, isGo := r.(*ssa.Go); isGo {
+					kills[r] = true
+					continue
+				}
+				if common.IsInvoke() && common.Value == v {
+					continue // Read, Write, ... on the resource
+				}
+				if resourceCreator(common) && !passesToDynamic(common, v) {
+					continue // library call using it
+				}
+				for _, arg := range common.Args {
+					if arg == v {
+						kills[r] = true
+					}
+				}
+			case *ssa.MakeClosure, *ssa.Store, *ssa.Send, *ssa.MapUpdate, *ssa.Return:
+				kills[r] = true
+			}
+		}
+	}
+	return released
+}
+
+// releaseCall reports whether common calls the release method on v.
+func releaseCall(common *ssa.CallCommon, v ssa.Value, release string) bool {
+	if common.IsInvoke() {
+		return common.Value == v && common.Method.Name() == release
+	}
+	callee := common.StaticCallee()
+	return callee != nil && callee.Signature.Recv() != nil && callee.Name() == release &&
+		len(common.Args) > 0 && common.Args[0] == v
+}
+
+// passesToDynamic reports whether common is a call through a function
+// value that receives v; the callee is unknown, so it may take ownership.
+func passesToDynamic(common *ssa.CallCommon, v ssa.Value) bool {
+	if common.IsInvoke() || common.StaticCallee() != nil {
+		return false
+	}
+	for _, arg := range common.Args {
+		if arg == v {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneNilBranches marks the CFG edges taken when v is nil (for the
+// resource) or non-nil (for the creator's error): the resource does not
+// exist there.
+func pruneNilBranches(v ssa.Value, isErr bool, pruned map[[2]int]bool) {
+	if v.Referrers() == nil {
+		return
+	}
+	for _, ref := range *v.Referrers() {
+		cmp, ok := ref.(*ssa.BinOp)
+		if !ok || (cmp.Op != token.EQL && cmp.Op != token.NEQ) || cmp.Referrers() == nil {
+			continue
+		}
+		other := cmp.Y
+		if other == v {
+			other = cmp.X
+		}
+		if c, ok := other.(*ssa.Const); !ok || c.Value != nil {
+			continue
+		}
+		// Successor taken when the comparison holds.
+		dead := 0
+		if (cmp.Op == token.NEQ) != isErr {
+			dead = 1
+		}
+		for _, use := range *cmp.Referrers() {
+			if ifInstr, ok := use.(*ssa.If); ok {
+				b := ifInstr.Block()
+				pruned[[2]int{b.Index, b.Succs[dead].Index}] = true
+			}
+		}
+	}
+}
+
+// resourceExit searches the CFG breadth-first from the creating call for a
+// return reached without passing a kill, so the nearest one is reported.
+// The creating call itself ends a path.
+func resourceExit(create *ssa.Call, kills map[ssa.Instruction]bool, pruned map[[2]int]bool) *ssa.Return {
+	type cursor struct {
+		b     *ssa.BasicBlock
+		start int
+	}
+	start := create.Block()
+	queue := []cursor{{start, instrIndex(start, create) + 1}}
+	visited := make(map[int]bool)
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		blocked := false
+		for _, instr := range c.b.Instrs[c.start:] {
+			if kills[instr] || instr == ssa.Instruction(create) {
+				blocked = true
+				break
+			}
+			if ret, ok := instr.(*ssa.Return); ok {
+				return ret
+			}
+		}
+		if blocked {
+			continue
+		}
+		for _, s := range c.b.Succs {
+			if pruned[[2]int{c.b.Index, s.Index}] || visited[s.Index] {
+				continue
+			}
+			visited[s.Index] = true
+			queue = append(queue, cursor{s, 0})
+		}
+	}
+	return nil
+}
+
+// returnsError reports whether ret returns an error other than nil.
+func returnsError(ret *ssa.Return) bool {
+	for _, r := range ret.Results {
+		if !isErrorType(r.Type()) {
+			continue
+		}
+		if c, ok := r.(*ssa.Const); ok && c.Value == nil {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+func isErrorType(t types.Type) bool {
+	return types.Identical(t, errorType)
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// httpMethodNames maps router registration methods named after an HTTP
+// method to that method.
+var httpMethodNames = map[string]string{
+	"Get": "GET", "GET": "GET",
+	"Post": "POST", "POST": "POST",
+	"Put": "PUT", "PUT": "PUT",
+	"Patch": "PATCH", "PATCH": "PATCH",
+	"Delete": "DELETE", "Del": "DELETE", "DELETE": "DELETE",
+	"Head":    "HEAD",
+	"Options": "OPTIONS",
+}
+
+// httpRoute is one (method, path) served by a route registration.
+type httpRoute struct {
+	method      string // "" when any method matches or the method is unknown
+	path        string // prefix + pattern; "" when the pattern is not constant
+	pattern     string
+	prefix      string
+	pathKnown   bool
+	prefixKnown bool
+}
+
+// routeResolver resolves route patterns, router prefixes and handlers.
+type routeResolver struct {
+	prog   *ssa.Program
+	consts *constResolver
+}
+
+// DiscoverHTTPRoutes finds HTTP route registrations (http.Handle /
+// HandleFunc, (*http.ServeMux).Handle, prometheus route.Router Get / Post /
+// ..., gorilla/mux, chi and httprouter) and resolves each route's method,
+// path and handler functions.
+//
+// Patterns resolve through constant propagation; Go 1.22 "METHOD /path"
+// patterns and gorilla .Methods(...) chains supply the method. The path
+// includes the router's prefix when it can be followed: WithPrefix /
+// PathPrefix().Subrouter() chains, router parameters whose static callers
+// all pass resolvable routers, and routers mounted under
+// http.StripPrefix or chi Mount. Handlers are followed through
+// http.HandlerFunc conversions, method values and wrapper calls taking
+// the real handler as an argument (middleware, instrumentation).
+//
+// Emits an http_route node per (method, path) with a confidence score,
+// registers_route edges from the registration call and routes_to edges to
+// the handler functions.
+func DiscoverHTTPRoutes(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Discovering HTTP routes...")
+
+	funcs := knownFuncs(ssaResult)
+	rr := &routeResolver{
+		prog:   ssaResult.Prog,
+		consts: newConstResolver(funcs),
+	}
+
+	var routes, resolved, handled int
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok || !isHTTPRegistration(call.Common()) {
+					continue
+				}
+				file, line, col := instrPos(call, fset)
+				if file == "" {
+					continue
+				}
+				site := posLookup.Get(file, line, col)
+				if site == "" {
+					continue
+				}
+				found, handlerArg := rr.routes(call)
+				if len(found) == 0 {
+					continue
+				}
+
+				var handlerIDs []string
+				seen := make(map[string]bool)
+				if handlerArg != nil {
+					for _, h := range rr.handlers(handlerArg, 0) {
+						id := ""
+						if src := sourceFunc(rr.prog, h); src != nil {
+							id = ssaFuncNodeID(src, fset, funcLookup)
+						}
+						if id != "" && !seen[id] {
+							seen[id] = true
+							handlerIDs = append(handlerIDs, id)
+						}
+					}
+				}
+				sort.Strings(handlerIDs)
+
+				fnID := ""
+				if fn.Synthetic == "" {
+					fnID = ssaFuncNodeID(fn, fset, funcLookup)
+				}
+				pkgPath := fn.Pkg.Pkg.Path()
+				for _, rt := range found {
+					conf := routeConfidence(rt, len(found), len(handlerIDs) > 0)
+					display := rt.path
+					if !rt.pathKnown {
+						display = "?"
+					}
+					id := RouteID(rt.method, display, site)
+					cpg.AddNode(Node{
+						ID:             id,
+						Kind:           "http_route",
+						Name:           strings.TrimSpace(rt.method + " " + display),
+						Package:        modSet.RelPkg(pkgPath),
+						File:           file,
+						Line:           line,
+						Col:            col,
+						ParentFunction: fnID,
+						Properties: map[string]any{
+							"method":          rt.method,
+							"path":            rt.path,
+							"pattern":         rt.pattern,
+							"prefix":          rt.prefix,
+							"path_resolved":   rt.pathKnown,
+							"prefix_resolved": rt.prefixKnown,
+							"router":          errCalleeName(call.Common()),
+							"handlers":        append([]string{}, handlerIDs...),
+							"component":       modSet.Component(pkgPath),
+							"confidence":      conf,
+						},
+					})
+					cpg.AddEdge(Edge{Source: site, Target: id, Kind: "registers_route"})
+					for _, h := range handlerIDs {
+						cpg.AddEdge(Edge{Source: id, Target: h, Kind: "routes_to"})
+					}
+					routes++
+					if rt.pathKnown && rt.prefixKnown {
+						resolved++
+					}
+					if len(handlerIDs) > 0 {
+						handled++
+					}
+				}
+			}
+		}
+	}
+
+	prog.Log("HTTP routes: %d (%d with full path, %d with resolved handler)", routes, resolved, handled)
+}
+
+// routeConfidence scores how much of a route was resolved: a constant
+// pattern under a known prefix, a single candidate value and a known
+// handler give 1.0.
+func routeConfidence(rt httpRoute, candidates int, hasHandler bool) float64 {
+	conf := 1.0
+	switch {
+	case !rt.pathKnown:
+		conf -= 0.5
+	case !rt.prefixKnown:
+		conf -= 0.3
+	}
+	if candidates > 1 {
+		conf -= 0.1
+	}
+	if !hasHandler {
+		conf -= 0.2
+	}
+	return conf
+}
+
+// routeCallName is the function or method name a call targets.
+func routeCallName(common *ssa.CallCommon) string {
+	if common.IsInvoke() {
+		return common.Method.Name()
+	}
+	if callee := common.StaticCallee(); callee != nil {
+		if origin := callee.Origin(); origin != nil {
+			callee = origin
+		}
+		return callee.Name()
+	}
+	return ""
+}
+
+// routes lists the (method, path) pairs a registration call serves and
+// returns its handler argument.
+func (rr *routeResolver) routes(call ssa.CallInstruction) ([]httpRoute, ssa.Value) {
+	common := call.Common()
+	name := routeCallName(common)
+	args := callArgsByIndex(common)
+	n := len(args)
+	if n == 0 {
+		return nil, nil
+	}
+
+	methodArg, pathArg, handlerArg := ssa.Value(nil), ssa.Value(nil), args[n-1]
+	switch {
+	case n == 3 && isStringValue(args[0]) && isStringValue(args[1]):
+		// chi Method / MethodFunc, httprouter Handle(method, path, h).
+		methodArg, pathArg = args[0], args[1]
+	case n >= 2 && isStringValue(args[0]):
+		pathArg = args[0]
+	}
+
+	var methods []string
+	switch {
+	case methodArg != nil:
+		methods = rr.consts.strings(methodArg)
+	case httpMethodNames[name] != "":
+		methods = []string{httpMethodNames[name]}
+	default:
+		methods = rr.chainedMethods(call)
+	}
+
+	recv := metricReceiver(common)
+	var patterns []string
+	patternKnown := true
+	if pathArg != nil {
+		patterns = rr.consts.strings(pathArg)
+		patternKnown = patterns != nil
+	} else {
+		// gorilla: r.Path("/x").Methods("GET").Handler(h)
+		var chainMethods []string
+		patterns, chainMethods, recv = rr.routeChain(recv)
+		patternKnown = patterns != nil
+		if len(methods) == 0 {
+			methods = chainMethods
+		}
+	}
+	if !patternKnown {
+		patterns = []string{""}
+	}
+	if len(methods) == 0 {
+		methods = []string{""}
+	}
+
+	prefixes := []routePrefix{{known: true}}
+	if recv != nil {
+		prefixes = rr.prefixes(recv, 0)
+	}
+
+	var out []httpRoute
+	for _, m := range methods {
+		for _, p := range patterns {
+			method, pattern := m, p
+			// Go 1.22 ServeMux patterns: "[METHOD ][HOST]/[PATH]".
+			if i := strings.IndexByte(pattern, ' '); i > 0 && method == "" {
+				method, pattern = pattern[:i], strings.TrimLeft(pattern[i+1:], " ")
+			}
+			for _, prefix := range prefixes {
+				out = append(out, httpRoute{
+					method:      strings.ToUpper(method),
+					path:        prefix.value + pattern,
+					pattern:     pattern,
+					prefix:      prefix.value,
+					pathKnown:   patternKnown,
+					prefixKnown: prefix.known,
+				})
+			}
+		}
+	}
+	if len(out) > constMaxValues {
+		out = out[:constMaxValues]
+	}
+	return out, handlerArg
+}
+
+// chainedMethods returns the methods set by a gorilla .Methods(...) call
+// on the route a registration returns.
+func (rr *routeResolver) chainedMethods(call ssa.CallInstruction) []string {
+	v, ok := call.(*ssa.Call)
+	if !ok || v.Referrers() == nil {
+		return nil
+	}
+	for _, ref := range *v.Referrers() {
+		c, ok := ref.(*ssa.Call)
+		if !ok || routeCallName(&c.Call) != "Methods" || metricReceiver(&c.Call) != v {
+			continue
+		}
+		return rr.varargStrings(&c.Call)
+	}
+	return nil
+}
+
+// varargStrings resolves every variadic string argument of a call, or
+// returns nil when one is not constant.
+func (rr *routeResolver) varargStrings(common *ssa.CallCommon) []string {
+	var out []string
+	for _, arg := range callArgsByIndex(common) {
+		vals := rr.consts.strings(arg)
+		if vals == nil {
+			return nil
+		}
+		out = append(out, vals...)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// routeChain walks a gorilla route builder chain (Path, PathPrefix,
+// Methods, Name, ...) back to the router, returning the path, methods and
+// the router value.
+func (rr *routeResolver) routeChain(v ssa.Value) ([]string, []string, ssa.Value) {
+	var paths, methods []string
+	for i := 0; i < constMaxDepth; i++ {
+		c, ok := v.(*ssa.Call)
+		if !ok {
+			break
+		}
+		recv := metricReceiver(&c.Call)
+		if recv == nil || !isRouteBuilder(&c.Call) {
+			break
+		}
+		switch routeCallName(&c.Call) {
+		case "Path", "PathPrefix":
+			if paths == nil {
+				if args := callArgsByIndex(&c.Call); len(args) == 1 {
+					paths = rr.consts.strings(args[0])
+				}
+			}
+		case "Methods":
+			if methods == nil {
+				methods = rr.varargStrings(&c.Call)
+			}
+		}
+		v = recv
+	}
+	return paths, methods, v
+}
+
+// isRouteBuilder reports whether a call is a method of a router package
+// returning a route (not a router) to configure further.
+func isRouteBuilder(common *ssa.CallCommon) bool {
+	callee := common.StaticCallee()
+	if callee == nil || callee.Pkg == nil || !httpRouterPkgs[callee.Pkg.Pkg.Path()] {
+		return false
+	}
+	res := callee.Signature.Results()
+	if res.Len() != 1 {
+		return false
+	}
+	n, ok := deref(res.At(0).Type()).(*types.Named)
+	return ok && n.Obj().Name() == "Route"
+}
+
+// routePrefix is one path prefix a router may carry; known is false for
+// routers mounted under a non-constant prefix or otherwise untraceable.
+type routePrefix struct {
+	value string
+	known bool
+}
+
+// unknownPrefix stands for a router whose prefix cannot be resolved.
+var unknownPrefix = []routePrefix{{}}
+
+// prefixes resolves the path prefixes routes registered on router v get.
+// Fresh routers have none; WithPrefix and PathPrefix().Subrouter() add
+// one; other router methods returning a router keep the receiver's. A
+// router parameter takes the prefixes of every static caller's argument
+// when those are all of its callers, and is unknown otherwise.
+func (rr *routeResolver) prefixes(v ssa.Value, depth int) []routePrefix {
+	if depth > constMaxDepth {
+		return unknownPrefix
+	}
+	switch x := v.(type) {
+	case *ssa.MakeInterface:
+		return rr.prefixes(x.X, depth+1)
+	case *ssa.ChangeInterface:
+		return rr.prefixes(x.X, depth+1)
+	case *ssa.ChangeType:
+		return rr.prefixes(x.X, depth+1)
+	case *ssa.Phi:
+		var out []routePrefix
+		for _, e := range x.Edges {
+			out = append(out, rr.prefixes(e, depth+1)...)
+		}
+		return dedupPrefixes(out)
+	case *ssa.Parameter:
+		fn := x.Parent()
+		idx := -1
+		for i, p := range fn.Params {
+			if p == x {
+				idx = i
+			}
+		}
+		// Callers outside the analyzed code may pass any router.
+		sites, ok := rr.consts.knownCallers(fn)
+		if !ok || idx < 0 {
+			return unknownPrefix
+		}
+		var out []routePrefix
+		for _, common := range sites {
+			if idx >= len(common.Args) {
+				return unknownPrefix
+			}
+			out = append(out, rr.prefixes(common.Args[idx], depth+1)...)
+		}
+		return dedupPrefixes(out)
+	case *ssa.Alloc:
+		return []routePrefix{{known: true}}
+	case *ssa.UnOp:
+		// http.DefaultServeMux
+		if g, ok := x.X.(*ssa.Global); ok && g.Pkg != nil && g.Pkg.Pkg.Path() == "net/http" {
+			return []routePrefix{{known: true}}
+		}
+	case *ssa.Call:
+		if mount, mounted := rr.mountPrefix(x); mounted {
+			return mount
+		}
+		callee := x.Call.StaticCallee()
+		if callee == nil || callee.Pkg == nil || !httpRouterPkgs[callee.Pkg.Pkg.Path()] {
+			return unknownPrefix
+		}
+		recv := metricReceiver(&x.Call)
+		if recv == nil {
+			return []routePrefix{{known: true}} // route.New, mux.NewRouter, chi.NewRouter, ...
+		}
+		switch routeCallName(&x.Call) {
+		case "WithPrefix", "PathPrefix":
+			args := callArgsByIndex(&x.Call)
+			if len(args) != 1 {
+				return unknownPrefix
+			}
+			ps := rr.consts.strings(args[0])
+			if ps == nil {
+				return unknownPrefix
+			}
+			var out []routePrefix
+			for _, base := range rr.prefixes(recv, depth+1) {
+				for _, p := range ps {
+					out = append(out, routePrefix{base.value + p, base.known})
+				}
+			}
+			return dedupPrefixes(out)
+		}
+		return rr.prefixes(recv, depth+1)
+	}
+	return unknownPrefix
+}
+
+// mountPrefix reports whether router v is mounted as a handler on another
+// router, and if so the prefixes it is mounted under: the constant prefix
+// of an http.StripPrefix wrapper or a chi Mount pattern, unknown for any
+// other mount.
+func (rr *routeResolver) mountPrefix(v ssa.Value) ([]routePrefix, bool) {
+	seen := make(map[ssa.Value]bool)
+	work := []ssa.Value{v}
+	for len(work) > 0 {
+		cur := work[len(work)-1]
+		work = work[:len(work)-1]
+		if seen[cur] || cur.Referrers() == nil {
+			continue
+		}
+		seen[cur] = true
+		for _, ref := range *cur.Referrers() {
+			switch r := ref.(type) {
+			case *ssa.MakeInterface:
+				work = append(work, r)
+			case *ssa.ChangeType:
+				work = append(work, r)
+			case *ssa.Call:
+				if metricReceiver(&r.Call) == cur {
+					continue
+				}
+				args := callArgsByIndex(&r.Call)
+				name := routeCallName(&r.Call)
+				switch {
+				case name == "StripPrefix" || name == "Mount":
+					if len(args) == 2 {
+						if ps := rr.consts.strings(args[0]); ps != nil {
+							out := make([]routePrefix, len(ps))
+							for i, p := range ps {
+								out[i] = routePrefix{p, true}
+							}
+							return out, true
+						}
+					}
+					return unknownPrefix, true
+				case isHTTPRegistration(&r.Call):
+					return unknownPrefix, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// handlers resolves a handler argument to the functions serving it,
+// looking through wrapper calls (middleware, instrumentation, adapters)
+// at the handler values they are given.
+func (rr *routeResolver) handlers(v ssa.Value, depth int) []*ssa.Function {
+	if depth > constMaxDepth {
+		return nil
+	}
+	if fns := handlerFuncs(rr.prog, v); len(fns) > 0 {
+		return fns
+	}
+	switch x := v.(type) {
+	case *ssa.MakeInterface:
+		return rr.handlers(x.X, depth+1)
+	case *ssa.ChangeType:
+		return rr.handlers(x.X, depth+1)
+	case *ssa.Call:
+		var out []*ssa.Function
+		for _, arg := range x.Call.Args {
+			if isHandlerValue(arg.Type()) {
+				out = append(out, rr.handlers(arg, depth+1)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// isHandlerValue reports whether values of t can serve requests: function
+// values and types with a ServeHTTP method.
+func isHandlerValue(t types.Type) bool {
+	if _, ok := t.Underlying().(*types.Signature); ok {
+		return true
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "ServeHTTP")
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// dedupPrefixes sorts ps and removes duplicates, capped at
+// constMaxValues.
+func dedupPrefixes(ps []routePrefix) []routePrefix {
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].value != ps[j].value {
+			return ps[i].value < ps[j].value
+		}
+		return ps[i].known && !ps[j].known
+	})
+	out := ps[:0]
+	for i, p := range ps {
+		if i == 0 || p != ps[i-1] {
+			out = append(out, p)
+		}
+	}
+	if len(out) > constMaxValues {
+		out = out[:constMaxValues]
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestIsHTTPRegistration(t *testing.T) {
+	prog, _ := buildTestProgram(t, testPkg{"example.com/app", `package app
+
+import (
+	"net/http"
+	"strings"
+)
+
+func h(w http.ResponseWriter, r *http.Request) {
+	_ = r.Header.Get("Content-Type")
+	r.Header.Del("X-Forwarded-For")
+	http.Get("http://prom/api/v1/query")
+	http.Post("http://am/api/v2/alerts", "application/json", strings.NewReader(""))
+	c := &http.Client{}
+	c.Get("http://prom/metrics")
+	c.Post("http://am/api/v2/alerts", "application/json", nil)
+}
+
+type router interface {
+	Handle(pattern string, h http.Handler)
+}
+
+func Setup(other router) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", h)
+	mux.Handle("/b", http.HandlerFunc(h))
+	http.HandleFunc("/c", h)
+	http.Handle("/d", mux)
+	mux.Handler(&http.Request{})
+	other.Handle("/e", mux)
+}
+`})
+
+	rr := &routeResolver{prog: prog}
+	var funcs []*ssa.Function
+	for _, m := range prog.ImportedPackage("example.com/app").Members {
+		if fn, ok := m.(*ssa.Function); ok {
+			funcs = append(funcs, fn)
+		}
+	}
+	rr.consts = newConstResolver(funcs)
+
+	var got []string
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok || !isHTTPRegistration(call.Common()) {
+					continue
+				}
+				found, _ := rr.routes(call)
+				for _, rt := range found {
+					got = append(got, rt.method+" "+rt.path)
+				}
+			}
+		}
+	}
+	slices.Sort(got)
+	want := []string{" /a", " /b", " /c", " /d"}
+	if !slices.Equal(got, want) {
+		t.Errorf("routes = %q, want %q", got, want)
+	}
+}
+
+func TestRoutePrefixParams(t *testing.T) {
+	saved := modSet
+	t.Cleanup(func() { modSet = saved })
+	modSet = NewModuleSet(ModuleInfo{ModPath: "example.com/app", Dir: "/src/example.com/app"}, nil)
+
+	prog, _ := buildTestProgram(t, testPkg{"example.com/app", `package app
+
+import "net/http"
+
+func h(w http.ResponseWriter, r *http.Request) {}
+
+func register(mux *http.ServeMux) { mux.HandleFunc("/internal", h) }
+func Register(mux *http.ServeMux) { mux.HandleFunc("/exported", h) }
+
+func Setup() {
+	mux := http.NewServeMux()
+	register(mux)
+	Register(mux)
+}
+`})
+
+	var funcs []*ssa.Function
+	for _, m := range prog.ImportedPackage("example.com/app").Members {
+		if fn, ok := m.(*ssa.Function); ok {
+			funcs = append(funcs, fn)
+		}
+	}
+	rr := &routeResolver{prog: prog, consts: newConstResolver(funcs)}
+
+	got := make(map[string]bool)
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok || !isHTTPRegistration(call.Common()) {
+					continue
+				}
+				found, _ := rr.routes(call)
+				for _, rt := range found {
+					got[rt.path] = rt.prefixKnown
+				}
+			}
+		}
+	}
+	want := map[string]bool{"/internal": true, "/exported": false}
+	for path, known := range want {
+		if k, ok := got[path]; !ok || k != known {
+			t.Errorf("route %s: prefix known = %v (found %v), want %v", path, k, ok, known)
+		}
+	}
+}
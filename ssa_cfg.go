@@ -3,6 +3,7 @@ package main
 import (
 	"go/token"
 	"go/types"
+	"sort"
 
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
@@ -66,7 +67,7 @@ func ExtractCFGAndDFG(
 ) {
 	prog.Log("Extracting CFG + DFG...")
 
-	var cfgEdges, dfgEdges, bbNodes, captureEdges int
+	var cfgEdges, dfgEdges, bbNodes, captureEdges, inBlockEdges, branchCondEdges int
 	var ssaPromFuncs, ssaWithBlocks, ssaMatched int
 
 	for fn := range ssaResult.AllFuncs {
@@ -153,6 +154,47 @@ func ExtractCFGAndDFG(
 			bbNodes++
 		}
 
+		// Block membership: AST node → every block holding one of its
+		// instructions, and branching block → its condition node. Together
+		// with cdg these lift block-level control dependence to statements.
+		for i, block := range fn.Blocks {
+			var lastID string
+			inBlock := make(map[string]bool) // an AST node may lower to several instructions
+			for _, instr := range block.Instrs {
+				file, line, col := instrPos(instr, fset)
+				if file == "" {
+					continue
+				}
+				id := posLookup.Get(file, line, col)
+				if id == "" {
+					continue
+				}
+				if !inBlock[id] {
+					inBlock[id] = true
+					cpg.AddEdge(Edge{Source: id, Target: blockIDs[i], Kind: "in_block"})
+					inBlockEdges++
+				}
+				lastID = id
+			}
+			if len(block.Succs) < 2 || len(block.Instrs) == 0 {
+				continue
+			}
+			condID := lastID
+			if ifInstr, ok := block.Instrs[len(block.Instrs)-1].(*ssa.If); ok {
+				if ci, ok := ifInstr.Cond.(ssa.Instruction); ok {
+					if file, line, col := instrPos(ci, fset); file != "" {
+						if id := posLookup.Get(file, line, col); id != "" {
+							condID = id
+						}
+					}
+				}
+			}
+			if condID != "" {
+				cpg.AddEdge(Edge{Source: blockIDs[i], Target: condID, Kind: "branch_cond"})
+				branchCondEdges++
+			}
+		}
+
 		// CFG entry edge: function → first block
 		cpg.AddEdge(Edge{
 			Source: funcNodeID, Target: blockIDs[0],
@@ -246,189 +288,124 @@ func ExtractCFGAndDFG(
 
 	prog.Log("SSA: %d Prometheus funcs, %d with blocks, %d matched to AST", ssaPromFuncs, ssaWithBlocks, ssaMatched)
 	prog.Log("Created %d basic_block nodes, %d CFG edges, %d DFG edges, %d capture edges", bbNodes, cfgEdges, dfgEdges, captureEdges)
+	prog.Log("Created %d in_block, %d branch_cond edges", inBlockEdges, branchCondEdges)
 }
 
-// ExtractChannelFlow finds channel send→receive pairs by tracking MakeChan
-// values through SSA referrers (including closures) and emits chan_flow edges.
+// ExtractChannelFlow tracks every make(chan) through SSA (closures, call
+// arguments, return values, struct fields and select cases; see
+// chanTracer) and emits one channel node per make site with its element
+// type, buffer size, senders, receivers and closers. Each operation site
+// gets a channel_op edge to its channel; chan_flow edges connect sends to
+// receives and chan_close edges connect closes to the receives they release.
 func ExtractChannelFlow(
 	ssaResult *SSAResult,
 	fset *token.FileSet,
 	posLookup *PosLookup,
+	funcLookup *FuncLookup,
 	cpg *CPG,
 	prog *Progress,
 ) {
 	prog.Log("Extracting channel flow edges...")
 
-	var chanFlowEdges int
+	var chanNodes, chanOpEdges, chanFlowEdges, chanCloseEdges int
 
-	// For each MakeChan, follow referrers to find all sends and receives
-	for fn := range ssaResult.AllFuncs {
-		if fn.Pkg == nil || fn.Synthetic != "" {
+	chans, _ := traceChannels(ssaResult)
+	for _, c := range chans {
+		file, line, col := instrPos(c.make, fset)
+		if file == "" {
 			continue
 		}
-		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
-			continue
+		fn := c.make.Parent()
+		relPkg := modSet.RelPkg(fn.Pkg.Pkg.Path())
+		id := ChanID(relPkg, BaseName(file), line, col)
+
+		ops := map[string][]string{"send": {}, "recv": {}, "close": {}}
+		seen := map[string]bool{}
+		for _, op := range c.ops {
+			p := op.pos()
+			if !p.IsValid() {
+				continue
+			}
+			pos := fset.Position(p)
+			rel := modSet.RelFile(pos.Filename)
+			if rel == "" {
+				continue
+			}
+			opID := posLookup.Get(rel, pos.Line, pos.Column)
+			if opID == "" || seen[op.kind+opID] {
+				continue
+			}
+			seen[op.kind+opID] = true
+			ops[op.kind] = append(ops[op.kind], opID)
 		}
 
-		for _, block := range fn.Blocks {
-			for _, instr := range block.Instrs {
-				mc, ok := instr.(*ssa.MakeChan)
-				if !ok {
-					continue
-				}
-
-				// Follow all referrers to find sends/receives on this channel
-				var sends, receives []string
-				visited := map[ssa.Value]bool{}
-				chanFollowRefs(mc, fset, posLookup, &sends, &receives, visited)
-
-				for _, sendID := range sends {
-					for _, recvID := range receives {
-						cpg.AddEdge(Edge{
-							Source: sendID, Target: recvID,
-							Kind: "chan_flow",
-						})
-						chanFlowEdges++
-					}
-				}
+		var fields []string
+		for _, f := range c.fields {
+			if f != "" {
+				fields = append(fields, f)
 			}
 		}
-	}
-
-	prog.Log("Created %d channel flow edges", chanFlowEdges)
-}
-
-// chanFollowRefs recursively follows SSA referrers of a channel value to find
-// all send and receive operations, including through closures and phi nodes.
-func chanFollowRefs(
-	val ssa.Value,
-	fset *token.FileSet, posLookup *PosLookup,
-	sends, receives *[]string,
-	visited map[ssa.Value]bool,
-) {
-	if visited[val] {
-		return
-	}
-	visited[val] = true
-
-	refs := val.Referrers()
-	if refs == nil {
-		return
-	}
+		sort.Strings(fields)
+		name := c.make.Type().String()
+		if len(fields) > 0 {
+			name = fields[0]
+		}
+		props := map[string]any{
+			"senders":   ops["send"],
+			"receivers": ops["recv"],
+			"closers":   ops["close"],
+		}
+		if ch := coreChan(c.make.Type()); ch != nil {
+			props["elem_type"] = ch.Elem().String()
+		}
+		if n, ok := c.capacity(); ok {
+			props["buffer"] = n
+		} else {
+			props["buffer"] = "dynamic"
+		}
+		if len(fields) > 0 {
+			props["fields"] = fields
+		}
+		if makeID := posLookup.Get(file, line, col); makeID != "" {
+			props["make_site"] = makeID
+		}
+		cpg.AddNode(Node{
+			ID:             id,
+			Kind:           "channel",
+			Name:           name,
+			File:           file,
+			Line:           line,
+			Col:            col,
+			Package:        relPkg,
+			ParentFunction: ssaFuncNodeID(fn, fset, funcLookup),
+			TypeInfo:       c.make.Type().String(),
+			Properties:     props,
+		})
+		chanNodes++
 
-	for _, ref := range *refs {
-		switch inst := ref.(type) {
-		case *ssa.Send:
-			if inst.Chan == val {
-				file, line, col := instrPos(inst, fset)
-				if file != "" {
-					if id := posLookup.Get(file, line, col); id != "" {
-						*sends = append(*sends, id)
-					}
-				}
-			}
-		case *ssa.UnOp:
-			if inst.Op == token.ARROW && inst.X == val {
-				// Channel receive: <-ch
-				file, line, col := instrPos(inst, fset)
-				if file != "" {
-					if id := posLookup.Get(file, line, col); id != "" {
-						*receives = append(*receives, id)
-					}
-				}
-			} else if inst.Op == token.MUL {
-				// Pointer dereference (load): channel was stored to an address,
-				// now being loaded back. Follow the loaded value's referrers.
-				chanFollowRefs(inst, fset, posLookup, sends, receives, visited)
-			}
-		case *ssa.Select:
-			// select{} statement: each state is a send or receive on a channel.
-			// Match states where the channel operand is the value we're tracking.
-			for _, st := range inst.States {
-				if st.Chan != val {
-					continue
-				}
-				if !st.Pos.IsValid() {
-					continue
-				}
-				pos := fset.Position(st.Pos)
-				rel := modSet.RelFile(pos.Filename)
-				if rel == "" {
-					continue
-				}
-				id := posLookup.Get(rel, pos.Line, pos.Column)
-				if id == "" {
-					continue
-				}
-				if st.Dir == types.SendOnly {
-					*sends = append(*sends, id)
-				} else {
-					*receives = append(*receives, id)
-				}
-			}
-		case *ssa.Call:
-			// Channel passed as argument — follow into statically-resolvable callee.
-			chanFollowCallArgs(&inst.Call, val, fset, posLookup, sends, receives, visited)
-			// Also follow the return value: callee may return the channel.
-			chanFollowRefs(inst, fset, posLookup, sends, receives, visited)
-		case *ssa.Go:
-			// Channel passed to a goroutine — follow into the launched function.
-			// *ssa.Go does NOT implement ssa.Value so the fallback won't catch it.
-			chanFollowCallArgs(&inst.Call, val, fset, posLookup, sends, receives, visited)
-		case *ssa.Defer:
-			// Channel passed to a deferred call — follow into the deferred function.
-			// *ssa.Defer does NOT implement ssa.Value so the fallback won't catch it.
-			chanFollowCallArgs(&inst.Call, val, fset, posLookup, sends, receives, visited)
-		case *ssa.Phi:
-			// Channel flows through a phi node — follow it
-			chanFollowRefs(inst, fset, posLookup, sends, receives, visited)
-		case *ssa.MakeClosure:
-			// Channel captured by a closure — follow into FreeVars
-			closureFn, ok := inst.Fn.(*ssa.Function)
-			if !ok {
-				continue
+		for _, kind := range []string{"send", "recv", "close"} {
+			for _, opID := range ops[kind] {
+				cpg.AddEdge(Edge{
+					Source: opID, Target: id, Kind: "channel_op",
+					Properties: map[string]any{"op": kind},
+				})
+				chanOpEdges++
 			}
-			for i, binding := range inst.Bindings {
-				if binding == val && i < len(closureFn.FreeVars) {
-					chanFollowRefs(closureFn.FreeVars[i], fset, posLookup, sends, receives, visited)
-				}
+		}
+		for _, recvID := range ops["recv"] {
+			for _, sendID := range ops["send"] {
+				cpg.AddEdge(Edge{Source: sendID, Target: recvID, Kind: "chan_flow"})
+				chanFlowEdges++
 			}
-		case *ssa.Store:
-			// Channel stored to an address — follow loads from same address
-			if inst.Val == val {
-				chanFollowRefs(inst.Addr, fset, posLookup, sends, receives, visited)
+			for _, closeID := range ops["close"] {
+				cpg.AddEdge(Edge{Source: closeID, Target: recvID, Kind: "chan_close"})
+				chanCloseEdges++
 			}
-		case ssa.Value:
-			// Other values that use this channel — follow referrers
-			chanFollowRefs(inst, fset, posLookup, sends, receives, visited)
 		}
 	}
-}
 
-// chanFollowCallArgs handles cross-function channel tracking: when a channel
-// value is passed as an argument to a call/go/defer, follow it into the callee's
-// corresponding parameter to discover sends/receives inside the called function.
-// Only works for statically-resolvable callees (*ssa.Function); interface dispatch
-// and calls through function-value variables are skipped.
-func chanFollowCallArgs(
-	common *ssa.CallCommon,
-	val ssa.Value,
-	fset *token.FileSet, posLookup *PosLookup,
-	sends, receives *[]string,
-	visited map[ssa.Value]bool,
-) {
-	if common.IsInvoke() {
-		return // interface dispatch — callee not statically resolvable
-	}
-	callee, ok := common.Value.(*ssa.Function)
-	if !ok {
-		return // indirect call (function variable) — not resolvable
-	}
-	for i, arg := range common.Args {
-		if arg == val && i < len(callee.Params) {
-			chanFollowRefs(callee.Params[i], fset, posLookup, sends, receives, visited)
-		}
-	}
+	prog.Log("Created %d channel nodes, %d channel_op, %d chan_flow, %d chan_close edges",
+		chanNodes, chanOpEdges, chanFlowEdges, chanCloseEdges)
 }
 
 // ExtractPanicRecover connects panic() calls to recover() calls within the same
@@ -593,6 +570,37 @@ func deref(t types.Type) types.Type {
 	return t
 }
 
+// coreChan returns the channel type t denotes: its underlying type, or for
+// a type parameter (make(C, 1) with C ~chan int) the core type of its
+// constraint. It returns nil when the type set's channels disagree.
+func coreChan(t types.Type) *types.Chan {
+	switch u := t.Underlying().(type) {
+	case *types.Chan:
+		return u
+	case *types.Interface:
+		var core *types.Chan
+		for i := range u.NumEmbeddeds() {
+			var terms []types.Type
+			if un, ok := u.EmbeddedType(i).(*types.Union); ok {
+				for j := range un.Len() {
+					terms = append(terms, un.Term(j).Type())
+				}
+			} else {
+				terms = append(terms, u.EmbeddedType(i))
+			}
+			for _, term := range terms {
+				ch := coreChan(term)
+				if ch == nil || (core != nil && !types.Identical(ch, core)) {
+					return nil
+				}
+				core = ch
+			}
+		}
+		return core
+	}
+	return nil
+}
+
 // ssaValueName extracts the source-level variable name from an SSA value.
 // Returns "" if no meaningful name is available.
 func ssaValueName(v ssa.Value) string {
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"go/types"
+	"testing"
+)
+
+func TestCoreChan(t *testing.T) {
+	prog, _ := buildTestProgram(t, testPkg{"example.com/app", `package app
+
+type Plain chan string
+
+type Ints interface{ ~chan int }
+
+type Mixed interface{ ~chan int | ~chan string }
+
+func A[C ~chan int]() C    { return make(C, 1) }
+func B[C Ints]() C         { return make(C) }
+func M[C Mixed](c C) C     { return c }
+func P() Plain             { return make(Plain) }
+`})
+	pkg := prog.ImportedPackage("example.com/app").Pkg
+	tests := []struct {
+		fn   string
+		want string // elem type, "" for no core channel
+	}{
+		{"A", "int"},
+		{"B", "int"},
+		{"M", ""},
+		{"P", "string"},
+	}
+	for _, tt := range tests {
+		sig := pkg.Scope().Lookup(tt.fn).Type().(*types.Signature).Results()
+		got := ""
+		if ch := coreChan(sig.At(0).Type()); ch != nil {
+			got = ch.Elem().String()
+		}
+		if got != tt.want {
+			t.Errorf("%s: coreChan elem = %q, want %q", tt.fn, got, tt.want)
+		}
+	}
+}
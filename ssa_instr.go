@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// flagSSAInstrs enables instruction-level ssa_instr nodes. Off by default
+// because it roughly triples the node count. Set by main before any
+// pipeline phase runs.
+var flagSSAInstrs = false
+
+// ExtractSSAInstructions emits one ssa_instr node per SSA instruction,
+// linked to its basic_block in order (block_instr, with the instruction
+// index) and to the instructions that define its operands (ssa_operand,
+// definition→use). Each instruction is also tied back to the AST node at
+// the same position (ssa_source) when there is one, so phi nodes, implicit
+// conversions and range-loop bookkeeping can be queried next to the syntax
+// they came from.
+//
+// Must run after ExtractCFGAndDFG, which creates the basic_block nodes.
+func ExtractSSAInstructions(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Extracting SSA instructions...")
+
+	var instrNodes, blockEdges, operandEdges, sourceEdges int
+
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" {
+			continue
+		}
+		if !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		if len(fn.Blocks) == 0 {
+			continue
+		}
+		funcNodeID := ssaFuncNodeID(fn, fset, funcLookup)
+		if funcNodeID == "" {
+			continue
+		}
+		relPkg := modSet.RelPkg(fn.Pkg.Pkg.Path())
+
+		// First pass: nodes, so operand edges can refer to any instruction
+		// regardless of block order.
+		instrIDs := make(map[ssa.Instruction]string)
+		for bi, block := range fn.Blocks {
+			bbID := BlockID(funcNodeID, bi)
+			bbLine, bbCol, bbFile := blockPos(block, fset)
+
+			for ii, instr := range block.Instrs {
+				id := InstrID(bbID, ii)
+				instrIDs[instr] = id
+
+				file, line, col := instrPos(instr, fset)
+				if file == "" {
+					file, line, col = bbFile, bbLine, bbCol
+				}
+
+				opcode := ssaOpcode(instr)
+				props := map[string]any{
+					"opcode": opcode,
+					"index":  ii,
+					"block":  bi,
+					"text":   ssaInstrText(instr),
+				}
+				var typeInfo string
+				if v, ok := instr.(ssa.Value); ok {
+					props["value"] = v.Name()
+					if !isEmptyTuple(v.Type()) {
+						typeInfo = v.Type().String()
+					}
+				}
+				if ops := ssaOperandNames(instr); len(ops) > 0 {
+					props["operands"] = ops
+				}
+				switch inst := instr.(type) {
+				case *ssa.BinOp:
+					props["op"] = inst.Op.String()
+				case *ssa.UnOp:
+					props["op"] = inst.Op.String()
+					if inst.CommaOk {
+						props["comma_ok"] = true
+					}
+				case *ssa.Phi:
+					props["comment"] = inst.Comment
+				case *ssa.Alloc:
+					props["heap"] = inst.Heap
+					props["comment"] = inst.Comment
+				case ssa.CallInstruction:
+					if inst.Common().IsInvoke() {
+						props["invoke"] = true
+					}
+				}
+
+				cpg.AddNode(Node{
+					ID:             id,
+					Kind:           "ssa_instr",
+					Name:           opcode,
+					File:           file,
+					Line:           line,
+					Col:            col,
+					Package:        relPkg,
+					ParentFunction: funcNodeID,
+					TypeInfo:       typeInfo,
+					Properties:     props,
+				})
+				instrNodes++
+
+				cpg.AddEdge(Edge{
+					Source: bbID, Target: id, Kind: "block_instr",
+					Properties: map[string]any{"index": ii},
+				})
+				blockEdges++
+
+				if f, l, c := instrPos(instr, fset); f != "" {
+					if astID := posLookup.Get(f, l, c); astID != "" {
+						cpg.AddEdge(Edge{Source: id, Target: astID, Kind: "ssa_source"})
+						sourceEdges++
+					}
+				}
+			}
+		}
+
+		// Second pass: operand def-use edges between instructions.
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				useID := instrIDs[instr]
+				for k, op := range instr.Operands(nil) {
+					if op == nil || *op == nil {
+						continue
+					}
+					def, ok := (*op).(ssa.Instruction)
+					if !ok {
+						continue
+					}
+					defID, ok := instrIDs[def]
+					if !ok {
+						continue
+					}
+					cpg.AddEdge(Edge{
+						Source: defID, Target: useID, Kind: "ssa_operand",
+						Properties: map[string]any{"operand": k},
+					})
+					operandEdges++
+				}
+			}
+		}
+	}
+
+	prog.Log("Created %d ssa_instr nodes, %d block_instr, %d ssa_operand, %d ssa_source edges",
+		instrNodes, blockEdges, operandEdges, sourceEdges)
+}
+
+// ssaOpcode returns the instruction's concrete type name, e.g. "Phi", "Call".
+func ssaOpcode(instr ssa.Instruction) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", instr), "*ssa.")
+}
+
+// ssaInstrText renders an instruction the way ssa.Function.WriteTo does:
+// "t3 = phi [0: t1, 1: t2]" for values, the bare form otherwise.
+func ssaInstrText(instr ssa.Instruction) string {
+	if v, ok := instr.(ssa.Value); ok && v.Name() != "" && !isEmptyTuple(v.Type()) {
+		return v.Name() + " = " + instr.String()
+	}
+	return instr.String()
+}
+
+// ssaOperandNames returns the short names of an instruction's operands
+// (t3, parameter names, constants as "1:int"); nil slots are skipped.
+func ssaOperandNames(instr ssa.Instruction) []string {
+	var names []string
+	for _, op := range instr.Operands(nil) {
+		if op == nil || *op == nil {
+			continue
+		}
+		names = append(names, (*op).Name())
+	}
+	return names
+}
+
+// isEmptyTuple reports whether t is the result type of a call with no results.
+func isEmptyTuple(t types.Type) bool {
+	tup, ok := t.(*types.Tuple)
+	return ok && tup.Len() == 0
+}
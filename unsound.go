@@ -0,0 +1,363 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Reasons a function defeats static call graph construction, recorded in
+// unsound_reason.
+const (
+	unsoundReflectCall    = "reflect_call"     // reflect.Value.Call/CallSlice
+	unsoundReflectMethod  = "reflect_method"   // method lookup by index or name through reflect
+	unsoundMakeFunc       = "reflect_makefunc" // reflect.MakeFunc builds a function at run time
+	unsoundUnsafe         = "unsafe_pointer"   // unsafe.Pointer reinterpreted, uintptr→unsafe.Pointer, unsafe.Slice/String/Add
+	unsoundCgoCall        = "cgo_call"         // call into C, which may call back into Go
+	unsoundCgoExport      = "cgo_export"       // //export: callable from C
+	unsoundLinkname       = "linkname"         // body bound elsewhere with go:linkname
+	unsoundLinknameExport = "linkname_export"  // exposed to other packages with go:linkname
+	unsoundExternalBody   = "external_body"    // declared without a body (assembly)
+)
+
+// hiddenEntryReasons make a function callable from places the call graph
+// cannot see; the other reasons hide what the function itself calls.
+var hiddenEntryReasons = map[string]bool{
+	unsoundCgoExport:      true,
+	unsoundLinknameExport: true,
+}
+
+// unsafeBuiltins are the unsafe package functions producing typed memory
+// views from raw pointers.
+var unsafeBuiltins = map[string]bool{
+	"Add": true, "Slice": true, "String": true, "SliceData": true, "StringData": true,
+}
+
+// AnalyzeUnsoundness inventories the constructs the VTA call graph cannot
+// model: reflective calls and method lookups, reflect.MakeFunc,
+// unsafe.Pointer reinterpretation, cgo calls and exports, go:linkname and
+// body-less (assembly) declarations. Each affected function gets
+// unsound_reason (sorted reason list) and unsafe_uses (one entry per
+// construct: reason, detail, site, file, line).
+//
+// The regions whose results should not be trusted are then flagged over
+// call edges. Functions that (transitively) call a function hiding its
+// callees get callgraph_incomplete with the nearest such function in
+// incomplete_via and the distance to it; their callee sets, and the
+// taint paths through them, may miss targets. Functions reachable from a
+// hidden entry (cgo export, linkname export) get reachability_incomplete
+// with the entry in hidden_entry_via: being callable from outside the
+// graph, they may be wrongly reported dead.
+func AnalyzeUnsoundness(
+	ssaResult *SSAResult,
+	fset *token.FileSet,
+	posLookup *PosLookup,
+	funcLookup *FuncLookup,
+	cpg *CPG,
+	prog *Progress,
+) {
+	prog.Log("Inventorying unsafe, cgo, reflection and linkname use...")
+
+	var funcs []*ssa.Function
+	byName := make(map[string]*ssa.Function) // relFile + ":" + name → package-level function
+	for fn := range ssaResult.AllFuncs {
+		if fn.Pkg == nil || fn.Synthetic != "" || !modSet.IsKnownPkg(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		funcs = append(funcs, fn)
+		if fn.Parent() == nil && fn.Signature.Recv() == nil {
+			if file, _, _ := funcPos(fn, fset); file != "" {
+				byName[file+":"+fn.Name()] = fn
+			}
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Pos() < funcs[j].Pos() })
+
+	uses := make(map[*ssa.Function][]map[string]any)
+	add := func(fn *ssa.Function, reason, detail string, file string, line, col int) {
+		site := ""
+		if file != "" {
+			site = posLookup.Get(file, line, col)
+		}
+		uses[fn] = append(uses[fn], map[string]any{
+			"reason": reason,
+			"detail": detail,
+			"site":   site,
+			"file":   file,
+			"line":   line,
+		})
+	}
+
+	// Directives live in comments, which SSA does not keep.
+	files := make([]string, 0, len(cpg.Sources))
+	for f := range cpg.Sources {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		for i, text := range strings.Split(cpg.Sources[file], "\n") {
+			fields := strings.Fields(text)
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "//go:linkname":
+				fn := byName[file+":"+fields[1]]
+				if fn == nil {
+					continue
+				}
+				remote := ""
+				if len(fields) > 2 {
+					remote = fields[2]
+				}
+				reason := unsoundLinknameExport
+				if len(fn.Blocks) == 0 {
+					reason = unsoundLinkname
+				}
+				add(fn, reason, remote, file, i+1, 1)
+			case "//export":
+				if fn := byName[file+":"+fields[1]]; fn != nil {
+					add(fn, unsoundCgoExport, fields[1], file, i+1, 1)
+				}
+			}
+		}
+	}
+
+	for _, fn := range funcs {
+		if len(fn.Blocks) == 0 {
+			if fd, ok := fn.Syntax().(*ast.FuncDecl); ok && fd.Body == nil && !hasReason(uses[fn], unsoundLinkname) {
+				file, line, col := funcPos(fn, fset)
+				add(fn, unsoundExternalBody, fn.Name(), file, line, col)
+			}
+			continue
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				reason, detail := unsoundConstruct(instr)
+				if reason == "" {
+					continue
+				}
+				file, line, col := instrPos(instr, fset)
+				add(fn, reason, detail, file, line, col)
+			}
+		}
+	}
+
+	// Record the inventory on the (source) function nodes; closures report
+	// through their enclosing function.
+	reasons := make(map[string]map[string]bool)
+	byFunc := make(map[string][]map[string]any)
+	counts := make(map[string]int)
+	for _, fn := range funcs {
+		if len(uses[fn]) == 0 {
+			continue
+		}
+		outer := fn
+		for outer.Parent() != nil {
+			outer = outer.Parent()
+		}
+		fnID := ssaFuncNodeID(outer, fset, funcLookup)
+		if fnID == "" {
+			continue
+		}
+		if reasons[fnID] == nil {
+			reasons[fnID] = make(map[string]bool)
+		}
+		for _, u := range uses[fn] {
+			reason := u["reason"].(string)
+			reasons[fnID][reason] = true
+			counts[reason]++
+		}
+		byFunc[fnID] = append(byFunc[fnID], uses[fn]...)
+	}
+
+	// Regions: callers of functions hiding their callees, and callees of
+	// hidden entries.
+	preds := make(map[string][]string)
+	succs := make(map[string][]string)
+	for _, e := range cpg.Edges {
+		if e.Kind == "call" {
+			preds[e.Target] = append(preds[e.Target], e.Source)
+			succs[e.Source] = append(succs[e.Source], e.Target)
+		}
+	}
+	var hiding, entries []string
+	for id, rs := range reasons {
+		for r := range rs {
+			if hiddenEntryReasons[r] {
+				entries = append(entries, id)
+				break
+			}
+		}
+		for r := range rs {
+			if !hiddenEntryReasons[r] {
+				hiding = append(hiding, id)
+				break
+			}
+		}
+	}
+	sort.Strings(hiding)
+	sort.Strings(entries)
+	incompleteVia, incompleteDist := unsoundRegion(hiding, preds)
+	hiddenVia, _ := unsoundRegion(entries, succs)
+
+	var incomplete, hidden int
+	for i := range cpg.Nodes {
+		n := &cpg.Nodes[i]
+		if n.Kind != "function" {
+			continue
+		}
+		via, inIncomplete := incompleteVia[n.ID]
+		entry, inHidden := hiddenVia[n.ID]
+		if len(byFunc[n.ID]) == 0 && !inIncomplete && !inHidden {
+			continue
+		}
+		if n.Properties == nil {
+			n.Properties = map[string]any{}
+		}
+		if rs := reasons[n.ID]; len(rs) > 0 {
+			list := make([]string, 0, len(rs))
+			for r := range rs {
+				list = append(list, r)
+			}
+			sort.Strings(list)
+			n.Properties["unsound_reason"] = list
+			n.Properties["unsafe_uses"] = byFunc[n.ID]
+		}
+		if inIncomplete {
+			n.Properties["callgraph_incomplete"] = true
+			n.Properties["incomplete_via"] = via
+			n.Properties["incomplete_distance"] = incompleteDist[n.ID]
+			incomplete++
+		}
+		if inHidden {
+			n.Properties["reachability_incomplete"] = true
+			n.Properties["hidden_entry_via"] = entry
+			hidden++
+		}
+	}
+
+	prog.Log("Unsoundness: %d functions (%d reflect, %d unsafe, %d cgo, %d linkname, %d external bodies); %d with incomplete call graphs, %d reachable from hidden entries",
+		len(byFunc), counts[unsoundReflectCall]+counts[unsoundReflectMethod]+counts[unsoundMakeFunc],
+		counts[unsoundUnsafe], counts[unsoundCgoCall]+counts[unsoundCgoExport],
+		counts[unsoundLinkname]+counts[unsoundLinknameExport], counts[unsoundExternalBody],
+		incomplete, hidden)
+}
+
+// unsoundConstruct classifies an instruction the call graph cannot model,
+// returning its reason and a short description, or "".
+func unsoundConstruct(instr ssa.Instruction) (string, string) {
+	switch instr := instr.(type) {
+	case *ssa.Convert:
+		from, to := instr.X.Type().Underlying(), instr.Type().Underlying()
+		switch {
+		case isBasicKind(from, types.UnsafePointer) && !isBasicKind(to, types.Uintptr):
+			return unsoundUnsafe, "unsafe.Pointer→" + types.TypeString(instr.Type(), (*types.Package).Name)
+		case isBasicKind(from, types.Uintptr) && isBasicKind(to, types.UnsafePointer):
+			return unsoundUnsafe, "uintptr→unsafe.Pointer"
+		}
+	case ssa.CallInstruction:
+		common := instr.Common()
+		if common.IsInvoke() {
+			if named, ok := common.Value.Type().(*types.Named); ok && isReflectType(named, "Type") {
+				if name := common.Method.Name(); name == "Method" || name == "MethodByName" {
+					return unsoundReflectMethod, "reflect.Type." + name
+				}
+			}
+			return "", ""
+		}
+		if b, ok := common.Value.(*ssa.Builtin); ok {
+			if unsafeBuiltins[b.Name()] {
+				return unsoundUnsafe, "unsafe." + b.Name()
+			}
+			return "", ""
+		}
+		callee := common.StaticCallee()
+		if callee == nil {
+			return "", ""
+		}
+		if c, ok := strings.CutPrefix(callee.Name(), "_Cfunc_"); ok {
+			return unsoundCgoCall, "C." + c
+		}
+		if callee.Pkg == nil || callee.Pkg.Pkg.Path() != "reflect" {
+			return "", ""
+		}
+		recv := callee.Signature.Recv()
+		if recv == nil {
+			if callee.Name() == "MakeFunc" {
+				return unsoundMakeFunc, "reflect.MakeFunc"
+			}
+			return "", ""
+		}
+		if named, ok := deref(recv.Type()).(*types.Named); ok && isReflectType(named, "Value") {
+			switch callee.Name() {
+			case "Call", "CallSlice":
+				return unsoundReflectCall, "reflect.Value." + callee.Name()
+			case "Method", "MethodByName":
+				return unsoundReflectMethod, "reflect.Value." + callee.Name()
+			}
+		}
+	}
+	return "", ""
+}
+
+// unsoundRegion runs a multi-source BFS from roots over next, returning
+// for every node reached (roots included) its nearest root and distance.
+func unsoundRegion(roots []string, next map[string][]string) (map[string]string, map[string]int) {
+	via := make(map[string]string)
+	dist := make(map[string]int)
+	queue := make([]string, 0, len(roots))
+	for _, r := range roots {
+		via[r] = r
+		dist[r] = 0
+		queue = append(queue, r)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, n := range next[id] {
+			if _, seen := via[n]; seen {
+				continue
+			}
+			via[n] = via[id]
+			dist[n] = dist[id] + 1
+			queue = append(queue, n)
+		}
+	}
+	return via, dist
+}
+
+// funcPos returns the relative file and position of a function's name.
+func funcPos(fn *ssa.Function, fset *token.FileSet) (string, int, int) {
+	if !fn.Pos().IsValid() {
+		return "", 0, 0
+	}
+	p := fset.Position(fn.Pos())
+	return modSet.RelFile(p.Filename), p.Line, p.Column
+}
+
+// hasReason reports whether uses records a construct with reason.
+func hasReason(uses []map[string]any, reason string) bool {
+	for _, u := range uses {
+		if u["reason"] == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// isBasicKind reports whether t is the basic type of the given kind.
+func isBasicKind(t types.Type, kind types.BasicKind) bool {
+	b, ok := t.(*types.Basic)
+	return ok && b.Kind() == kind
+}
+
+// isReflectType reports whether named is reflect.<name>.
+func isReflectType(named *types.Named, name string) bool {
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "reflect" && obj.Name() == name
+}